@@ -0,0 +1,520 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aplgr/grovegrid/pkg/grovegrid"
+)
+
+type buildFlags struct {
+	commonFlags
+	out        string
+	site       bool
+	jsonOut    string
+	gridCSVDir string
+	cellsCSV   string
+	svgOut     string
+	pngOut     string
+	pngCell    float64
+	pngDPI     float64
+	reportOut  string
+	reportTop  int
+	vegaOut    string
+	echartsOut string
+	plotlyOut  string
+	grafanaOut string
+	grafanaURL string
+	geoOut     string
+	latCol     string
+	lonCol     string
+	// precompress lists the -precompress formats, parsed by runBuild.
+	precompress []string
+	dryRun      bool
+	manifest    bool
+	offline     bool
+	csp         bool
+	sri         bool
+	sriLock     string
+}
+
+func newBuildFlags() (*flag.FlagSet, *buildFlags) {
+	fs := newFlagSet("grovegrid build")
+	f := &buildFlags{}
+	f.register(fs, groupInput|groupBuild|groupOutput|groupServe)
+	fs.StringVar(&f.out, "out", "./out", "Output directory")
+	fs.BoolVar(&f.site, "site", false, "write one page per month plus an index page instead of a single page with all data")
+	fs.StringVar(&f.jsonOut, "json-out", "", "optional path to write JSON data (disabled if empty)")
+	fs.StringVar(&f.gridCSVDir, "grid-csv-dir", "", "optional directory to write one dense grid CSV (X rows × Y columns) per month (disabled if empty)")
+	fs.StringVar(&f.cellsCSV, "cells-csv-dir", "", "optional directory to write the filled grid per month as long CSV, one row per cell incl. -1 no-data cells (disabled if empty)")
+	fs.StringVar(&f.svgOut, "svg-out", "", "optional directory to write one standalone SVG heatmap per month (disabled if empty)")
+	fs.StringVar(&f.pngOut, "png-out", "", "optional directory to write one PNG heatmap per month (disabled if empty)")
+	fs.Float64Var(&f.pngCell, "png-cell", 24, "PNG cell size in pixels at 96 DPI")
+	fs.Float64Var(&f.pngDPI, "png-dpi", 96, "PNG resolution; 192 doubles every dimension")
+	fs.StringVar(&f.reportOut, "report-out", "", "optional path to write a Markdown summary report (disabled if empty)")
+	fs.IntVar(&f.reportTop, "report-top", 5, "number of top cells listed per month in -report-out")
+	fs.StringVar(&f.vegaOut, "vega-out", "", "optional directory to write a Vega-Lite spec plus its cell data per month (disabled if empty)")
+	fs.StringVar(&f.echartsOut, "echarts-out", "", "optional directory to write one ECharts option JSON per month (disabled if empty)")
+	fs.StringVar(&f.plotlyOut, "plotly-out", "", "optional directory to write one Plotly figure JSON per month (disabled if empty)")
+	fs.StringVar(&f.grafanaOut, "grafana-out", "", "optional directory to write a Grafana dashboard plus one cell JSON per month (disabled if empty)")
+	fs.StringVar(&f.grafanaURL, "grafana-data-url", "http://localhost:8000", "URL where the -grafana-out directory is served (default of the dashboard's data_url variable)")
+	fs.StringVar(&f.geoOut, "geojson-out", "", "optional directory to write one GeoJSON FeatureCollection per month from latitude/longitude extras (disabled if empty)")
+	fs.StringVar(&f.latCol, "lat-col", "", "extra holding the latitude (default: lat, latitude, breite)")
+	fs.StringVar(&f.lonCol, "lon-col", "", "extra holding the longitude (default: lon, lng, longitude, laenge)")
+	fs.BoolVar(&f.offline, "offline", false, "inline the scripts and stylesheets a custom template loads from URLs or files, so the page works without a network")
+	fs.BoolVar(&f.csp, "csp", false, "move the inline scripts, styles and data of the page to files loaded with integrity hashes, for a strict Content-Security-Policy (implies -external-data)")
+	fs.BoolVar(&f.sri, "sri", false, "add integrity hashes of the scripts and stylesheets a custom template loads from other origins, fetched at build time")
+	fs.StringVar(&f.sriLock, "sri-lock", "", "optional JSON file recording the -sri hashes; the build fails if a recorded asset changed (implies -sri)")
+	fs.BoolVar(&f.manifest, "manifest", false, "also write manifest.json in -out listing every file written with its size and SHA-256")
+	fs.BoolVar(&f.dryRun, "dry-run", false, "read and check the inputs, then list the files that would be written with their sizes, without writing anything")
+	fs.Func("precompress", "also write pre-compressed copies of the pages and JSON in -out: gz, br or gz,br", func(s string) error {
+		formats, err := parseFormats(s)
+		f.precompress = formats
+		return err
+	})
+	return fs, f
+}
+
+// runBuild generates index.html (and optional side outputs) into -out. With
+// -watch it regenerates on every input change until interrupted.
+func runBuild(args []string) error {
+	fs, f := newBuildFlags()
+	opts, err := f.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	defer f.close()
+	if err := f.checkOutputs(opts); err != nil {
+		return err
+	}
+	if f.dryRun {
+		if f.watch {
+			return withCode(exitUsage, fmt.Errorf("-dry-run cannot be used with -watch"))
+		}
+		return dryRun(f, opts)
+	}
+
+	if !f.watch {
+		return generate(f, opts)
+	}
+	if err := generate(f, opts); err != nil {
+		f.logger.Error("build failed", "err", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fmt.Println("Watching", f.watchTarget(), "for changes (Ctrl+C to stop)")
+	watchDir(ctx, f.watchTarget(), f.watchInterval, func() {
+		if err := generate(f, opts); err != nil {
+			f.logger.Error("rebuild failed", "err", err)
+		}
+	})
+	return nil
+}
+
+// output is an output flag of build: a directory, or a file if file is set.
+type output struct {
+	path *string
+	file bool
+}
+
+// outputs returns the output flags of f, set or not.
+func (f *buildFlags) outputs() []output {
+	return []output{
+		{&f.out, false}, {&f.jsonOut, true}, {&f.gridCSVDir, false}, {&f.cellsCSV, false},
+		{&f.svgOut, false}, {&f.pngOut, false}, {&f.reportOut, true}, {&f.vegaOut, false},
+		{&f.echartsOut, false}, {&f.plotlyOut, false}, {&f.grafanaOut, false}, {&f.geoOut, false},
+	}
+}
+
+// checkOutputs rejects output flags of f that would overwrite the input,
+// -offline with a payload the page fetches, and the options the static
+// outputs and the grid and cells CSVs cannot show: they take values below
+// 0 for no data.
+func (f *buildFlags) checkOutputs(opts grovegrid.Options) error {
+	if f.gridCSVDir != "" && sameDir(f.gridCSVDir, f.in) {
+		return withCode(exitWrite, fmt.Errorf("-grid-csv-dir must not be the input directory %s", f.in))
+	}
+	if f.cellsCSV != "" && sameDir(f.cellsCSV, f.in) {
+		return withCode(exitWrite, fmt.Errorf("-cells-csv-dir must not be the input directory %s", f.in))
+	}
+	if f.offline && (f.csp || f.pageOptions().dataURL != "") {
+		return withCode(exitUsage, fmt.Errorf("-offline cannot be used with -csp, -external-data or -data-format msgpack"))
+	}
+	if static := f.svgOut + f.pngOut + f.vegaOut + f.echartsOut + f.plotlyOut + f.grafanaOut + f.gridCSVDir + f.cellsCSV; static != "" {
+		if opts.Normalize == grovegrid.NormalizeZScore {
+			return withCode(exitUsage, fmt.Errorf("-normalize zscore cannot be used with -svg-out, -png-out, -vega-out, -echarts-out, -plotly-out, -grafana-out, -grid-csv-dir or -cells-csv-dir"))
+		}
+		if opts.Signed {
+			return withCode(exitUsage, fmt.Errorf("-signed cannot be used with -svg-out, -png-out, -vega-out, -echarts-out, -plotly-out, -grafana-out, -grid-csv-dir or -cells-csv-dir"))
+		}
+	}
+	return nil
+}
+
+func generate(f *buildFlags, opts grovegrid.Options) error {
+	out, err := grovegrid.Build(opts)
+	if err != nil {
+		return err
+	}
+	index, err := writeOutputs(f, out)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Done. Open:", index)
+	return nil
+}
+
+// writeOutputs writes the page (or the site) of out and the side outputs
+// f enables, and returns the path of the page.
+func writeOutputs(f *buildFlags, out *grovegrid.Output) (string, error) {
+	months := out.Meta.Months
+	start := time.Now()
+	began := start
+	po := f.pageOptions()
+	if f.offline {
+		po.assets = newAssetInliner(f.httpTimeout, f.assetDirs()...)
+	}
+	if f.sri || f.sriLock != "" {
+		var err error
+		if po.sri, err = newSRIInjector(f.httpTimeout, f.sriLock); err != nil {
+			return "", err
+		}
+	}
+	if f.csp {
+		po.csp = newCSPWriter(f.out)
+		po.dataURL = "data." + f.dataFormat
+	}
+	// finish records new -sri-lock hashes, unless this is a dry run, and
+	// writes the manifest once everything else is written
+	finish := func() error {
+		if po.sri != nil && !f.dryRun {
+			if err := po.sri.save(); err != nil {
+				return err
+			}
+		}
+		if f.manifest {
+			return writeManifest(f, began, out.Meta.GeneratedAt)
+		}
+		return nil
+	}
+
+	tmpl, err := readTemplate(f.template, f.templateDir, f.theme)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(f.out, 0o755); err != nil {
+		return "", withCode(exitWrite, err)
+	}
+
+	if err := writeSideOutputs(f, out); err != nil {
+		return "", err
+	}
+	f.logger.Info("wrote side outputs", "elapsed", time.Since(start))
+	start = time.Now()
+
+	if f.site {
+		if err := writeSite(f.out, tmpl, out, po); err != nil {
+			return "", err
+		}
+		if err := precompress(f.out, f.precompress); err != nil {
+			return "", err
+		}
+		f.logger.Info("wrote site", "dir", f.out, "pages", len(months), "elapsed", time.Since(start))
+		if err := finish(); err != nil {
+			return "", err
+		}
+		return filepath.Join(f.out, "index.html"), nil
+	}
+
+	// with -external-data the page fetches data.json (or data.msgpack) next
+	// to it
+	if po.dataURL != "" {
+		if err := writeData(filepath.Join(f.out, po.dataURL), out, po); err != nil {
+			return "", err
+		}
+	}
+
+	// write index.html
+	html, err := renderHTML(tmpl, out, po)
+	if err != nil {
+		return "", err
+	}
+	index := filepath.Join(f.out, "index.html")
+	if err := os.WriteFile(index, html, 0o644); err != nil {
+		return "", withCode(exitWrite, err)
+	}
+	if err := precompress(f.out, f.precompress); err != nil {
+		return "", err
+	}
+	f.logger.Info("wrote page", "path", index, "bytes", len(html), "elapsed", time.Since(start))
+	if err := finish(); err != nil {
+		return "", err
+	}
+	return index, nil
+}
+
+// writeSideOutputs writes the outputs of out other than the page that the
+// flags of f enable, such as -json-out and -svg-out.
+func writeSideOutputs(f *buildFlags, out *grovegrid.Output) error {
+	months := out.Meta.Months
+	// optional: write data.json if -json-out is set
+	if f.jsonOut != "" {
+		if err := os.MkdirAll(filepath.Dir(f.jsonOut), 0o755); err != nil {
+			return withCode(exitWrite, err)
+		}
+		if err := writeJSON(f.jsonOut, out, f.compact); err != nil {
+			return err
+		}
+	}
+
+	// optional: write one dense grid CSV per month if -grid-csv-dir is set
+	if f.gridCSVDir != "" {
+		if err := os.MkdirAll(f.gridCSVDir, 0o755); err != nil {
+			return withCode(exitWrite, err)
+		}
+		for _, m := range months {
+			p, err := outPath(f.gridCSVDir, m+".csv")
+			if err != nil {
+				return err
+			}
+			if err := grovegrid.WriteGridCSV(p, out.Meta.Labels.X, out.Datasets[m].Heat, out.Meta.XMin, out.Meta.XMax, out.Meta.YMin, out.Meta.YMax); err != nil {
+				return withCode(exitWrite, fmt.Errorf("write %s: %w", p, err))
+			}
+		}
+	}
+
+	// optional: write one filled long-format CSV per month if -cells-csv-dir is set
+	if f.cellsCSV != "" {
+		if err := os.MkdirAll(f.cellsCSV, 0o755); err != nil {
+			return withCode(exitWrite, err)
+		}
+		for _, m := range months {
+			p, err := outPath(f.cellsCSV, m+".csv")
+			if err != nil {
+				return err
+			}
+			if err := grovegrid.WriteCellsCSV(p, out.Meta.Labels, out.Datasets[m].Cells()); err != nil {
+				return withCode(exitWrite, fmt.Errorf("write %s: %w", p, err))
+			}
+		}
+	}
+
+	// optional: write one SVG per month if -svg-out is set
+	if f.svgOut != "" {
+		if err := os.MkdirAll(f.svgOut, 0o755); err != nil {
+			return withCode(exitWrite, err)
+		}
+		for _, m := range months {
+			p, err := outPath(f.svgOut, m+".svg")
+			if err != nil {
+				return err
+			}
+			if err := grovegrid.WriteSVGFile(p, out, m); err != nil {
+				return withCode(exitWrite, fmt.Errorf("write %s: %w", p, err))
+			}
+		}
+	}
+
+	// optional: write one PNG per month if -png-out is set
+	if f.pngOut != "" {
+		if err := os.MkdirAll(f.pngOut, 0o755); err != nil {
+			return withCode(exitWrite, err)
+		}
+		po := grovegrid.PNGOptions{Cell: f.pngCell, DPI: f.pngDPI}
+		for _, m := range months {
+			p, err := outPath(f.pngOut, m+".png")
+			if err != nil {
+				return err
+			}
+			if err := grovegrid.WritePNGFile(p, out, m, po); err != nil {
+				return withCode(exitWrite, fmt.Errorf("write %s: %w", p, err))
+			}
+		}
+	}
+
+	// optional: write report.md if -report-out is set
+	if f.reportOut != "" {
+		if err := os.MkdirAll(filepath.Dir(f.reportOut), 0o755); err != nil {
+			return withCode(exitWrite, err)
+		}
+		if err := grovegrid.WriteReportFile(f.reportOut, out, f.reportTop); err != nil {
+			return withCode(exitWrite, fmt.Errorf("write %s: %w", f.reportOut, err))
+		}
+	}
+
+	// optional: write Vega-Lite specs if -vega-out is set
+	if f.vegaOut != "" {
+		if err := os.MkdirAll(f.vegaOut, 0o755); err != nil {
+			return withCode(exitWrite, err)
+		}
+		for _, m := range months {
+			spec, err := grovegrid.VegaLiteSpec(out, m, m+".json")
+			if err != nil {
+				return err
+			}
+			p, err := outPath(f.vegaOut, m+".vl.json")
+			if err != nil {
+				return err
+			}
+			if err := writeJSON(p, spec, f.compact); err != nil {
+				return err
+			}
+			if p, err = outPath(f.vegaOut, m+".json"); err != nil {
+				return err
+			}
+			if err := writeJSON(p, out.Datasets[m].Cells(), f.compact); err != nil {
+				return err
+			}
+		}
+	}
+
+	// optional: write ECharts options if -echarts-out is set
+	if f.echartsOut != "" {
+		if err := os.MkdirAll(f.echartsOut, 0o755); err != nil {
+			return withCode(exitWrite, err)
+		}
+		for _, m := range months {
+			opt, err := grovegrid.EChartsOption(out, m)
+			if err != nil {
+				return err
+			}
+			p, err := outPath(f.echartsOut, m+".json")
+			if err != nil {
+				return err
+			}
+			if err := writeJSON(p, opt, f.compact); err != nil {
+				return err
+			}
+		}
+	}
+
+	// optional: write Plotly figures if -plotly-out is set
+	if f.plotlyOut != "" {
+		if err := os.MkdirAll(f.plotlyOut, 0o755); err != nil {
+			return withCode(exitWrite, err)
+		}
+		for _, m := range months {
+			fig, err := grovegrid.PlotlyFigure(out, m)
+			if err != nil {
+				return err
+			}
+			p, err := outPath(f.plotlyOut, m+".json")
+			if err != nil {
+				return err
+			}
+			if err := writeJSON(p, fig, f.compact); err != nil {
+				return err
+			}
+		}
+	}
+
+	// optional: write a Grafana dashboard if -grafana-out is set
+	if f.grafanaOut != "" {
+		if err := os.MkdirAll(f.grafanaOut, 0o755); err != nil {
+			return withCode(exitWrite, err)
+		}
+		for _, m := range months {
+			p, err := outPath(f.grafanaOut, m+".json")
+			if err != nil {
+				return err
+			}
+			if err := writeJSON(p, out.Datasets[m].Cells(), f.compact); err != nil {
+				return err
+			}
+		}
+		dash := grovegrid.GrafanaDashboard(out, strings.TrimSuffix(f.grafanaURL, "/"))
+		if err := writeJSON(filepath.Join(f.grafanaOut, "dashboard.json"), dash, f.compact); err != nil {
+			return err
+		}
+	}
+
+	// optional: write GeoJSON if -geojson-out is set
+	if f.geoOut != "" {
+		if err := os.MkdirAll(f.geoOut, 0o755); err != nil {
+			return withCode(exitWrite, err)
+		}
+		written := 0
+		for _, m := range months {
+			fc, n, err := grovegrid.GeoJSON(out, m, f.latCol, f.lonCol)
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				continue
+			}
+			p, err := outPath(f.geoOut, m+".geojson")
+			if err != nil {
+				return err
+			}
+			if err := writeJSON(p, fc, f.compact); err != nil {
+				return err
+			}
+			written++
+		}
+		if written == 0 {
+			return withCode(exitInput, fmt.Errorf("-geojson-out: no points with latitude/longitude extras (see -lat-col, -lon-col)"))
+		}
+	}
+
+	return nil
+}
+
+// outPath joins dir and name, the file name of a slice's output, and makes
+// sure the result is a file directly in dir. Build accepts only slice names
+// that are plain file names; this keeps any other name, such as one a
+// custom Source returns, from writing outside dir.
+func outPath(dir, name string) (string, error) {
+	p := filepath.Join(dir, name)
+	if filepath.Base(name) != name || filepath.Dir(p) != filepath.Clean(dir) {
+		return "", withCode(exitWrite, fmt.Errorf("output file %q would not be in %s", name, dir))
+	}
+	return p, nil
+}
+
+// writeJSON writes v as indented JSON to path, or minified if compact is set.
+func writeJSON(path string, v interface{}, compact bool) error {
+	b, err := marshalJSON(v, compact)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return withCode(exitWrite, err)
+	}
+	return nil
+}
+
+// writeData writes the payload fetched by the page, see encodeData.
+func writeData(path string, v interface{}, po pageOptions) error {
+	b, err := encodeData(v, po)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return withCode(exitWrite, err)
+	}
+	return nil
+}
+
+func marshalJSON(v interface{}, compact bool) ([]byte, error) {
+	if compact {
+		return json.Marshal(v)
+	}
+	return json.MarshalIndent(v, "", "  ")
+}
+
+func sameDir(a, b string) bool {
+	aa, err1 := filepath.Abs(a)
+	bb, err2 := filepath.Abs(b)
+	if err1 != nil || err2 != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return aa == bb
+}
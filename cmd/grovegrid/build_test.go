@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aplgr/grovegrid/pkg/grovegrid"
+)
+
+func TestCheckOutputsSigned(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		f    buildFlags
+	}{
+		{"-grid-csv-dir", buildFlags{gridCSVDir: "grid"}},
+		{"-cells-csv-dir", buildFlags{cellsCSV: "cells"}},
+		{"-svg-out", buildFlags{svgOut: "svg"}},
+	} {
+		tc.f.in = "data"
+		for _, opts := range []grovegrid.Options{{Signed: true}, {Normalize: grovegrid.NormalizeZScore}} {
+			err := tc.f.checkOutputs(opts)
+			if err == nil || !strings.Contains(err.Error(), tc.name) {
+				t.Errorf("%s with %+v: got %v, want it refused", tc.name, opts, err)
+			}
+		}
+		if err := tc.f.checkOutputs(grovegrid.Options{}); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/aplgr/grovegrid/pkg/grovegrid"
+)
+
+type compareFlags struct {
+	format    string
+	tolerance float64
+	compact   bool
+}
+
+func newCompareFlags() (*flag.FlagSet, *compareFlags) {
+	fs := newFlagSet("grovegrid compare")
+	f := &compareFlags{}
+	fs.StringVar(&f.format, "format", "text", "report format: text (a summary plus one line per change) or json")
+	fs.Float64Var(&f.tolerance, "tolerance", 0, "ignore value changes of at most this much")
+	fs.BoolVar(&f.compact, "compact", false, "write minified JSON with -format json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: grovegrid compare [flags] OLD.json NEW.json")
+		fs.PrintDefaults()
+	}
+	return fs, f
+}
+
+// payloadFile is the part of a JSON payload (-json-out, or data.json of
+// -external-data) that compare reads, in either layout.
+type payloadFile struct {
+	Meta     grovegrid.Meta          `json:"meta"`
+	Datasets map[string]payloadMonth `json:"datasets"`
+}
+
+type payloadMonth struct {
+	// Points in LayoutRows
+	Points []struct {
+		X     int     `json:"x"`
+		Y     int     `json:"y"`
+		Value float64 `json:"value"`
+	} `json:"points"`
+	// LayoutFlat
+	Xs     []int     `json:"xs"`
+	Ys     []int     `json:"ys"`
+	Values []float64 `json:"values"`
+}
+
+// cells returns the values of the cells with data.
+func (m payloadMonth) cells() map[[2]int]float64 {
+	vs := map[[2]int]float64{}
+	for _, p := range m.Points {
+		vs[[2]int{p.X, p.Y}] = p.Value
+	}
+	for i := range m.Xs {
+		if i < len(m.Ys) && i < len(m.Values) {
+			vs[[2]int{m.Xs[i], m.Ys[i]}] = m.Values[i]
+		}
+	}
+	return vs
+}
+
+// The kinds of cellDelta.
+const (
+	cellAdded   = "added"
+	cellRemoved = "removed"
+	cellChanged = "changed"
+)
+
+// cellDelta is a cell that differs between the payloads compare reads, in
+// a slice both hold. From is missing for added cells, To for removed ones.
+type cellDelta struct {
+	Month  string   `json:"month"`
+	X      int      `json:"x"`
+	Y      int      `json:"y"`
+	Kind   string   `json:"kind"`
+	From   *float64 `json:"from,omitempty"`
+	To     *float64 `json:"to,omitempty"`
+	Change float64  `json:"change,omitempty"`
+}
+
+// comparison is the report of compare.
+type comparison struct {
+	MonthsAdded   []string    `json:"months_added"`
+	MonthsRemoved []string    `json:"months_removed"`
+	CellsAdded    int         `json:"cells_added"`
+	CellsRemoved  int         `json:"cells_removed"`
+	CellsChanged  int         `json:"cells_changed"`
+	Cells         []cellDelta `json:"cells"`
+}
+
+func (c comparison) changed() bool {
+	return len(c.MonthsAdded)+len(c.MonthsRemoved)+len(c.Cells) > 0
+}
+
+// runCompare reports the slices and cells that differ between two JSON
+// payloads, such as the data.json of the last release and of a new build;
+// it fails with exitChanged if there are any, as a data regression gate.
+func runCompare(args []string) error {
+	fs, f := newCompareFlags()
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &exitError{code: exitUsage, err: err, quiet: true}
+	}
+	if fs.NArg() != 2 {
+		return withCode(exitUsage, fmt.Errorf("compare: want two payloads OLD NEW, got %d arguments", fs.NArg()))
+	}
+	if f.format != "text" && f.format != "json" {
+		return withCode(exitUsage, fmt.Errorf("-format %q: want text or json", f.format))
+	}
+	if f.tolerance < 0 {
+		return withCode(exitUsage, fmt.Errorf("-tolerance %v: want 0 or more", f.tolerance))
+	}
+	old, err := readPayload(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	cur, err := readPayload(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	c := comparePayloads(old, cur, f.tolerance)
+
+	if f.format == "json" {
+		b, err := marshalJSON(c, f.compact)
+		if err != nil {
+			return err
+		}
+		os.Stdout.Write(append(b, '\n'))
+	} else {
+		printComparison(c, cur.Meta)
+	}
+	if c.changed() {
+		return &exitError{code: exitChanged, err: errors.New("payloads differ"), quiet: true}
+	}
+	return nil
+}
+
+func readPayload(path string) (*payloadFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, withCode(exitInput, err)
+	}
+	var p payloadFile
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, withCode(exitParse, fmt.Errorf("%s: %w", path, err))
+	}
+	if p.Datasets == nil {
+		return nil, withCode(exitParse, fmt.Errorf("%s: no datasets, want a grovegrid payload", path))
+	}
+	return &p, nil
+}
+
+// comparePayloads compares the slices of Meta.Months of old and cur, and
+// the cells of the slices in both; value changes of at most tolerance are
+// ignored.
+func comparePayloads(old, cur *payloadFile, tolerance float64) comparison {
+	c := comparison{MonthsAdded: []string{}, MonthsRemoved: []string{}, Cells: []cellDelta{}}
+	for _, m := range cur.Meta.Months {
+		if !slices.Contains(old.Meta.Months, m) {
+			c.MonthsAdded = append(c.MonthsAdded, m)
+		}
+	}
+	for _, m := range old.Meta.Months {
+		if !slices.Contains(cur.Meta.Months, m) {
+			c.MonthsRemoved = append(c.MonthsRemoved, m)
+			continue
+		}
+		before, after := old.Datasets[m].cells(), cur.Datasets[m].cells()
+		var keys [][2]int
+		for k := range before {
+			keys = append(keys, k)
+		}
+		for k := range after {
+			if _, ok := before[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i][0] != keys[j][0] {
+				return keys[i][0] < keys[j][0]
+			}
+			return keys[i][1] < keys[j][1]
+		})
+		for _, k := range keys {
+			d := cellDelta{Month: m, X: k[0], Y: k[1]}
+			from, hadFrom := before[k]
+			to, hasTo := after[k]
+			switch {
+			case !hadFrom:
+				d.Kind, d.To = cellAdded, &to
+				c.CellsAdded++
+			case !hasTo:
+				d.Kind, d.From = cellRemoved, &from
+				c.CellsRemoved++
+			case math.Abs(to-from) > tolerance:
+				d.Kind, d.From, d.To, d.Change = cellChanged, &from, &to, to-from
+				c.CellsChanged++
+			default:
+				continue
+			}
+			c.Cells = append(c.Cells, d)
+		}
+	}
+	return c
+}
+
+// printComparison prints c as text, naming the cells by the axes of meta.
+func printComparison(c comparison, meta grovegrid.Meta) {
+	num := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	for _, m := range c.MonthsAdded {
+		fmt.Printf("%s: slice added\n", m)
+	}
+	for _, m := range c.MonthsRemoved {
+		fmt.Printf("%s: slice removed\n", m)
+	}
+	names := meta.AxisNames
+	for _, d := range c.Cells {
+		cell := fmt.Sprintf("%s: %s %s, %s %s", d.Month, meta.Labels.X, names.Name("x", d.X), meta.Labels.Y, names.Name("y", d.Y))
+		switch d.Kind {
+		case cellAdded:
+			fmt.Printf("%s: added (%s)\n", cell, num(*d.To))
+		case cellRemoved:
+			fmt.Printf("%s: removed (was %s)\n", cell, num(*d.From))
+		default:
+			sign := ""
+			if d.Change > 0 {
+				sign = "+"
+			}
+			fmt.Printf("%s: %s -> %s (%s%s)\n", cell, num(*d.From), num(*d.To), sign, num(d.Change))
+		}
+	}
+	if !c.changed() {
+		fmt.Println("No changes")
+		return
+	}
+	fmt.Printf("%d slices added, %d removed; %d cells added, %d removed, %d changed\n",
+		len(c.MonthsAdded), len(c.MonthsRemoved), c.CellsAdded, c.CellsRemoved, c.CellsChanged)
+}
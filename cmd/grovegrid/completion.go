@@ -0,0 +1,201 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/aplgr/grovegrid/pkg/grovegrid"
+)
+
+func init() {
+	// completion lists the commands, so it joins them once they are declared
+	commands = append(commands, command{"completion", "print the completion script for bash, zsh or fish", runCompletion})
+}
+
+// commandFlags returns the flag sets of the commands that have flags.
+var commandFlags = map[string]func() *flag.FlagSet{
+	"build":           func() *flag.FlagSet { fs, _ := newBuildFlags(); return fs },
+	"serve":           func() *flag.FlagSet { fs, _ := newServeFlags(); return fs },
+	"validate":        func() *flag.FlagSet { fs, _ := newValidateFlags(); return fs },
+	"diff":            func() *flag.FlagSet { fs, _ := newDiffFlags(); return fs },
+	"compare":         func() *flag.FlagSet { fs, _ := newCompareFlags(); return fs },
+	"validate-output": func() *flag.FlagSet { fs, _ := newValidateOutputFlags(); return fs },
+	"export":          func() *flag.FlagSet { fs, _ := newExportFlags(); return fs },
+	"init":            func() *flag.FlagSet { fs, _ := newInitFlags(); return fs },
+}
+
+// completionShells lists the shells completion writes scripts for, by the
+// argument naming them.
+var completionShells = map[string]string{
+	"bash": bashCompletion,
+	"zsh":  zshCompletion,
+	"fish": fishCompletion,
+}
+
+// runCompletion prints the completion script of a shell, or with "months"
+// the slice names in the input directory, which the scripts offer for the
+// slices diff takes.
+func runCompletion(args []string) error {
+	if len(args) == 0 {
+		return withCode(exitUsage, fmt.Errorf("completion: want bash, zsh, fish or months"))
+	}
+	if args[0] == "months" {
+		fs := newFlagSet("grovegrid completion months")
+		c := &commonFlags{}
+		c.register(fs, groupInput)
+		opts, err := c.parse(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		defer c.close()
+		names, err := grovegrid.SliceNames(opts.InDir, opts.PeriodPattern)
+		if err != nil {
+			return withCode(exitInput, err)
+		}
+		for _, n := range names {
+			fmt.Println(n)
+		}
+		return nil
+	}
+	script, ok := completionShells[args[0]]
+	if !ok {
+		return withCode(exitUsage, fmt.Errorf("completion %q: want bash, zsh, fish or months", args[0]))
+	}
+	t := template.Must(template.New(args[0]).Funcs(template.FuncMap{
+		"short": shortUsage,
+		"sq":    func(s string) string { return strings.ReplaceAll(s, "'", `'\''`) },
+		"fishq": func(s string) string { return strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) },
+	}).Parse(script))
+	return t.Execute(os.Stdout, completionData())
+}
+
+type completionCommand struct {
+	Name, Summary string
+	Flags         []*flag.Flag
+}
+
+// completionData lists the commands and their flags for the scripts.
+func completionData() []completionCommand {
+	var out []completionCommand
+	for _, c := range commands {
+		cc := completionCommand{Name: c.name, Summary: c.summary}
+		if newFS := commandFlags[c.name]; newFS != nil {
+			newFS().VisitAll(func(f *flag.Flag) { cc.Flags = append(cc.Flags, f) })
+		}
+		out = append(out, cc)
+	}
+	return out
+}
+
+// shortUsage cuts the usage of a flag to its first clause and at most
+// about 60 bytes, for the descriptions of the completions.
+func shortUsage(s string) string {
+	for _, sep := range []string{"; ", ", e.g."} {
+		if i := strings.Index(s, sep); i > 0 {
+			s = s[:i]
+		}
+	}
+	if len(s) > 60 {
+		if i := strings.LastIndex(s[:60], " "); i > 0 {
+			s = s[:i] + "…"
+		}
+	}
+	return s
+}
+
+const bashCompletion = `# bash completion for grovegrid: source <(grovegrid completion bash)
+_grovegrid() {
+	local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}
+	local cmd=build in= flags= i
+	[[ $COMP_CWORD -gt 1 && ${COMP_WORDS[1]} != -* ]] && cmd=${COMP_WORDS[1]}
+	for ((i = 1; i < COMP_CWORD - 1; i++)); do
+		[[ ${COMP_WORDS[i]} == -in ]] && in=${COMP_WORDS[i+1]}
+	done
+	if [[ $COMP_CWORD -eq 1 && $cur != -* ]]; then
+		COMPREPLY=($(compgen -W "{{range .}}{{.Name}} {{end}}" -- "$cur"))
+		return
+	fi
+	case $cmd in
+{{- range .}}{{if .Flags}}
+	{{.Name}}) flags="{{range .Flags}}-{{.Name}} {{end}}" ;;
+{{- end}}{{end}}
+	esac
+	if [[ $cur == -* ]]; then
+		COMPREPLY=($(compgen -W "$flags" -- "$cur"))
+	elif [[ $cmd == diff && $prev != -* ]]; then
+		COMPREPLY=($(compgen -W "$("${COMP_WORDS[0]}" completion months ${in:+-in "$in"} 2>/dev/null)" -- "$cur"))
+	elif [[ $cmd == completion && $COMP_CWORD -eq 2 ]]; then
+		COMPREPLY=($(compgen -W "bash zsh fish months" -- "$cur"))
+	fi
+}
+complete -o default -F _grovegrid grovegrid
+`
+
+const zshCompletion = `#compdef grovegrid
+# zsh completion for grovegrid: save as _grovegrid in a directory of $fpath,
+# or source <(grovegrid completion zsh)
+_grovegrid() {
+	local cmd=build in i
+	local -a items
+	(( CURRENT > 2 )) && [[ $words[2] != -* ]] && cmd=$words[2]
+	for (( i = 2; i < CURRENT - 1; i++ )); do
+		[[ $words[i] == -in ]] && in=$words[i+1]
+	done
+	if (( CURRENT == 2 )) && [[ $words[CURRENT] != -* ]]; then
+		items=({{range .}}'{{.Name}}:{{sq .Summary}}' {{end}})
+		_describe command items
+		return
+	fi
+	if [[ $words[CURRENT] == -* ]]; then
+		case $cmd in
+{{- range .}}{{if .Flags}}
+		{{.Name}}) items=({{range .Flags}}'-{{.Name}}:{{sq (short .Usage)}}' {{end}}) ;;
+{{- end}}{{end}}
+		esac
+		_describe flag items
+	elif [[ $cmd == diff && $words[CURRENT-1] != -* ]]; then
+		items=(${(f)"$($words[1] completion months ${in:+-in $in} 2>/dev/null)"})
+		compadd -a items
+	elif [[ $cmd == completion ]] && (( CURRENT == 3 )); then
+		compadd bash zsh fish months
+	else
+		_files
+	fi
+}
+if [[ $funcstack[1] == _grovegrid ]]; then
+	_grovegrid "$@"
+else
+	compdef _grovegrid grovegrid
+fi
+`
+
+const fishCompletion = `# fish completion for grovegrid: save as ~/.config/fish/completions/grovegrid.fish
+function __grovegrid_command
+	set -l words (commandline -opc)
+	if set -q words[2]; and not string match -q -- '-*' $words[2]
+		echo $words[2]
+	else
+		echo build
+	end
+end
+
+function __grovegrid_months
+	set -l words (commandline -opc)
+	set -l args
+	if set -l i (contains -i -- -in $words); and set -q words[(math $i + 1)]
+		set args -in $words[(math $i + 1)]
+	end
+	$words[1] completion months $args 2>/dev/null
+end
+{{range .}}
+complete -c grovegrid -n __fish_use_subcommand -f -a {{.Name}} -d '{{fishq .Summary}}'
+{{- end}}
+{{range $c := .}}{{range .Flags}}
+complete -c grovegrid -n 'test (__grovegrid_command) = {{$c.Name}}' -o {{.Name}} -d '{{fishq (short .Usage)}}'
+{{- end}}{{end}}
+complete -c grovegrid -n 'test (__grovegrid_command) = diff' -f -a '(__grovegrid_months)'
+complete -c grovegrid -n 'test (__grovegrid_command) = completion' -f -a 'bash zsh fish months'
+`
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// compressors maps each -precompress format, which is also the file suffix,
+// to its writer.
+var compressors = map[string]func(io.Writer) io.WriteCloser{
+	"gz": func(w io.Writer) io.WriteCloser {
+		zw, _ := gzip.NewWriterLevel(w, gzip.BestCompression)
+		return zw
+	},
+	"br": func(w io.Writer) io.WriteCloser {
+		return brotli.NewWriterLevel(w, brotli.BestCompression)
+	},
+}
+
+// parseFormats splits a -precompress value such as "gz,br".
+func parseFormats(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var formats []string
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if _, ok := compressors[f]; !ok {
+			return nil, fmt.Errorf("unknown format %q, want gz or br", f)
+		}
+		formats = append(formats, f)
+	}
+	return formats, nil
+}
+
+// precompress writes a .gz or .br copy next to every .html, .json and
+// .msgpack file of dir and the scripts and stylesheets of -csp, so static
+// hosts can serve them with Content-Encoding.
+func precompress(dir string, formats []string) error {
+	if len(formats) == 0 {
+		return nil
+	}
+	var files []string
+	for _, pat := range []string{"*.html", "*.json", "*.msgpack", cspAssetsDir + "/*.js", cspAssetsDir + "/*.css"} {
+		m, err := filepath.Glob(filepath.Join(dir, pat))
+		if err != nil {
+			return withCode(exitWrite, err)
+		}
+		files = append(files, m...)
+	}
+	for _, p := range files {
+		src, err := os.ReadFile(p)
+		if err != nil {
+			return withCode(exitWrite, err)
+		}
+		for _, f := range formats {
+			var buf bytes.Buffer
+			zw := compressors[f](&buf)
+			if _, err := zw.Write(src); err != nil {
+				return withCode(exitWrite, err)
+			}
+			if err := zw.Close(); err != nil {
+				return withCode(exitWrite, err)
+			}
+			if err := os.WriteFile(p+"."+f, buf.Bytes(), 0o644); err != nil {
+				return withCode(exitWrite, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/aplgr/grovegrid/pkg/grovegrid"
+)
+
+// defaultConfigFile is loaded from the working directory when -config is not given.
+const defaultConfigFile = "grovegrid.yaml"
+
+// config mirrors grovegrid.yaml. Top-level scalar keys are flag names
+// (e.g. `in`, `title`, `json-out`); command-line flags take precedence.
+type config struct {
+	Columns grovegrid.Columns      `yaml:"columns"`
+	Palette paletteConfig          `yaml:"palette"`
+	Notes   map[string]string      `yaml:"notes"`
+	Months  map[string]monthConfig `yaml:"months"`
+	Vars    map[string]string      `yaml:"vars"`
+	Format  formatConfig           `yaml:"format"`
+	Flags   map[string]interface{} `yaml:",inline"`
+}
+
+type paletteConfig struct {
+	Zero        string   `yaml:"zero"`
+	NoData      string   `yaml:"nodata"`
+	Gradient    []string `yaml:"gradient"`
+	LightZero   string   `yaml:"light-zero"`
+	LightNoData string   `yaml:"light-nodata"`
+}
+
+// formatConfig holds the number formats of values and sizes.
+type formatConfig struct {
+	Value *grovegrid.Format `yaml:"value"`
+	Size  *grovegrid.Format `yaml:"size"`
+}
+
+type monthConfig struct {
+	Columns grovegrid.Columns `yaml:"columns"`
+	Notes   string            `yaml:"notes"`
+}
+
+// loadConfig reads path, or grovegrid.yaml if path is empty and that file
+// exists. A missing default file yields an empty config.
+func loadConfig(path string) (*config, error) {
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigFile
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return &config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cfg config
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// applyFlags sets every flag of fset named in the config that was not given
+// on the command line. Keys belonging to other commands are skipped.
+func (c *config) applyFlags(fset *flag.FlagSet) error {
+	set := map[string]bool{}
+	fset.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for name, v := range c.Flags {
+		if !knownFlag(name) || name == "config" {
+			return fmt.Errorf("unknown config key %q", name)
+		}
+		if set[name] || fset.Lookup(name) == nil {
+			continue
+		}
+		if err := fset.Set(name, configValue(v)); err != nil {
+			return fmt.Errorf("config key %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// configValue renders a YAML scalar, list or mapping the way it would be
+// typed as a flag (mappings become key=value pairs).
+func configValue(v interface{}) string {
+	switch t := v.(type) {
+	case []interface{}:
+		parts := make([]string, len(t))
+		for i, e := range t {
+			parts[i] = fmt.Sprint(e)
+		}
+		return strings.Join(parts, ",")
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = k + "=" + fmt.Sprint(t[k])
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(v)
+}
+
+// apply copies the structured config sections into opts. Column flags given
+// on the command line win over the columns section.
+func (c *config) apply(opts *grovegrid.Options) {
+	if opts.Columns.X == "" {
+		opts.Columns.X = c.Columns.X
+	}
+	if opts.Columns.Y == "" {
+		opts.Columns.Y = c.Columns.Y
+	}
+	if opts.Columns.Value == "" {
+		opts.Columns.Value = c.Columns.Value
+	}
+	if opts.Columns.Size == "" {
+		opts.Columns.Size = c.Columns.Size
+	}
+	if opts.Columns.Date == "" {
+		opts.Columns.Date = c.Columns.Date
+	}
+	// computed columns add up: the flags' follow the columns section's
+	opts.Columns.Computed = append(append([]string(nil), c.Columns.Computed...), opts.Columns.Computed...)
+	opts.ZeroColor = c.Palette.Zero
+	opts.NoDataColor = c.Palette.NoData
+	opts.GradColors = c.Palette.Gradient
+	opts.LightZeroColor = c.Palette.LightZero
+	opts.LightNoDataColor = c.Palette.LightNoData
+	opts.ValueFormat = c.Format.Value
+	opts.SizeFormat = c.Format.Size
+	opts.Notes = c.Notes
+	if len(c.Months) > 0 {
+		opts.Months = map[string]grovegrid.MonthOptions{}
+		for m, mc := range c.Months {
+			opts.Months[m] = grovegrid.MonthOptions{Columns: mc.Columns, Notes: mc.Notes}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grovegrid.yaml")
+	const cfg = "columns:\n  x: row\n  y: position\n  value: errors\n  date: day\n"
+	if err := os.WriteFile(path, []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fs, f := newBuildFlags()
+	opts, err := f.parse(fs, []string{"-config", path, "-y-col", "slot"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := opts.Columns
+	if c.X != "row" || c.Y != "slot" || c.Value != "errors" || c.Date != "day" {
+		t.Errorf("columns %+v, want x row, y slot (the flag), value errors and date day", c)
+	}
+}
+
+func TestConfigDateColFlag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grovegrid.yaml")
+	if err := os.WriteFile(path, []byte("columns:\n  date: day\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fs, f := newBuildFlags()
+	opts, err := f.parse(fs, []string{"-config", path, "-date-col", "when"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Columns.Date != "when" {
+		t.Errorf("date column %q, want when from -date-col", opts.Columns.Date)
+	}
+}
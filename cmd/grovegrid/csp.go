@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"html"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// cspAssetsDir is the directory of -out the scripts and stylesheets of
+// -csp are written to.
+const cspAssetsDir = "assets"
+
+// contentSecurityPolicy is the policy -csp pages declare and can be served
+// under. Alpine.js evaluates its directives with Function, hence
+// 'unsafe-eval', and sets the styles bound with :style as attributes.
+const contentSecurityPolicy = "default-src 'none'; script-src 'self' 'unsafe-eval'; style-src 'self'; style-src-attr 'unsafe-inline'; img-src 'self' data: blob:; connect-src 'self'; base-uri 'none'; form-action 'none'"
+
+var (
+	// inlineCodeRe matches the <script> and <style> blocks with their
+	// attributes and content.
+	inlineCodeRe = regexp.MustCompile(`(?is)<script\b([^>]*)>(.*?)</script\s*>|<style\b([^>]*)>(.*?)</style\s*>`)
+	typeAttrRe   = regexp.MustCompile(`(?is)\btype\s*=\s*["']?([^"'\s>]+)`)
+	headRe       = regexp.MustCompile(`(?is)<head\b[^>]*>`)
+)
+
+// cspWriter moves the inline scripts and styles of rendered pages into
+// files of cspAssetsDir named by the hash of their content (-csp), which
+// the pages load with Subresource Integrity, so that they work under
+// contentSecurityPolicy without 'unsafe-inline'.
+type cspWriter struct {
+	dir     string
+	written map[string]bool
+}
+
+func newCSPWriter(dir string) *cspWriter {
+	return &cspWriter{dir: dir, written: map[string]bool{}}
+}
+
+// externalize returns html with its inline code moved to asset files and
+// the policy declared in its head. Data blocks such as
+// <script type="application/json"> are left as they are.
+func (c *cspWriter) externalize(page []byte) ([]byte, error) {
+	var err error
+	page = inlineCodeRe.ReplaceAllFunc(page, func(tag []byte) []byte {
+		if err != nil {
+			return tag
+		}
+		m := inlineCodeRe.FindSubmatch(tag)
+		if m[1] != nil { // <script>
+			attrs, code := string(m[1]), string(m[2])
+			if srcRe.MatchString(attrs) || strings.TrimSpace(code) == "" || !isScriptType(attrs) {
+				return tag
+			}
+			var ref, sri string
+			if ref, sri, err = c.write(code, ".js"); err != nil {
+				return tag
+			}
+			return []byte(`<script` + attrs + ` src="` + ref + `" integrity="` + sri + `"></script>`)
+		}
+		attrs, css := string(m[3]), string(m[4])
+		if strings.TrimSpace(css) == "" {
+			return tag
+		}
+		var ref, sri string
+		if ref, sri, err = c.write(css, ".css"); err != nil {
+			return tag
+		}
+		return []byte(`<link rel="stylesheet"` + attrs + ` href="` + ref + `" integrity="` + sri + `">`)
+	})
+	if err != nil {
+		return nil, err
+	}
+	meta := `<meta http-equiv="Content-Security-Policy" content="` + html.EscapeString(contentSecurityPolicy) + `">`
+	if loc := headRe.FindIndex(page); loc != nil {
+		page = append(page[:loc[1]:loc[1]], append([]byte("\n  "+meta), page[loc[1]:]...)...)
+	}
+	return page, nil
+}
+
+// write writes code to an asset file with extension ext, unless a page
+// wrote it already, and returns its URL and integrity value.
+func (c *cspWriter) write(code, ext string) (ref, sri string, err error) {
+	sum := sha256.Sum256([]byte(code))
+	name := hex.EncodeToString(sum[:8]) + ext
+	ref = path.Join(cspAssetsDir, name)
+	sri = "sha256-" + base64.StdEncoding.EncodeToString(sum[:])
+	if c.written[name] {
+		return ref, sri, nil
+	}
+	dir := filepath.Join(c.dir, cspAssetsDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", "", withCode(exitWrite, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(code), 0o644); err != nil {
+		return "", "", withCode(exitWrite, err)
+	}
+	c.written[name] = true
+	return ref, sri, nil
+}
+
+// isScriptType reports whether a <script> with attrs holds code rather
+// than data.
+func isScriptType(attrs string) bool {
+	m := typeAttrRe.FindStringSubmatch(attrs)
+	if m == nil {
+		return true
+	}
+	t := strings.ToLower(m[1])
+	return t == "module" || strings.HasSuffix(t, "javascript") || strings.HasSuffix(t, "ecmascript")
+}
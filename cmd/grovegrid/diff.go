@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/aplgr/grovegrid/pkg/grovegrid"
+)
+
+type diffFlags struct {
+	commonFlags
+	format string
+}
+
+func newDiffFlags() (*flag.FlagSet, *diffFlags) {
+	fs := newFlagSet("grovegrid diff")
+	f := &diffFlags{}
+	f.register(fs, groupInput|groupBuild)
+	fs.StringVar(&f.format, "format", "text", "output format: text (one line per cell), csv or json")
+	fs.BoolVar(&f.compact, "compact", false, "write minified JSON with -format json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: grovegrid diff [flags] FROM TO")
+		fs.PrintDefaults()
+	}
+	return fs, f
+}
+
+// cellChange is the change of one cell between the two slices of diff.
+type cellChange struct {
+	X      int     `json:"x"`
+	Y      int     `json:"y"`
+	From   float64 `json:"from"`
+	To     float64 `json:"to"`
+	Change float64 `json:"change"`
+}
+
+// runDiff prints the change of each cell with data in both slices FROM and
+// TO, the dataset -diff adds to the page.
+func runDiff(args []string) error {
+	fs, f := newDiffFlags()
+	opts, err := f.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	defer f.close()
+	if fs.NArg() != 2 {
+		return withCode(exitUsage, fmt.Errorf("diff: want two slices FROM TO, got %d arguments", fs.NArg()))
+	}
+	if f.format != "text" && f.format != "csv" && f.format != "json" {
+		return withCode(exitUsage, fmt.Errorf("-format %q: want text, csv or json", f.format))
+	}
+	from, to := fs.Arg(0), fs.Arg(1)
+	opts.Diff = [2]string{from, to}
+	out, err := grovegrid.Build(opts)
+	if err != nil {
+		return err
+	}
+
+	values := func(name string) map[[2]int]float64 {
+		vs := map[[2]int]float64{}
+		for _, h := range out.Datasets[name].Heat {
+			vs[[2]int{int(h[0]), int(h[1])}] = h[2]
+		}
+		return vs
+	}
+	before, after := values(from), values(to)
+	var changes []cellChange
+	for _, h := range out.Datasets[out.Meta.Diff.Name].Heat {
+		c := [2]int{int(h[0]), int(h[1])}
+		changes = append(changes, cellChange{X: c[0], Y: c[1], From: before[c], To: after[c], Change: h[2]})
+	}
+
+	num := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	switch f.format {
+	case "json":
+		b, err := marshalJSON(changes, f.compact)
+		if err != nil {
+			return err
+		}
+		os.Stdout.Write(append(b, '\n'))
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"x", "y", "from", "to", "change"})
+		for _, c := range changes {
+			w.Write([]string{strconv.Itoa(c.X), strconv.Itoa(c.Y), num(c.From), num(c.To), num(c.Change)})
+		}
+		w.Flush()
+		return w.Error()
+	default:
+		names := out.Meta.AxisNames
+		for _, c := range changes {
+			sign := ""
+			if c.Change > 0 {
+				sign = "+"
+			}
+			fmt.Printf("%s %s, %s %s: %s -> %s (%s%s)\n", out.Meta.Labels.X, names.Name("x", c.X), out.Meta.Labels.Y, names.Name("y", c.Y), num(c.From), num(c.To), sign, num(c.Change))
+		}
+		fmt.Printf("%d cells with data in %s and %s\n", len(changes), from, to)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aplgr/grovegrid/pkg/grovegrid"
+)
+
+// dryRun builds the payload like runBuild and prints the slices, the grid
+// and the files build would write with their sizes. The files are written
+// to a temporary directory that is removed again, so the sizes are exact;
+// the parse cache is not used, so nothing else is written either.
+func dryRun(f *buildFlags, opts grovegrid.Options) error {
+	opts.CacheDir = ""
+	out, err := grovegrid.Build(opts)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp("", "grovegrid-dry-run-")
+	if err != nil {
+		return withCode(exitWrite, err)
+	}
+	defer os.RemoveAll(tmp)
+
+	// every output goes to a numbered directory of tmp standing for the
+	// directory it would be written to
+	d := *f
+	var dirs []string
+	for _, o := range d.outputs() {
+		if *o.path == "" {
+			continue
+		}
+		dir := filepath.Join(tmp, strconv.Itoa(len(dirs)))
+		if o.file {
+			dirs = append(dirs, filepath.Dir(*o.path))
+			*o.path = filepath.Join(dir, filepath.Base(*o.path))
+		} else {
+			dirs = append(dirs, *o.path)
+			*o.path = dir
+		}
+	}
+	if _, err := writeOutputs(&d, out); err != nil {
+		return err
+	}
+
+	m := out.Meta
+	fmt.Println("Dry run, nothing written.")
+	fmt.Printf("Slices: %d (%s)\n", len(m.Months), strings.Join(m.Months, ", "))
+	fmt.Printf("Grid: %d × %d (%s %d..%d, %s %d..%d)\n", m.XMax-m.XMin+1, m.YMax-m.YMin+1, m.Labels.X, m.XMin, m.XMax, m.Labels.Y, m.YMin, m.YMax)
+	fmt.Println("Would write:")
+	var files int
+	var total int64
+	for i, dir := range dirs {
+		root := filepath.Join(tmp, strconv.Itoa(i))
+		err := filepath.WalkDir(root, func(p string, e fs.DirEntry, err error) error {
+			if err != nil || e.IsDir() {
+				return err
+			}
+			info, err := e.Info()
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(root, p)
+			fmt.Printf("  %s  %s\n", filepath.Join(dir, rel), byteSize(info.Size()))
+			files++
+			total += info.Size()
+			return nil
+		})
+		if err != nil {
+			return withCode(exitWrite, err)
+		}
+	}
+	fmt.Printf("Total: %d files, %s\n", files, byteSize(total))
+	return nil
+}
+
+// byteSize formats n bytes with a decimal unit, such as "12.3 kB".
+func byteSize(n int64) string {
+	if n < 1000 {
+		return strconv.FormatInt(n, 10) + " B"
+	}
+	v, unit := float64(n)/1000, "kB"
+	for _, u := range []string{"MB", "GB"} {
+		if v < 1000 {
+			break
+		}
+		v, unit = v/1000, u
+	}
+	return strconv.FormatFloat(v, 'f', 1, 64) + " " + unit
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/aplgr/grovegrid/pkg/grovegrid"
+)
+
+// Exit codes.
+const (
+	exitFailure  = 1
+	exitUsage    = 2 // invalid flags or config file; also used by the flag package
+	exitInput    = 3 // input directory missing, unreadable or empty
+	exitParse    = 4 // an input file could not be parsed
+	exitWrite    = 5 // an output file could not be written
+	exitTemplate = 6 // the page template could not be read or rendered
+	exitInvalid  = 7 // validate found issues in the inputs, validate-output in the payloads
+	exitChanged  = 8 // compare found differences between the payloads
+)
+
+// exitError attaches an exit code to an error returned by run. quiet errors
+// were already reported (e.g. by the flag package) and are not printed again.
+type exitError struct {
+	code  int
+	err   error
+	quiet bool
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+func (e *exitError) Unwrap() error { return e.err }
+
+func withCode(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// exitCode maps an error returned by run to the process exit code.
+func exitCode(err error) int {
+	var ee *exitError
+	if errors.As(err, &ee) {
+		return ee.code
+	}
+	var pe *grovegrid.ParseError
+	if errors.As(err, &pe) {
+		return exitParse
+	}
+	if errors.Is(err, grovegrid.ErrInputDir) || errors.Is(err, grovegrid.ErrNoInput) || errors.Is(err, grovegrid.ErrDuplicateSlice) {
+		return exitInput
+	}
+	return exitFailure
+}
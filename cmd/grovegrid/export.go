@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/aplgr/grovegrid/pkg/grovegrid"
+)
+
+// exportFormats maps the -format values of export to the build flag that
+// writes them into dir.
+var exportFormats = []struct {
+	name string
+	set  func(b *buildFlags, dir string)
+}{
+	{"json", func(b *buildFlags, dir string) { b.jsonOut = filepath.Join(dir, "data.json") }},
+	{"grid-csv", func(b *buildFlags, dir string) { b.gridCSVDir = dir }},
+	{"cells-csv", func(b *buildFlags, dir string) { b.cellsCSV = dir }},
+	{"svg", func(b *buildFlags, dir string) { b.svgOut = dir }},
+	{"png", func(b *buildFlags, dir string) { b.pngOut = dir }},
+	{"report", func(b *buildFlags, dir string) { b.reportOut = filepath.Join(dir, "report.md") }},
+	{"vega", func(b *buildFlags, dir string) { b.vegaOut = dir }},
+	{"echarts", func(b *buildFlags, dir string) { b.echartsOut = dir }},
+	{"plotly", func(b *buildFlags, dir string) { b.plotlyOut = dir }},
+	{"grafana", func(b *buildFlags, dir string) { b.grafanaOut = dir }},
+	{"geojson", func(b *buildFlags, dir string) { b.geoOut = dir }},
+}
+
+func exportFormatNames() []string {
+	names := make([]string, len(exportFormats))
+	for i, e := range exportFormats {
+		names[i] = e.name
+	}
+	return names
+}
+
+type exportFlags struct {
+	commonFlags
+	format     string
+	out        string
+	pngCell    float64
+	pngDPI     float64
+	reportTop  int
+	grafanaURL string
+	latCol     string
+	lonCol     string
+}
+
+func newExportFlags() (*flag.FlagSet, *exportFlags) {
+	fs := newFlagSet("grovegrid export")
+	f := &exportFlags{}
+	f.register(fs, groupInput|groupBuild|groupOutput)
+	fs.StringVar(&f.format, "format", "", "output to write: "+strings.Join(exportFormatNames(), "|"))
+	fs.StringVar(&f.out, "out", "./out", "Output directory")
+	fs.Float64Var(&f.pngCell, "png-cell", 24, "PNG cell size in pixels at 96 DPI")
+	fs.Float64Var(&f.pngDPI, "png-dpi", 96, "PNG resolution; 192 doubles every dimension")
+	fs.IntVar(&f.reportTop, "report-top", 5, "number of top cells listed per month in the report")
+	fs.StringVar(&f.grafanaURL, "grafana-data-url", "http://localhost:8000", "URL where the -out directory is served (default of the dashboard's data_url variable)")
+	fs.StringVar(&f.latCol, "lat-col", "", "extra holding the latitude (default: lat, latitude, breite)")
+	fs.StringVar(&f.lonCol, "lon-col", "", "extra holding the longitude (default: lon, lng, longitude, laenge)")
+	return fs, f
+}
+
+// runExport writes one side output of build, such as the SVGs or the
+// Plotly figures, into -out without the page.
+func runExport(args []string) error {
+	fs, f := newExportFlags()
+	opts, err := f.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	defer f.close()
+	b := &buildFlags{commonFlags: f.commonFlags, out: f.out, pngCell: f.pngCell, pngDPI: f.pngDPI, reportTop: f.reportTop, grafanaURL: f.grafanaURL, latCol: f.latCol, lonCol: f.lonCol}
+	found := false
+	for _, e := range exportFormats {
+		if e.name == f.format {
+			e.set(b, f.out)
+			found = true
+		}
+	}
+	if !found {
+		return withCode(exitUsage, fmt.Errorf("-format %q: want one of %v", f.format, exportFormatNames()))
+	}
+	if err := b.checkOutputs(opts); err != nil {
+		return err
+	}
+	out, err := grovegrid.Build(opts)
+	if err != nil {
+		return err
+	}
+	if err := writeSideOutputs(b, out); err != nil {
+		return err
+	}
+	fmt.Println("Done. Wrote", f.format, "to", f.out)
+	return nil
+}
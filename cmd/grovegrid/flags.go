@@ -0,0 +1,610 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aplgr/grovegrid/pkg/grovegrid"
+)
+
+// commonFlags are shared by every command that builds a grid.
+type commonFlags struct {
+	config       string
+	in           string
+	title        string
+	template     string
+	templateDir  string
+	theme        string
+	scheme       string
+	externalData bool
+	dataFormat   string
+	compact      bool
+	workers      int
+	cacheDir     string
+	verbose      bool
+	veryVerbose  bool
+	logFormat    string
+	logLevel     string
+	// logger is set up by parse from -log-format, -log-level, -v and -vv.
+	logger        *slog.Logger
+	sparse        bool
+	strict        bool
+	signed        bool
+	typedExtras   bool
+	viewExtras    bool
+	dup           string
+	gaps          string
+	aggregate     string
+	diff          string
+	trend         bool
+	periodPattern string
+	yoy           bool
+	smooth        int
+	highlight     int
+	normalize     string
+	anomaly       float64
+	anomalyMethod string
+	marginals     string
+	layout        string
+	colors        string
+	cbSafe        bool
+	scale         string
+	bins          int
+	thresholds    string
+	center        *float64
+	valueRange    string
+	clamp         string
+	legend        grovegrid.Legend
+	legendTicks   string
+	tooltip       string
+	urlCol        string
+	cols          grovegrid.Columns
+	xLabel        string
+	yLabel        string
+	valueLabel    string
+	sizeLabel     string
+	axisNames     string
+	annotations   string
+	watch         bool
+	watchInterval time.Duration
+	sqlite        string
+	dsn           string
+	query         string
+	queryVars     kvFlag
+	vars          kvFlag
+	monthCol      string
+	dateCol       string
+	period        string
+	valueCols     string
+	sizeCols      string
+	compute       listFlag
+	timezone      string
+	location      *time.Location
+	reproducible  bool
+	month         string
+	inFormat      string
+	httpTimeout   time.Duration
+	httpRetries   int
+	sheetsID      string
+	sheetsKey     string
+	sheetsTabs    string
+	promURL       string
+	promQuery     string
+	promStart     string
+	promEnd       string
+	promRange     time.Duration
+	promStep      time.Duration
+	promX         string
+	promY         string
+	influxURL     string
+	influxOrg     string
+	influxToken   string
+	influxQuery   string
+
+	// db is the database -sqlite or -dsn opened; see close.
+	db *sql.DB
+}
+
+// flagGroup is a set of the groups of commonFlags; a command registers the
+// groups it uses, so its -h lists only those and the rest are unknown flags.
+type flagGroup uint8
+
+const (
+	groupInput flagGroup = 1 << iota
+	groupBuild
+	groupOutput
+	groupServe
+)
+
+// register registers the flags of groups in fs. The flags of the other
+// groups are registered in a set of their own, which is never parsed, so
+// their fields still get the flags' defaults.
+func (c *commonFlags) register(fs *flag.FlagSet, groups flagGroup) {
+	unused := flag.NewFlagSet("unused", flag.ContinueOnError)
+	in := func(g flagGroup) *flag.FlagSet {
+		if groups&g != 0 {
+			return fs
+		}
+		return unused
+	}
+	c.registerInput(in(groupInput))
+	c.registerBuild(in(groupBuild))
+	c.registerOutput(in(groupOutput))
+	c.registerServe(in(groupServe))
+}
+
+// registerInput registers the flags that select and read the records: the
+// sources, the columns, -config and the diagnostics.
+func (c *commonFlags) registerInput(fs *flag.FlagSet) {
+	fs.StringVar(&c.config, "config", "", "config file (default: ./"+defaultConfigFile+" if present)")
+	fs.StringVar(&c.in, "in", "./data", "Input directory with one CSV/JSON/JSONL file per slice (e.g. 2025-01.csv, 2025-02.jsonl), a URL (file or directory listing), or - for a single slice on stdin")
+	fs.StringVar(&c.month, "month", "", "slice name for -in - (default: current month, e.g. 2025-01)")
+	fs.StringVar(&c.inFormat, "in-format", "csv", "format of -in -: csv, json or jsonl")
+	fs.DurationVar(&c.httpTimeout, "http-timeout", 30*time.Second, "timeout per HTTP request for URL inputs")
+	fs.StringVar(&c.sheetsID, "sheets-id", "", "read from this Google Sheets spreadsheet (ID from its URL) instead of -in")
+	fs.StringVar(&c.sheetsKey, "sheets-key", "", "service-account key file (JSON) for -sheets-id")
+	fs.StringVar(&c.sheetsTabs, "sheets-tabs", "", "comma-separated tabs to read, one slice each (default: all tabs)")
+	fs.StringVar(&c.promURL, "prom-url", "", "read from this Prometheus server (e.g. http://prometheus:9090) instead of -in")
+	fs.StringVar(&c.promQuery, "prom-query", "", "PromQL range query for -prom-url")
+	fs.StringVar(&c.promStart, "prom-start", "", "range start (date or RFC 3339; default: -prom-end minus -prom-range)")
+	fs.StringVar(&c.promEnd, "prom-end", "", "range end (date or RFC 3339; default: now)")
+	fs.DurationVar(&c.promRange, "prom-range", 90*24*time.Hour, "range length when -prom-start is not set")
+	fs.DurationVar(&c.promStep, "prom-step", time.Hour, "query resolution step")
+	fs.StringVar(&c.promX, "prom-x-label", "", "series label holding the X coordinate")
+	fs.StringVar(&c.promY, "prom-y-label", "", "series label holding the Y coordinate")
+	fs.StringVar(&c.influxURL, "influx-url", "", "read from this InfluxDB 2.x server (e.g. http://influxdb:8086) instead of -in")
+	fs.StringVar(&c.influxOrg, "influx-org", "", "InfluxDB organization")
+	fs.StringVar(&c.influxToken, "influx-token", "", "InfluxDB API token (default $INFLUX_TOKEN)")
+	fs.StringVar(&c.influxQuery, "influx-query", "", "Flux query for -influx-url; columns.x/y/value/size name its result columns")
+	fs.IntVar(&c.httpRetries, "http-retries", 2, "extra attempts for failed HTTP requests (network errors, 429, 5xx)")
+	fs.StringVar(&c.cols.X, "x-col", "", "header name (or JSON key) of the X column (default: 1st column / \"x\")")
+	fs.StringVar(&c.cols.Y, "y-col", "", "header name (or JSON key) of the Y column (default: 2nd column / \"y\")")
+	fs.StringVar(&c.cols.Value, "value-col", "", "header name (or JSON key) of the Value column (default: 3rd column / \"value\")")
+	fs.Var(&c.compute, "compute", "computed column \"name = expression\" over other columns with + - * / and parentheses, e.g. \"rate = errors / requests\" (repeatable); use it like any column")
+	fs.StringVar(&c.cols.Filter, "filter", "", "keep only the records matching a condition, e.g. 'value > 0 && extras.region == \"EU\"' (fields x, y, value, size, extras.<column>)")
+	fs.StringVar(&c.sizeCols, "size-cols", "", "comma-separated Size columns (e.g. volume,revenue) the page can switch the point size between; the first is the Size column")
+	fs.StringVar(&c.valueCols, "value-cols", "", "comma-separated Value columns (e.g. errors,latency,cost), one metric each: the page switches between them, the static outputs show the first")
+	fs.StringVar(&c.cols.Size, "size-col", "", "header name (or JSON key) of the Size column (default: 4th column / \"size\")")
+	fs.StringVar(&c.periodPattern, "period-pattern", "", "regular expression deriving each slice name from its file name, e.g. \"(?P<year>\\d{4})_Q(?P<quarter>\\d)\" for 2025-Q1 (groups year with quarter, week, month, day; else period or the first group)")
+	fs.BoolVar(&c.strict, "strict", false, "fail on X/Y cells that are not integers and Value/Size cells that are not numbers, with file, line and column, instead of reading them as categories or 0")
+	fs.BoolVar(&c.signed, "signed", false, "read negative values as data (e.g. profit and loss) instead of no data, drawn on a scale diverging at 0; empty cells have no data")
+	fs.StringVar(&c.dup, "dup", grovegrid.DupLast, "policy for a cell given more than once in a slice: "+strings.Join(grovegrid.DupPolicies, "|"))
+	fs.IntVar(&c.workers, "workers", 0, "number of input files parsed in parallel (default: one per CPU)")
+	fs.StringVar(&c.cacheDir, "cache-dir", "", "keep parsed input files in this directory and reparse only files that changed (disabled if empty)")
+	fs.BoolVar(&c.verbose, "v", false, "report progress on stderr: files found and the time of each phase")
+	fs.BoolVar(&c.veryVerbose, "vv", false, "like -v, plus the records parsed and rows skipped per file")
+	fs.StringVar(&c.logFormat, "log-format", "text", "format of the diagnostics on stderr: text (key=value) or json (one object per line)")
+	fs.StringVar(&c.logLevel, "log-level", "warn", "lowest level of the diagnostics on stderr: debug, info, warn or error (-v and -vv lower it to info and debug)")
+	fs.StringVar(&c.sqlite, "sqlite", "", "read records from this SQLite database instead of -in (needs -query)")
+	fs.StringVar(&c.dsn, "dsn", "", "read records from a PostgreSQL (postgres://...) or MySQL (mysql://...) database instead of -in (needs -query)")
+	fs.StringVar(&c.query, "query", "", "SQL query (text/template, see -query-var) returning the record columns plus -month-col or -date-col")
+	c.queryVars = kvFlag{}
+	fs.Var(c.queryVars, "query-var", "key=value bound as a query parameter where {{.key}} stands in -query (repeatable)")
+	fs.StringVar(&c.monthCol, "month-col", "month", "query result column that names each record's slice")
+	fs.StringVar(&c.dateCol, "date-col", "", "column (or query result column) with each record's date, for long-format input holding all slices in one file; records are grouped into -period slices by it")
+	fs.StringVar(&c.timezone, "timezone", "", "IANA time zone (e.g. Europe/Berlin) of generated_at, of dates without a zone and of the periods dates are grouped into (default: local time for generated_at, UTC for dates)")
+	fs.StringVar(&c.period, "period", grovegrid.PeriodMonth, "period -date-col groups records into: "+strings.Join(grovegrid.Periods, "|"))
+}
+
+// registerBuild registers the flags that add derived slices and datasets.
+func (c *commonFlags) registerBuild(fs *flag.FlagSet) {
+	fs.StringVar(&c.gaps, "gaps", grovegrid.GapsKeep, "months missing between monthly slices: keep (only listed in meta.gaps), empty (insert a slice without data) or interpolate (insert cells interpolated between the months around the gap)")
+	fs.StringVar(&c.aggregate, "aggregate", "", "comma-separated functions ("+strings.Join(grovegrid.AggregateFuncs, "|")+") adding a slice that combines each cell across all slices, e.g. \"ALL (mean)\"")
+	fs.StringVar(&c.normalize, "normalize", "", "color each slice by its normalized values: minmax (0..1), zscore (standard scores, drawn like -diff) or rank (percentile); tooltips keep the raw values")
+	fs.IntVar(&c.highlight, "highlight", 0, "outline the points with the N highest and the N lowest values of each slice; 0 = off")
+	fs.Float64Var(&c.anomaly, "anomaly", 0, "flag the cells whose value deviates more than K spreads (see -anomaly-method) from the cell's values across the slices; 0 = off")
+	fs.StringVar(&c.anomalyMethod, "anomaly-method", grovegrid.AnomalyStdDev, "spread of -anomaly: stddev (from the mean) or mad (median absolute deviation from the median, robust to a few extreme months)")
+	fs.StringVar(&c.marginals, "marginals", "", "add the sum and mean of every row and column to each slice and plot this one (sum or mean) as bars along the axes")
+	fs.IntVar(&c.smooth, "smooth", 0, "add a slice per slice with the rolling mean of each cell over the N months ending with it (N slices if the slices are not months), e.g. \"2025-03 (3-month mean)\"; 0 = off")
+	fs.StringVar(&c.diff, "diff", "", "two slices FROM,TO (e.g. 2025-01,2025-06): add a dataset of the change of each cell between them, colored on a scale diverging at 0")
+	fs.BoolVar(&c.yoy, "yoy", false, "add a dataset of the change of each cell from the same month a year earlier (e.g. \"YOY (2025-03 vs 2024-03)\") for each month that has one, colored on a scale diverging at 0")
+	fs.BoolVar(&c.trend, "trend", false, "add a dataset of the linear trend (slope per month, or per slice if the slices are not months) of each cell across the slices, colored on a scale diverging at 0")
+}
+
+// registerOutput registers the flags that shape the page and the payload.
+func (c *commonFlags) registerOutput(fs *flag.FlagSet) {
+	fs.StringVar(&c.title, "title", "GroveGrid", "Page title, with the placeholders {period_range}, {generated_date} and {months_count} filled in")
+	fs.StringVar(&c.template, "template", "", "page template file, or a directory of partials overriding blocks of the embedded template")
+	fs.StringVar(&c.theme, "theme", "classic", "page theme: "+strings.Join(themeNames(), "|"))
+	fs.StringVar(&c.scheme, "color-scheme", "dark", "initial page color scheme: dark|light (viewers can toggle)")
+	fs.BoolVar(&c.externalData, "external-data", false, "load data.json with fetch instead of inlining it (the page must be served over HTTP)")
+	fs.StringVar(&c.dataFormat, "data-format", "json", "encoding of the data file fetched with -external-data: json or msgpack (MessagePack, implies -external-data)")
+	fs.BoolVar(&c.compact, "compact", false, "write minified JSON (inline payload, -json-out and other JSON outputs)")
+	fs.BoolVar(&c.sparse, "sparse", false, "list only the cells present in the input in each slice's heat instead of the full grid")
+	fs.StringVar(&c.layout, "layout", grovegrid.LayoutRows, "dataset encoding of the JSON payload: rows (heat triples and point objects) or flat (parallel arrays, smaller for big grids)")
+	fs.StringVar(&c.colors, "colors", "", "gradient for values > 0, low to high: a palette ("+strings.Join(grovegrid.PaletteNames(), "|")+", _r reverses) or \"#rgb,#rgb,...\"")
+	fs.BoolVar(&c.cbSafe, "colorblind-safe", false, "reject gradients running from red to green; without -colors use okabe-ito (safe palettes: "+strings.Join(grovegrid.ColorblindSafe, ", ")+")")
+	fs.StringVar(&c.scale, "scale", grovegrid.ScaleLinear, "color scale for values > 0: "+strings.Join(grovegrid.Scales, "|"))
+	fs.IntVar(&c.bins, "bins", 0, "number of gradient bins; the gradient is resampled to this many colors (default: one per color)")
+	fs.StringVar(&c.thresholds, "thresholds", "", "ascending class lower bounds, e.g. 0,50,80,95: one color per class (implies -scale threshold)")
+	fs.Func("center", "pivot of -scale diverging (default: middle of the value range)", func(s string) error {
+		v, err := strconv.ParseFloat(s, 64)
+		c.center = &v
+		return err
+	})
+	fs.StringVar(&c.valueRange, "range", grovegrid.RangeGlobal, "scale colors and sizes to the range of all slices (global) or of each slice (month)")
+	fs.StringVar(&c.clamp, "clamp", "", "clamp the color scale to two percentiles of the values > 0, e.g. p1,p99; the values beyond take the end colors")
+	fs.StringVar(&c.legend.Title, "legend-title", "", "legend title (default: the value column)")
+	fs.StringVar(&c.legend.Unit, "legend-unit", "", "unit appended to legend and tooltip values, e.g. \" ms\"")
+	fs.IntVar(&c.legend.TickCount, "legend-ticks", 0, "number of evenly spaced values labeled on the legend gradient")
+	fs.StringVar(&c.legendTicks, "legend-tick-values", "", "comma-separated values to label on the legend gradient instead of -legend-ticks")
+	fs.StringVar(&c.tooltip, "tooltip", "", "tooltip text for cells with data, e.g. '{{.Value}} errors on {{.Extras.host}}' (fields .X, .Y, .Value, .Size, .Month, .Extras.<column>)")
+	fs.StringVar(&c.templateDir, "template-dir", "", "optional directory with an index.html overriding the embedded template")
+	fs.StringVar(&c.urlCol, "url-col", grovegrid.DefaultURLColumn, "header name (or JSON key) of the extra column whose http(s) URLs the page opens on a click on the cell, e.g. a Grafana panel")
+	fs.BoolVar(&c.typedExtras, "typed-extras", false, "infer the type of each extras column (number, date, boolean or string) and write the extras typed, with the types in meta.extra_types")
+	fs.BoolVar(&c.viewExtras, "view-extras", false, "let the page color and size the points by any numeric extras column instead of Value and Size")
+	fs.StringVar(&c.xLabel, "x-label", "", "display name of the X axis (default: the X column header)")
+	fs.StringVar(&c.yLabel, "y-label", "", "display name of the Y axis (default: the Y column header)")
+	fs.StringVar(&c.valueLabel, "value-label", "", "display name of the values (default: the Value column header)")
+	fs.StringVar(&c.sizeLabel, "size-label", "", "display name of the sizes (default: the Size column header)")
+	fs.StringVar(&c.axisNames, "axis-names", "", "CSV (axis,index,name) or JSON ({\"x\": {\"1\": \"Berlin\"}}) file naming the coordinates of the axes")
+	fs.StringVar(&c.annotations, "annotations", "", "CSV (month,x,y,text[,color]) file of callouts on cells, e.g. 2025-03,4,2,incident #1234")
+	c.vars = kvFlag{}
+	fs.Var(c.vars, "var", "key=value available as {{.Vars.key}} in the page template (repeatable)")
+	fs.BoolVar(&c.reproducible, "reproducible", false, "byte-identical output for identical input: omit generated_at, or take it from $SOURCE_DATE_EPOCH if set")
+}
+
+// registerServe registers the flags that keep the command running.
+func (c *commonFlags) registerServe(fs *flag.FlagSet) {
+	fs.BoolVar(&c.watch, "watch", false, "keep running and regenerate when files in -in change")
+	fs.DurationVar(&c.watchInterval, "watch-interval", 2*time.Second, "polling interval for -watch")
+}
+
+// remoteInput returns the flag that selects an input -watch has no files to
+// poll for, such as "-dsn", or "" if the input is -in or -sqlite.
+func (c *commonFlags) remoteInput() string {
+	switch {
+	case c.in == "-":
+		return "-in -"
+	case isURL(c.in):
+		return "-in " + c.in
+	case c.promURL != "":
+		return "-prom-url"
+	case c.influxURL != "":
+		return "-influx-url"
+	case c.sheetsID != "":
+		return "-sheets-id"
+	case c.sqlite == "" && c.dsn != "":
+		return "-dsn"
+	}
+	return ""
+}
+
+// isURL reports whether -in names a URL rather than a directory.
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// watchTarget returns the directory -watch polls.
+func (c *commonFlags) watchTarget() string {
+	if c.sqlite != "" {
+		return filepath.Dir(c.sqlite)
+	}
+	return c.in
+}
+
+// parse parses args into fs, applies the config file and returns the build
+// options.
+func (c *commonFlags) parse(fs *flag.FlagSet, args []string) (grovegrid.Options, error) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return grovegrid.Options{}, err
+		}
+		return grovegrid.Options{}, &exitError{code: exitUsage, err: err, quiet: true}
+	}
+	cfg, err := loadConfig(c.config)
+	if err != nil {
+		return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("config: %w", err))
+	}
+	if err := cfg.applyFlags(fs); err != nil {
+		return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("config: %w", err))
+	}
+	logger, err := c.newLogger()
+	if err != nil {
+		return grovegrid.Options{}, err
+	}
+	opts := grovegrid.Options{InDir: c.in, Title: c.title, Columns: c.cols, Sparse: c.sparse, Strict: c.strict, Signed: c.signed, TypedExtras: c.typedExtras, ViewExtras: c.viewExtras, URLColumn: c.urlCol, Duplicates: c.dup, Gaps: c.gaps, Trend: c.trend, Period: c.period, PeriodPattern: c.periodPattern, YoY: c.yoy, Smooth: c.smooth, Highlight: c.highlight, Normalize: c.normalize, Anomaly: c.anomaly, AnomalyMethod: c.anomalyMethod, Marginals: c.marginals, Layout: c.layout, Workers: c.workers, CacheDir: c.cacheDir,
+		Logger: logger,
+		XLabel: c.xLabel, YLabel: c.yLabel, ValueLabel: c.valueLabel, SizeLabel: c.sizeLabel}
+	cfg.apply(&opts)
+	if err := checkTheme(c.theme); err != nil {
+		return grovegrid.Options{}, err
+	}
+	themePalette(&opts, c.theme)
+	if c.colors != "" {
+		if opts.GradColors, err = grovegrid.ParsePalette(c.colors); err != nil {
+			return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-colors: %w", err))
+		}
+	}
+	if c.cbSafe {
+		if len(opts.GradColors) == 0 {
+			opts.GradColors = grovegrid.Palettes["okabe-ito"]
+		}
+		if grovegrid.RedGreen(opts.GradColors) {
+			return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-colorblind-safe: gradient %v runs from red to green", opts.GradColors))
+		}
+	}
+	if !slices.Contains(grovegrid.Scales, c.scale) {
+		return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-scale %q: want one of %v", c.scale, grovegrid.Scales))
+	}
+	opts.Scale = c.scale
+	if c.thresholds != "" {
+		if opts.Thresholds, err = parseThresholds(c.thresholds); err != nil {
+			return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-thresholds: %w", err))
+		}
+		opts.Scale = grovegrid.ScaleThreshold
+	}
+	if c.thresholds == "" && c.scale == grovegrid.ScaleThreshold {
+		return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-scale threshold needs -thresholds"))
+	}
+	opts.Center = c.center
+	if c.valueRange != grovegrid.RangeGlobal && c.valueRange != grovegrid.RangeMonth {
+		return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-range %q: want global or month", c.valueRange))
+	}
+	opts.Range = c.valueRange
+	if c.clamp != "" {
+		if opts.Clamp, err = parseClamp(c.clamp); err != nil {
+			return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-clamp: %w", err))
+		}
+	}
+	if c.axisNames != "" {
+		if opts.AxisNames, err = grovegrid.LoadAxisNames(c.axisNames); err != nil {
+			return grovegrid.Options{}, withCode(exitInput, fmt.Errorf("-axis-names: %w", err))
+		}
+	}
+	if c.annotations != "" {
+		if opts.Annotations, err = grovegrid.LoadAnnotations(c.annotations); err != nil {
+			return grovegrid.Options{}, withCode(exitInput, fmt.Errorf("-annotations: %w", err))
+		}
+	}
+	opts.Legend = c.legend
+	opts.Generator = generator()
+	if err := grovegrid.CheckTitle(c.title); err != nil {
+		return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-%w", err))
+	}
+	if err := grovegrid.CheckTooltip(c.tooltip); err != nil {
+		return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-%w", err))
+	}
+	opts.Tooltip = c.tooltip
+	if c.legendTicks != "" {
+		if opts.Legend.Ticks, err = parseThresholds(c.legendTicks); err != nil {
+			return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-legend-tick-values: %w", err))
+		}
+	}
+	if c.layout != grovegrid.LayoutRows && c.layout != grovegrid.LayoutFlat {
+		return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-layout %q: want rows or flat", c.layout))
+	}
+	if !slices.Contains(grovegrid.DupPolicies, c.dup) {
+		return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-dup %q: want one of %v", c.dup, grovegrid.DupPolicies))
+	}
+	if !slices.Contains(grovegrid.GapPolicies, c.gaps) {
+		return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-gaps %q: want one of %v", c.gaps, grovegrid.GapPolicies))
+	}
+	if c.normalize != "" && !slices.Contains(grovegrid.Normalizations, c.normalize) {
+		return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-normalize %q: want one of %v", c.normalize, grovegrid.Normalizations))
+	}
+	if c.anomaly < 0 {
+		return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-anomaly %g: want a positive number", c.anomaly))
+	}
+	if !slices.Contains(grovegrid.AnomalyMethods, c.anomalyMethod) {
+		return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-anomaly-method %q: want one of %v", c.anomalyMethod, grovegrid.AnomalyMethods))
+	}
+	if c.marginals != "" && !slices.Contains(grovegrid.MarginalKinds, c.marginals) {
+		return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-marginals %q: want one of %v", c.marginals, grovegrid.MarginalKinds))
+	}
+	if c.aggregate != "" {
+		for _, fn := range strings.Split(c.aggregate, ",") {
+			fn = strings.TrimSpace(fn)
+			if !slices.Contains(grovegrid.AggregateFuncs, fn) {
+				return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-aggregate %q: want one of %v", fn, grovegrid.AggregateFuncs))
+			}
+			if !slices.Contains(opts.Aggregates, fn) {
+				opts.Aggregates = append(opts.Aggregates, fn)
+			}
+		}
+	}
+	if c.diff != "" {
+		from, to, ok := strings.Cut(c.diff, ",")
+		if !ok || strings.TrimSpace(from) == "" || strings.TrimSpace(to) == "" {
+			return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-diff %q: want two slices FROM,TO", c.diff))
+		}
+		opts.Diff = [2]string{strings.TrimSpace(from), strings.TrimSpace(to)}
+	}
+	if c.valueCols != "" {
+		if c.cols.Value != "" {
+			return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-value-cols cannot be used with -value-col"))
+		}
+		for _, col := range strings.Split(c.valueCols, ",") {
+			if col = strings.TrimSpace(col); col != "" && !slices.Contains(opts.ValueColumns, col) {
+				opts.ValueColumns = append(opts.ValueColumns, col)
+			}
+		}
+		if len(opts.ValueColumns) > 0 {
+			opts.Columns.Value = opts.ValueColumns[0]
+		}
+	}
+	opts.Columns.Computed = append(opts.Columns.Computed, c.compute...)
+	if err := grovegrid.CheckComputed(opts.Columns.Computed); err != nil {
+		return grovegrid.Options{}, withCode(exitUsage, err)
+	}
+	if err := grovegrid.CheckFilter(opts.Columns.Filter); err != nil {
+		return grovegrid.Options{}, withCode(exitUsage, err)
+	}
+	if c.sizeCols != "" {
+		if c.cols.Size != "" {
+			return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-size-cols cannot be used with -size-col"))
+		}
+		for _, col := range strings.Split(c.sizeCols, ",") {
+			if col = strings.TrimSpace(col); col != "" && !slices.Contains(opts.SizeColumns, col) {
+				opts.SizeColumns = append(opts.SizeColumns, col)
+			}
+		}
+		if len(opts.SizeColumns) > 0 {
+			opts.Columns.Size = opts.SizeColumns[0]
+		}
+	}
+	if !slices.Contains(grovegrid.Periods, c.period) {
+		return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-period %q: want one of %v", c.period, grovegrid.Periods))
+	}
+	if _, err := regexp.Compile(c.periodPattern); err != nil {
+		return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-period-pattern: %w", err))
+	}
+	if c.smooth < 0 {
+		return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-smooth %d: want a positive number", c.smooth))
+	}
+	if c.highlight < 0 {
+		return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-highlight %d: want a positive number", c.highlight))
+	}
+	if c.workers < 0 {
+		return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-workers %d: want a positive number", c.workers))
+	}
+	if c.bins < 0 {
+		return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-bins %d: want a positive number", c.bins))
+	}
+	opts.Bins = c.bins
+	if c.dataFormat != "json" && c.dataFormat != "msgpack" {
+		return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-data-format %q: want json or msgpack", c.dataFormat))
+	}
+	if c.scheme != "dark" && c.scheme != "light" {
+		return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-color-scheme %q: want dark or light", c.scheme))
+	}
+	for k, v := range cfg.Vars {
+		if _, ok := c.vars[k]; !ok {
+			c.vars[k] = v
+		}
+	}
+	if c.timezone != "" {
+		if c.location, err = time.LoadLocation(c.timezone); err != nil {
+			return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("-timezone: %w", err))
+		}
+		opts.Location = c.location
+	}
+	opts.Reproducible = c.reproducible
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		n, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return grovegrid.Options{}, withCode(exitUsage, fmt.Errorf("SOURCE_DATE_EPOCH %q: want seconds since 1970", epoch))
+		}
+		opts.GeneratedAt = time.Unix(n, 0).UTC()
+	}
+	if opts.Source, err = c.source(); err != nil {
+		return grovegrid.Options{}, err
+	}
+	if c.dateCol != "" {
+		opts.Columns.Date = c.dateCol
+	}
+	// a query buckets by its own date column
+	if src, isSQL := opts.Source.(*grovegrid.SQLSource); isSQL {
+		if src.DateColumn == "" {
+			src.DateColumn = opts.Columns.Date
+		}
+		opts.Columns.Date = ""
+	}
+	return opts, nil
+}
+
+// parseThresholds parses a comma-separated ascending list of numbers.
+func parseThresholds(s string) ([]float64, error) {
+	var out []float64
+	for _, part := range strings.Split(s, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", part)
+		}
+		if len(out) > 0 && v <= out[len(out)-1] {
+			return nil, fmt.Errorf("%v is not above %v; want ascending values", v, out[len(out)-1])
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// parseClamp parses the percentiles of -clamp, "p1,p99" or "1,99".
+func parseClamp(s string) ([2]float64, error) {
+	lo, hi, ok := strings.Cut(s, ",")
+	if !ok {
+		return [2]float64{}, fmt.Errorf("%q: want two percentiles, e.g. p1,p99", s)
+	}
+	var out [2]float64
+	for i, part := range []string{lo, hi} {
+		v, err := strconv.ParseFloat(strings.TrimPrefix(strings.TrimSpace(part), "p"), 64)
+		if err != nil {
+			return [2]float64{}, fmt.Errorf("%q is not a percentile", part)
+		}
+		out[i] = v
+	}
+	if out[0] < 0 || out[1] > 100 || out[0] >= out[1] {
+		return [2]float64{}, fmt.Errorf("%q: want 0 <= low < high <= 100", s)
+	}
+	return out, nil
+}
+
+// newLogger sets up c.logger from -log-format and -log-level, lowered to
+// Info by -v and to Debug by -vv, and makes it the logger main reports
+// errors to.
+func (c *commonFlags) newLogger() (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.logLevel)); err != nil {
+		return nil, withCode(exitUsage, fmt.Errorf("-log-level: unknown level %q (want debug, info, warn or error)", c.logLevel))
+	}
+	if c.verbose && level > slog.LevelInfo {
+		level = slog.LevelInfo
+	}
+	if c.veryVerbose {
+		level = slog.LevelDebug
+	}
+	ho := &slog.HandlerOptions{Level: level}
+	switch c.logFormat {
+	case "text":
+		c.logger = slog.New(slog.NewTextHandler(os.Stderr, ho))
+	case "json":
+		c.logger = slog.New(slog.NewJSONHandler(os.Stderr, ho))
+	default:
+		return nil, withCode(exitUsage, fmt.Errorf("-log-format: unknown format %q (want text or json)", c.logFormat))
+	}
+	logger = c.logger
+	return c.logger, nil
+}
+
+func (c *commonFlags) pageOptions() pageOptions {
+	po := pageOptions{vars: c.vars, scheme: c.scheme, compact: c.compact, dataFormat: c.dataFormat}
+	if c.externalData || c.dataFormat == "msgpack" {
+		po.dataURL = "data." + c.dataFormat
+	}
+	return po
+}
+
+// flagSets lists the flag sets of all commands; config keys must be known to
+// at least one of them.
+var flagSets = []func() *flag.FlagSet{
+	func() *flag.FlagSet { fs, _ := newBuildFlags(); return fs },
+	func() *flag.FlagSet { fs, _ := newServeFlags(); return fs },
+	func() *flag.FlagSet { fs, _ := newValidateFlags(); return fs },
+	func() *flag.FlagSet { fs, _ := newDiffFlags(); return fs },
+	func() *flag.FlagSet { fs, _ := newExportFlags(); return fs },
+}
+
+func knownFlag(name string) bool {
+	for _, newFS := range flagSets {
+		if newFS().Lookup(name) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestFlagGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		newFS   func() *flag.FlagSet
+		has     []string
+		hasNot  []string
+		misused []string
+	}{
+		{"build", flagSets[0], []string{"in", "gaps", "theme", "watch", "out"}, nil, nil},
+		{"serve", flagSets[1], []string{"in", "gaps", "theme", "watch", "addr"}, []string{"out"}, []string{"-out", "x"}},
+		{"validate", flagSets[2], []string{"in", "strict", "compact", "value-min"}, []string{"gaps", "theme", "colors", "watch"}, []string{"-theme", "dark"}},
+		{"diff", flagSets[3], []string{"in", "normalize", "compact"}, []string{"theme", "watch"}, []string{"-watch"}},
+		{"export", flagSets[4], []string{"in", "gaps", "colors", "format"}, []string{"watch"}, []string{"-watch"}},
+	}
+	for _, tt := range tests {
+		fs := tt.newFS()
+		for _, n := range tt.has {
+			if fs.Lookup(n) == nil {
+				t.Errorf("%s: no -%s", tt.name, n)
+			}
+		}
+		for _, n := range tt.hasNot {
+			if fs.Lookup(n) != nil {
+				t.Errorf("%s: has -%s", tt.name, n)
+			}
+		}
+		if tt.misused != nil {
+			fs.SetOutput(io.Discard)
+			if err := fs.Parse(tt.misused); err == nil {
+				t.Errorf("%s %v: got no error", tt.name, tt.misused)
+			}
+		}
+	}
+}
+
+func TestFlagGroupDefaults(t *testing.T) {
+	// the flags validate does not register still have their defaults
+	fs, f := newValidateFlags()
+	if _, err := f.parse(fs, []string{"-in", t.TempDir()}); err != nil {
+		t.Fatal(err)
+	}
+	if f.theme != "classic" || f.dup != "last" || f.watchInterval == 0 {
+		t.Errorf("theme %q, dup %q, watch interval %v: want the defaults", f.theme, f.dup, f.watchInterval)
+	}
+}
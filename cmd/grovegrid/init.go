@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// starterConfig is the grovegrid.yaml written by init.
+const starterConfig = `# grovegrid.yaml: defaults for every grovegrid command. Top-level keys are
+# flag names (see grovegrid <command> -h); flags given on the command line win.
+in: ./data
+out: ./out
+title: "GroveGrid {period_range}"
+
+# Select columns by header name instead of position 1..4:
+# columns:
+#   x: row
+#   y: position
+#   value: condition
+#   size: height
+
+# Per-slice overrides:
+# months:
+#   2025-03:
+#     notes: "datacenter migration this month"
+`
+
+// starterCSV is the sample slice written by init.
+const starterCSV = `row,position,condition,height,species
+1,1,3.2,35,Y
+1,2,0,28,Y
+1,3,1.4,15,Cu
+2,1,4.0,42,P
+2,2,,30,P
+2,3,2.5,22,Cu
+`
+
+type initFlags struct {
+	force bool
+}
+
+func newInitFlags() (*flag.FlagSet, *initFlags) {
+	fs := newFlagSet("grovegrid init")
+	f := &initFlags{}
+	fs.BoolVar(&f.force, "force", false, "overwrite existing files")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: grovegrid init [flags] [DIR]")
+		fs.PrintDefaults()
+	}
+	return fs, f
+}
+
+// runInit writes a starter grovegrid.yaml and a sample slice in data/ into
+// the directory given (the working directory by default), so that build
+// works right away. Existing files are kept unless -force is set.
+func runInit(args []string) error {
+	fset, f := newInitFlags()
+	if err := fset.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &exitError{code: exitUsage, err: err, quiet: true}
+	}
+	if fset.NArg() > 1 {
+		return withCode(exitUsage, fmt.Errorf("init: want at most one directory, got %d arguments", fset.NArg()))
+	}
+	dir := "."
+	if fset.NArg() == 1 {
+		dir = fset.Arg(0)
+	}
+	files := []struct{ path, content string }{
+		{filepath.Join(dir, defaultConfigFile), starterConfig},
+		{filepath.Join(dir, "data", time.Now().Format("2006-01")+".csv"), starterCSV},
+	}
+	if !f.force {
+		for _, f := range files {
+			if _, err := os.Stat(f.path); err == nil {
+				return withCode(exitWrite, fmt.Errorf("%s exists (use -force to overwrite)", f.path))
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return withCode(exitWrite, err)
+			}
+		}
+	}
+	for _, f := range files {
+		if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+			return withCode(exitWrite, err)
+		}
+		if err := os.WriteFile(f.path, []byte(f.content), 0o644); err != nil {
+			return withCode(exitWrite, err)
+		}
+		fmt.Println("Wrote", f.path)
+	}
+	next := "grovegrid build"
+	if dir != "." {
+		next = "cd " + dir + " && " + next
+	}
+	fmt.Println("Next:", next+", then open out/index.html")
+	return nil
+}
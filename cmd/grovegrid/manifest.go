@@ -0,0 +1,95 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// manifestFile is the name of the -manifest file in -out.
+const manifestFile = "manifest.json"
+
+// manifest lists the files a build wrote, for deployment tools to verify
+// them and sync only the changed ones.
+type manifest struct {
+	GeneratedAt string          `json:"generated_at,omitempty"`
+	Files       []manifestEntry `json:"files"`
+}
+
+type manifestEntry struct {
+	// Path is relative to the manifest, with forward slashes.
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// writeManifest writes manifest.json into -out, listing the files in the
+// outputs of f modified since the build started at start. Older files, such
+// as the pages of slices no longer in the input, are left out. generatedAt
+// is the Meta.GeneratedAt of the build.
+func writeManifest(f *buildFlags, start time.Time, generatedAt string) error {
+	// file times have coarser clocks than time.Now
+	since := start.Truncate(time.Second)
+	path := filepath.Join(f.out, manifestFile)
+	seen := map[string]bool{}
+	m := manifest{GeneratedAt: generatedAt, Files: []manifestEntry{}}
+	add := func(p string, info fs.FileInfo) error {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return err
+		}
+		if seen[abs] || p == path || info.ModTime().Before(since) {
+			return nil
+		}
+		seen[abs] = true
+		sum, err := fileSHA256(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(f.out, p)
+		if err != nil {
+			rel = abs
+		}
+		m.Files = append(m.Files, manifestEntry{Path: filepath.ToSlash(rel), Size: info.Size(), SHA256: sum})
+		return nil
+	}
+	for _, o := range f.outputs() {
+		if *o.path == "" {
+			continue
+		}
+		err := filepath.WalkDir(*o.path, func(p string, e fs.DirEntry, err error) error {
+			if err != nil || e.IsDir() {
+				return err
+			}
+			info, err := e.Info()
+			if err != nil {
+				return err
+			}
+			return add(p, info)
+		})
+		if err != nil && !(o.file && os.IsNotExist(err)) {
+			return withCode(exitWrite, err)
+		}
+	}
+	sort.Slice(m.Files, func(i, j int) bool { return m.Files[i].Path < m.Files[j].Path })
+	return writeJSON(path, m, f.compact)
+}
+
+// fileSHA256 returns the hex SHA-256 of the file at path.
+func fileSHA256(path string) (string, error) {
+	r, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
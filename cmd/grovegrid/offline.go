@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// assetTagRe matches the <script> and <style> blocks, so that tags in
+	// their code are skipped, and the <link> tags.
+	assetTagRe = regexp.MustCompile(`(?is)<script\b([^>]*)>(.*?)</script\s*>|<style\b[^>]*>.*?</style\s*>|<link\b[^>]*>`)
+	srcRe      = regexp.MustCompile(`(?is)\s+src\s*=\s*("([^"]*)"|'([^']*)')`)
+	hrefRe     = regexp.MustCompile(`(?is)\bhref\s*=\s*("([^"]*)"|'([^']*)')`)
+	relRe      = regexp.MustCompile(`(?is)\brel\s*=\s*["']?stylesheet\b`)
+	// loadAttrRe matches the attributes that make no sense on inline code.
+	loadAttrRe = regexp.MustCompile(`(?is)\s+(integrity|crossorigin|async|defer|referrerpolicy)(\s*=\s*("[^"]*"|'[^']*'|[^\s>]+))?`)
+)
+
+// assetInliner replaces the scripts and stylesheets a rendered page loads
+// from URLs or files by their content (-offline), so the page works
+// without a network. The embedded template inlines its libraries already;
+// custom templates may load theirs from a CDN.
+type assetInliner struct {
+	// dirs are searched in order for assets given by a relative path.
+	dirs   []string
+	client *http.Client
+	cache  map[string][]byte
+}
+
+func newAssetInliner(timeout time.Duration, dirs ...string) *assetInliner {
+	return &assetInliner{dirs: dirs, client: &http.Client{Timeout: timeout}, cache: map[string][]byte{}}
+}
+
+// inline returns html with its external scripts and stylesheets inlined.
+func (a *assetInliner) inline(html []byte) ([]byte, error) {
+	var err error
+	html = assetTagRe.ReplaceAllFunc(html, func(tag []byte) []byte {
+		if err != nil {
+			return tag
+		}
+		m := assetTagRe.FindSubmatch(tag)
+		switch {
+		case m[1] != nil: // <script>
+			src := srcRe.FindSubmatch(m[1])
+			if src == nil || len(strings.TrimSpace(string(m[2]))) > 0 {
+				return tag
+			}
+			b, aerr := a.asset(string(src[2]) + string(src[3]))
+			if aerr != nil {
+				err = withCode(exitTemplate, fmt.Errorf("-offline: %w", aerr))
+				return tag
+			}
+			attrs := loadAttrRe.ReplaceAllString(srcRe.ReplaceAllString(string(m[1]), ""), "")
+			return []byte("<script" + attrs + ">" + inlineScriptContent(b) + "</script>")
+		case bytes.HasPrefix(bytes.ToLower(tag), []byte("<link")):
+			href := hrefRe.FindSubmatch(tag)
+			if href == nil || !relRe.Match(tag) {
+				return tag
+			}
+			b, aerr := a.asset(string(href[2]) + string(href[3]))
+			if aerr != nil {
+				err = withCode(exitTemplate, fmt.Errorf("-offline: %w", aerr))
+				return tag
+			}
+			css := strings.NewReplacer("</style", "<\\/style", "</STYLE", "<\\/STYLE").Replace(string(b))
+			return []byte("<style>" + css + "</style>")
+		}
+		return tag
+	})
+	return html, err
+}
+
+// asset returns the content of the script or stylesheet at ref, a URL or a
+// path relative to one of the dirs of a.
+func (a *assetInliner) asset(ref string) ([]byte, error) {
+	ref = absoluteURL(ref)
+	if b, ok := a.cache[ref]; ok {
+		return b, nil
+	}
+	var b []byte
+	var err error
+	if isRemoteAsset(ref) {
+		b, err = a.fetch(ref)
+	} else {
+		b, err = a.file(ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.cache[ref] = b
+	return b, nil
+}
+
+// isRemoteAsset reports whether the script or stylesheet at ref is loaded
+// from a URL rather than a path.
+func isRemoteAsset(ref string) bool {
+	return strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://") || strings.HasPrefix(ref, "//")
+}
+
+// absoluteURL gives a protocol-relative ref, such as //cdn.example/x.js,
+// the https scheme.
+func absoluteURL(ref string) string {
+	if strings.HasPrefix(ref, "//") {
+		return "https:" + ref
+	}
+	return ref
+}
+
+func (a *assetInliner) fetch(u string) ([]byte, error) {
+	resp, err := a.client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (a *assetInliner) file(ref string) ([]byte, error) {
+	// a relative URL may carry a query or fragment
+	if i := strings.IndexAny(ref, "?#"); i >= 0 {
+		ref = ref[:i]
+	}
+	rel := filepath.FromSlash(strings.TrimPrefix(ref, "/"))
+	for _, dir := range a.dirs {
+		b, err := os.ReadFile(filepath.Join(dir, rel))
+		if err == nil {
+			return b, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("%s not found in %s", ref, strings.Join(a.dirs, ", "))
+}
+
+// assetDirs returns the directories -offline looks up relative assets in:
+// the directory of the page template, then -out, where the page would
+// load them from.
+func (f *buildFlags) assetDirs() []string {
+	var dirs []string
+	switch {
+	case f.template != "":
+		if st, err := os.Stat(f.template); err == nil && st.IsDir() {
+			dirs = append(dirs, f.template)
+		} else {
+			dirs = append(dirs, filepath.Dir(f.template))
+		}
+	case f.templateDir != "":
+		dirs = append(dirs, f.templateDir)
+	}
+	return append(dirs, f.out)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aplgr/grovegrid/internal/web"
+	"github.com/aplgr/grovegrid/pkg/grovegrid"
+)
+
+// page is what the page template sees: the Output (.Meta, .Datasets), the
+// slice names, user variables from -var and the vendored scripts.
+type page struct {
+	*grovegrid.Output
+	Title  string
+	Months []string
+	Vars   map[string]string
+	// Scheme is the initial color scheme, "dark" or "light".
+	Scheme string
+	// DataURL, if set, is fetched instead of inlining the Payload; it is
+	// JSON or, if DataFormat is "msgpack", MessagePack.
+	DataURL    string
+	DataFormat string
+	// Payload is the Output as JSON for <script type="application/json">.
+	Payload   template.JS
+	EChartsJS template.JS
+	AlpineJS  template.JS
+}
+
+// legacyPlaceholders maps the placeholders of templates written for the
+// old string substitution to template actions.
+var legacyPlaceholders = strings.NewReplacer(
+	"{{TITLE}}", "{{.Title}}",
+	"{{INLINE_JSON}}", "{{.Payload}}",
+	"{{ECHARTS_JS}}", "{{.EChartsJS}}",
+	"{{ALPINE_JS}}", "{{.AlpineJS}}",
+)
+
+// parseTemplate parses a page template. Old-style placeholders such as
+// {{TITLE}} keep working.
+func parseTemplate(name string, src []byte) (*template.Template, error) {
+	t, err := template.New(name).Parse(legacyPlaceholders.Replace(string(src)))
+	if err != nil {
+		return nil, withCode(exitTemplate, err)
+	}
+	return t, nil
+}
+
+// pageOptions are the page settings that are not part of the Output.
+type pageOptions struct {
+	vars    map[string]string
+	scheme  string
+	dataURL string
+	compact bool
+	// dataFormat is "json" or "msgpack", the encoding of the file at dataURL.
+	dataFormat string
+	// assets, if set, inlines the scripts and stylesheets the page loads.
+	assets *assetInliner
+	// sri, if set, adds integrity values to the remote scripts and
+	// stylesheets the page loads.
+	sri *sriInjector
+	// csp, if set, moves the inline scripts and styles of the page to
+	// files; the payload is then fetched from dataURL.
+	csp *cspWriter
+}
+
+// encodeData encodes the payload fetched from po.dataURL.
+func encodeData(v interface{}, po pageOptions) ([]byte, error) {
+	if po.dataFormat == "msgpack" {
+		return marshalMsgpack(v)
+	}
+	return marshalJSON(v, po.compact)
+}
+
+// renderHTML executes the page template for out.
+func renderHTML(t *template.Template, out *grovegrid.Output, po pageOptions) ([]byte, error) {
+	var bb []byte
+	if po.dataURL == "" {
+		var err error
+		if bb, err = marshalJSON(out, po.compact); err != nil {
+			return nil, err
+		}
+	}
+	if po.vars == nil {
+		po.vars = map[string]string{}
+	}
+	p := page{
+		Output:     out,
+		Title:      out.Meta.Title,
+		Months:     out.Meta.Months,
+		Vars:       po.vars,
+		Scheme:     orDefault(po.scheme, "dark"),
+		DataURL:    po.dataURL,
+		DataFormat: orDefault(po.dataFormat, "json"),
+		// json.Marshal escapes <, > and &, so the payload cannot end the script
+		Payload:   template.JS(bb),
+		EChartsJS: template.JS(inlineScriptContent(web.EChartsJS)),
+		AlpineJS:  template.JS(inlineScriptContent(web.AlpineJS)),
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, p); err != nil {
+		return nil, withCode(exitTemplate, fmt.Errorf("render template: %w", err))
+	}
+	html := buf.Bytes()
+	if po.assets != nil {
+		var err error
+		if html, err = po.assets.inline(html); err != nil {
+			return nil, err
+		}
+	}
+	if po.sri != nil {
+		var err error
+		if html, err = po.sri.inject(html); err != nil {
+			return nil, err
+		}
+	}
+	if po.csp != nil {
+		return po.csp.externalize(html)
+	}
+	return html, nil
+}
+
+// readTemplate returns the page template with theme applied. path is a
+// template file or a directory of partials (see readTemplateDir); dir is a
+// directory holding an index.html (-template-dir). With both empty the
+// embedded template is used.
+func readTemplate(path, dir, theme string) (*template.Template, error) {
+	if path == "" && dir != "" {
+		path = filepath.Join(dir, "index.html")
+	}
+	if path == "" {
+		t, err := embeddedTemplate()
+		if err != nil {
+			return nil, err
+		}
+		return t, applyTheme(t, theme)
+	}
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil, withCode(exitTemplate, fmt.Errorf("read template: %w", err))
+	}
+	if st.IsDir() {
+		return readTemplateDir(path, theme)
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, withCode(exitTemplate, fmt.Errorf("read template: %w", err))
+	}
+	t, err := parseTemplate(filepath.Base(path), src)
+	if err != nil {
+		return nil, err
+	}
+	return t, applyTheme(t, theme)
+}
+
+func embeddedTemplate() (*template.Template, error) {
+	src, err := fs.ReadFile(web.Templates, "templates/index.html")
+	if err != nil {
+		return nil, withCode(exitTemplate, fmt.Errorf("read template: %w", err))
+	}
+	return parseTemplate("index.html", src)
+}
+
+// readTemplateDir uses dir/index.html as the page, or the embedded page if
+// dir has none, and adds every other *.html file of dir to the set. Their
+// {{define}}s replace the blocks of the page ("head", "header" and "footer"
+// in the embedded one), so branding can be added without copying the page.
+// Partials are added after the theme and may override it.
+func readTemplateDir(dir, theme string) (*template.Template, error) {
+	var t *template.Template
+	var err error
+	if _, serr := os.Stat(filepath.Join(dir, "index.html")); serr == nil {
+		src, rerr := os.ReadFile(filepath.Join(dir, "index.html"))
+		if rerr != nil {
+			return nil, withCode(exitTemplate, fmt.Errorf("read template: %w", rerr))
+		}
+		t, err = parseTemplate("index.html", src)
+	} else {
+		t, err = embeddedTemplate()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := applyTheme(t, theme); err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, withCode(exitTemplate, err)
+	}
+	for _, f := range files {
+		name := filepath.Base(f)
+		if name == "index.html" {
+			continue
+		}
+		src, err := os.ReadFile(f)
+		if err != nil {
+			return nil, withCode(exitTemplate, fmt.Errorf("read template: %w", err))
+		}
+		if _, err := t.New(name).Parse(legacyPlaceholders.Replace(string(src))); err != nil {
+			return nil, withCode(exitTemplate, err)
+		}
+	}
+	return t, nil
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+func inlineScriptContent(b []byte) string {
+	r := strings.NewReplacer("</script", "<\\/script", "</SCRIPT", "<\\/SCRIPT")
+	return r.Replace(string(b))
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aplgr/grovegrid/pkg/grovegrid"
+)
+
+func TestRenderHTMLEscapesPayload(t *testing.T) {
+	const hostile = "</script><img src=x onerror=alert(1)> a|b `c`"
+	in := t.TempDir()
+	csv := "x,y,value,size,remark\n1,1,3,1,\"" + hostile + "\"\n"
+	if err := os.WriteFile(filepath.Join(in, "2025-01.csv"), []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := grovegrid.Build(grovegrid.Options{InDir: in, Title: "t", XLabel: hostile, Months: map[string]grovegrid.MonthOptions{"2025-01": {Notes: hostile}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := readTemplate("", "", "classic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	html, err := renderHTML(tmpl, out, pageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(html), "<img src=x") {
+		t.Error("the page holds markup from the input")
+	}
+
+	// the payload decodes, so no </script> of the input ends it early
+	payload, _ := sitePayloadBytes(t, html)
+	md := payload.Datasets["2025-01"]
+	if payload.Meta.Labels.X != hostile || md.Notes != hostile {
+		t.Errorf("label %q and notes %q, want both %q", payload.Meta.Labels.X, md.Notes, hostile)
+	}
+	if ex, _ := md.Points[0]["extras"].(map[string]interface{}); ex["remark"] != hostile {
+		t.Errorf("extras %v, want remark %q", md.Points[0]["extras"], hostile)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+//go:generate sh -c "go run . schema > ../../docs/output.schema.json"
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aplgr/grovegrid/pkg/grovegrid"
+)
+
+// runSchema prints the JSON Schema of the payload, published as
+// docs/output.schema.json.
+func runSchema(args []string) error {
+	fs := newFlagSet("grovegrid schema")
+	compact := fs.Bool("compact", false, "write minified JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: grovegrid schema [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &exitError{code: exitUsage, err: err, quiet: true}
+	}
+	b, err := marshalJSON(grovegrid.OutputSchema(), *compact)
+	if err != nil {
+		return err
+	}
+	os.Stdout.Write(append(b, '\n'))
+	return nil
+}
+
+type validateOutputFlags struct {
+	format  string
+	compact bool
+}
+
+func newValidateOutputFlags() (*flag.FlagSet, *validateOutputFlags) {
+	fs := newFlagSet("grovegrid validate-output")
+	f := &validateOutputFlags{}
+	fs.StringVar(&f.format, "format", "text", "report format: text (one line per issue) or json")
+	fs.BoolVar(&f.compact, "compact", false, "write minified JSON with -format json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: grovegrid validate-output [flags] FILE... (- for stdin)")
+		fs.PrintDefaults()
+	}
+	return fs, f
+}
+
+// outputIssues are the schema issues of one payload file.
+type outputIssues struct {
+	Path   string                  `json:"path"`
+	Issues []grovegrid.SchemaIssue `json:"issues"`
+}
+
+// runValidateOutput checks JSON payloads, such as data.json, against the
+// schema of the payload and prints the issues found; it fails with
+// exitInvalid if there are any.
+func runValidateOutput(args []string) error {
+	fs, f := newValidateOutputFlags()
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &exitError{code: exitUsage, err: err, quiet: true}
+	}
+	if fs.NArg() == 0 {
+		return withCode(exitUsage, fmt.Errorf("validate-output: want one or more payload files"))
+	}
+	if f.format != "text" && f.format != "json" {
+		return withCode(exitUsage, fmt.Errorf("-format %q: want text or json", f.format))
+	}
+	var reports []outputIssues
+	total := 0
+	for _, path := range fs.Args() {
+		var b []byte
+		var err error
+		if path == "-" {
+			b, err = io.ReadAll(os.Stdin)
+		} else {
+			b, err = os.ReadFile(path)
+		}
+		if err != nil {
+			return withCode(exitInput, err)
+		}
+		issues, err := grovegrid.ValidateOutput(b)
+		if err != nil {
+			return withCode(exitParse, fmt.Errorf("%s: %w", path, err))
+		}
+		if issues == nil {
+			issues = []grovegrid.SchemaIssue{}
+		}
+		reports = append(reports, outputIssues{Path: path, Issues: issues})
+		total += len(issues)
+	}
+
+	if f.format == "json" {
+		b, err := marshalJSON(reports, f.compact)
+		if err != nil {
+			return err
+		}
+		os.Stdout.Write(append(b, '\n'))
+	} else {
+		for _, r := range reports {
+			for _, is := range r.Issues {
+				fmt.Printf("%s: %s: %s\n", r.Path, is.Path, is.Message)
+			}
+		}
+		fmt.Printf("%d files: %d issues\n", len(reports), total)
+	}
+	if total > 0 {
+		return &exitError{code: exitInvalid, err: fmt.Errorf("%d issues", total), quiet: true}
+	}
+	return nil
+}
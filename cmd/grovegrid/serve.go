@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aplgr/grovegrid/pkg/grovegrid"
+)
+
+type serveFlags struct {
+	commonFlags
+	addr string
+}
+
+func newServeFlags() (*flag.FlagSet, *serveFlags) {
+	fs := newFlagSet("grovegrid serve")
+	f := &serveFlags{}
+	f.register(fs, groupInput|groupBuild|groupOutput|groupServe)
+	fs.StringVar(&f.addr, "addr", ":8080", "listen address")
+	return fs, f
+}
+
+// liveReloadScript reloads the page when the server sends a "reload" event.
+const liveReloadScript = `<script>new EventSource("/events").addEventListener("reload", () => location.reload());</script>`
+
+// site holds the rendered page and payload served by runServe.
+type site struct {
+	mu   sync.RWMutex
+	html []byte
+	data []byte
+
+	clientsMu sync.Mutex
+	clients   map[chan struct{}]bool
+}
+
+func (s *site) rebuild(f *serveFlags, opts grovegrid.Options) error {
+	out, err := grovegrid.Build(opts)
+	if err != nil {
+		return err
+	}
+	tmpl, err := readTemplate(f.template, f.templateDir, f.theme)
+	if err != nil {
+		return err
+	}
+	html, err := renderHTML(tmpl, out, f.pageOptions())
+	if err != nil {
+		return err
+	}
+	if f.watch {
+		html = injectBeforeBodyEnd(html, liveReloadScript)
+	}
+	var data []byte
+	if f.dataFormat == "msgpack" {
+		data, err = marshalMsgpack(out)
+	} else {
+		data, err = json.Marshal(out)
+	}
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.html, s.data = html, data
+	s.mu.Unlock()
+	return nil
+}
+
+// notify tells every connected browser to reload.
+func (s *site) notify() {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	for c := range s.clients {
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *site) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	c := make(chan struct{}, 1)
+	s.clientsMu.Lock()
+	s.clients[c] = true
+	s.clientsMu.Unlock()
+	defer func() {
+		s.clientsMu.Lock()
+		delete(s.clients, c)
+		s.clientsMu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-c:
+			fmt.Fprint(w, "event: reload\ndata: {}\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// runServe builds the page in memory and serves it at / and the payload at
+// /data.json (/data.msgpack with -data-format msgpack) until interrupted. With -watch, inputs are rebuilt on change
+// and open pages reload via server-sent events on /events.
+func runServe(args []string) error {
+	fs, f := newServeFlags()
+	opts, err := f.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	defer f.close()
+
+	s := &site{clients: map[chan struct{}]bool{}}
+	if err := s.rebuild(f, opts); err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(s.html)
+	})
+	mux.HandleFunc("GET /index.html", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/", http.StatusMovedPermanently)
+	})
+	contentType := map[string]string{"json": "application/json", "msgpack": "application/vnd.msgpack"}[f.dataFormat]
+	mux.HandleFunc("GET /data."+f.dataFormat, func(w http.ResponseWriter, r *http.Request) {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		w.Header().Set("Content-Type", contentType)
+		w.Write(s.data)
+	})
+	if f.watch {
+		mux.HandleFunc("GET /events", s.serveEvents)
+	}
+
+	srv := &http.Server{Addr: f.addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	if f.watch {
+		go watchDir(ctx, f.watchTarget(), f.watchInterval, func() {
+			if err := s.rebuild(f, opts); err != nil {
+				f.logger.Error("rebuild failed", "err", err)
+				return
+			}
+			fmt.Println("Rebuilt, reloading browsers")
+			s.notify()
+		})
+	}
+
+	fmt.Printf("Serving on http://%s\n", displayAddr(f.addr))
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// injectBeforeBodyEnd inserts snippet before the last </body>, or appends it.
+func injectBeforeBodyEnd(html []byte, snippet string) []byte {
+	s := string(html)
+	if i := strings.LastIndex(strings.ToLower(s), "</body>"); i >= 0 {
+		return []byte(s[:i] + snippet + "\n" + s[i:])
+	}
+	return []byte(s + snippet)
+}
+
+func displayAddr(addr string) string {
+	if len(addr) > 0 && addr[0] == ':' {
+		return "localhost" + addr
+	}
+	return addr
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/aplgr/grovegrid/internal/web"
+	"github.com/aplgr/grovegrid/pkg/grovegrid"
+)
+
+// sitePage is one entry of the site index.
+type sitePage struct {
+	Month string
+	Href  string
+	Cells int
+	Notes string
+	// Metrics links the pages of the slice by the other value columns of
+	// -value-cols.
+	Metrics []siteLink
+}
+
+// siteLink is a link of the site index.
+type siteLink struct {
+	Name string
+	Href string
+}
+
+// sitePageName returns the file name of the page of month by the metric
+// at index i of -value-cols: month.html for the first metric, else
+// month.metric.html. The URL escapes it with url.PathEscape.
+func sitePageName(month string, metrics []string, i int) string {
+	if i == 0 {
+		return month
+	}
+	return month + "." + metrics[i]
+}
+
+// writeSite writes one page per month (and per metric of -value-cols),
+// each inlining only its own slice and linking to the others through
+// Meta.Pages and Meta.MetricPages, plus an index page.
+func writeSite(dir string, tmpl *template.Template, out *grovegrid.Output, po pageOptions) error {
+	metrics := out.Meta.Metrics
+	payloads := []*grovegrid.Output{out}
+	for _, k := range metrics[min(1, len(metrics)):] {
+		payloads = append(payloads, out.Metrics[k])
+	}
+	// pages[i] maps the slices of payloads[i] to their pages
+	pages := make([]map[string]string, len(payloads))
+	for i, p := range payloads {
+		pages[i] = make(map[string]string, len(p.Meta.Months))
+		for _, m := range p.Meta.Months {
+			pages[i][m] = url.PathEscape(sitePageName(m, metrics, i)) + ".html"
+		}
+	}
+
+	index := make([]sitePage, 0, len(out.Meta.Months))
+	for i, p := range payloads {
+		for _, m := range p.Meta.Months {
+			md := p.Datasets[m]
+			page := *p
+			page.Meta.Pages = pages[i]
+			page.Datasets = map[string]*grovegrid.MonthData{m: md}
+			page.Metrics = nil
+			if len(metrics) > 1 {
+				page.Meta.Metrics = metrics
+				if i > 0 {
+					page.Meta.Metric = metrics[i]
+				}
+				page.Meta.MetricPages = make(map[string]string, len(metrics))
+				for j, k := range metrics {
+					if href, ok := pages[j][m]; ok {
+						page.Meta.MetricPages[k] = href
+					}
+				}
+			}
+			name := sitePageName(m, metrics, i)
+			pagePO := po
+			if po.dataURL != "" {
+				ext := "." + orDefault(po.dataFormat, "json")
+				pagePO.dataURL = url.PathEscape(name) + ext
+				p, err := outPath(dir, name+ext)
+				if err != nil {
+					return err
+				}
+				if err := writeData(p, &page, po); err != nil {
+					return err
+				}
+			}
+			html, err := renderHTML(tmpl, &page, pagePO)
+			if err != nil {
+				return err
+			}
+			p, err := outPath(dir, name+".html")
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(p, html, 0o644); err != nil {
+				return withCode(exitWrite, err)
+			}
+			if i > 0 {
+				continue
+			}
+
+			cells := 0
+			for _, pt := range md.Points {
+				if v, _ := pt["value"].(float64); v >= 0 {
+					cells++
+				}
+			}
+			entry := sitePage{Month: m, Href: pages[0][m], Cells: cells, Notes: md.Notes}
+			for j, k := range metrics[min(1, len(metrics)):] {
+				if href, ok := pages[j+1][m]; ok {
+					entry.Metrics = append(entry.Metrics, siteLink{Name: k, Href: href})
+				}
+			}
+			index = append(index, entry)
+		}
+	}
+
+	src, err := fs.ReadFile(web.Templates, "templates/site.html")
+	if err != nil {
+		return withCode(exitTemplate, err)
+	}
+	t, err := template.New("site").Parse(string(src))
+	if err != nil {
+		return withCode(exitTemplate, fmt.Errorf("site template: %w", err))
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, map[string]interface{}{"Title": out.Meta.Title, "Pages": index}); err != nil {
+		return withCode(exitTemplate, err)
+	}
+	html := buf.Bytes()
+	if po.csp != nil {
+		if html, err = po.csp.externalize(html); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), html, 0o644); err != nil {
+		return withCode(exitWrite, err)
+	}
+	return nil
+}
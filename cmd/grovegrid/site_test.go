@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aplgr/grovegrid/pkg/grovegrid"
+)
+
+var payloadRe = regexp.MustCompile(`(?s)<script id="payload" type="application/json">(.*?)</script>`)
+
+// sitePayload returns the payload inlined into the site page file and
+// its size as compact JSON.
+func sitePayload(t *testing.T, path string) (*grovegrid.Output, int) {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sitePayloadBytes(t, b)
+}
+
+// sitePayloadBytes is sitePayload for the page html.
+func sitePayloadBytes(t *testing.T, html []byte) (*grovegrid.Output, int) {
+	t.Helper()
+	m := payloadRe.FindSubmatch(html)
+	if m == nil {
+		t.Fatal("no payload")
+	}
+	var out grovegrid.Output
+	if err := json.Unmarshal(m[1], &out); err != nil {
+		t.Fatal(err)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, m[1]); err != nil {
+		t.Fatal(err)
+	}
+	return &out, compact.Len()
+}
+
+func TestWriteSiteMetrics(t *testing.T) {
+	in := t.TempDir()
+	for _, month := range []string{"2024-01", "2024-02", "2024-03"} {
+		var rows strings.Builder
+		rows.WriteString("row,position,errors,latency\n")
+		rows.WriteString("1,1,5,40\n")
+		for x := 1; x <= 20; x++ {
+			for y := 2; y <= 20; y++ {
+				fmt.Fprintf(&rows, "%d,%d,%d,%d\n", x, y, x+y, 10*x)
+			}
+		}
+		if err := os.WriteFile(filepath.Join(in, month+".csv"), []byte(rows.String()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out, err := grovegrid.Build(grovegrid.Options{InDir: in, ValueColumns: []string{"errors", "latency"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, err := json.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := readTemplate("", "", "classic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := writeSite(dir, tmpl, out, pageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.html"))
+	for i := range files {
+		files[i] = filepath.Base(files[i])
+	}
+	sort.Strings(files)
+	want := []string{"2024-01.html", "2024-01.latency.html", "2024-02.html", "2024-02.latency.html", "2024-03.html", "2024-03.latency.html", "index.html"}
+	if strings.Join(files, " ") != strings.Join(want, " ") {
+		t.Fatalf("pages %v, want %v", files, want)
+	}
+
+	for _, tc := range []struct {
+		file, metric string
+		value        float64
+	}{{"2024-02.html", "", 5}, {"2024-02.latency.html", "latency", 40}} {
+		page, size := sitePayload(t, filepath.Join(dir, tc.file))
+		if len(page.Datasets) != 1 || page.Datasets["2024-02"] == nil {
+			t.Errorf("%s: datasets %d, want only 2024-02", tc.file, len(page.Datasets))
+		}
+		if page.Metrics != nil {
+			t.Errorf("%s: inlines the payloads of %d other metrics", tc.file, len(page.Metrics))
+		}
+		if page.Meta.Metric != tc.metric {
+			t.Errorf("%s: metric %q, want %q", tc.file, page.Meta.Metric, tc.metric)
+		}
+		if got := page.Meta.MetricPages["latency"]; got != "2024-02.latency.html" {
+			t.Errorf("%s: latency page %q", tc.file, got)
+		}
+		if got := page.Meta.Pages["2024-03"]; got != strings.Replace(tc.file, "2024-02", "2024-03", 1) {
+			t.Errorf("%s: page of 2024-03 is %q", tc.file, got)
+		}
+		var found bool
+		for _, p := range page.Datasets["2024-02"].Points {
+			if p["x"] == float64(1) && p["y"] == float64(1) {
+				found = p["value"] == tc.value
+			}
+		}
+		if !found {
+			t.Errorf("%s: cell 1,1 does not hold %v", tc.file, tc.value)
+		}
+		// one slice of one metric out of three slices of two
+		if size*4 > len(full) {
+			t.Errorf("%s: payload %d bytes of %d for the whole site", tc.file, size, len(full))
+		}
+	}
+
+	index, err := os.ReadFile(filepath.Join(dir, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(index), `href="2024-03.latency.html"`) {
+		t.Error("index does not link the latency pages")
+	}
+}
+
+func TestOutputsStayInDir(t *testing.T) {
+	in := t.TempDir()
+	if err := os.WriteFile(filepath.Join(in, "2024-01.csv"), []byte("row,position,value\n1,1,3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := grovegrid.Build(grovegrid.Options{InDir: in})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a payload whose slice name escapes, as a custom Source could give
+	const name = "../escaped"
+	out.Meta.Months = []string{name}
+	out.Datasets = map[string]*grovegrid.MonthData{name: out.Datasets["2024-01"]}
+
+	root := t.TempDir()
+	dir := filepath.Join(root, "out")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := readTemplate("", "", "classic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeSite(dir, tmpl, out, pageOptions{}); exitCode(err) != exitWrite {
+		t.Errorf("writeSite: got %v, want a write error", err)
+	}
+	f := &buildFlags{svgOut: filepath.Join(dir, "svg"), gridCSVDir: filepath.Join(dir, "grid"), echartsOut: filepath.Join(dir, "echarts")}
+	if err := writeSideOutputs(f, out); exitCode(err) != exitWrite {
+		t.Errorf("writeSideOutputs: got %v, want a write error", err)
+	}
+	for _, d := range []string{root, dir} {
+		if escaped, _ := filepath.Glob(filepath.Join(d, "escaped*")); len(escaped) > 0 {
+			t.Errorf("wrote %v outside the output directories", escaped)
+		}
+	}
+
+	for name, ok := range map[string]bool{"2024-01.svg": true, "DIFF (b - a).json": true, "../x.svg": false, "a/b.svg": false, "..": false} {
+		if _, err := outPath(dir, name); (err == nil) != ok {
+			t.Errorf("outPath(%q): got %v, want ok %v", name, err, ok)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+
+	"github.com/aplgr/grovegrid/pkg/grovegrid"
+)
+
+// source returns the configured non-directory input, or nil to read -in.
+func (c *commonFlags) source() (grovegrid.Source, error) {
+	if name := c.remoteInput(); name != "" && c.watch {
+		return nil, withCode(exitUsage, fmt.Errorf("-watch polls local files and cannot be used with %s; rerun the build instead, e.g. from cron", name))
+	}
+	if c.in == "-" {
+		month := c.month
+		if month == "" {
+			now := time.Now()
+			if c.location != nil {
+				now = now.In(c.location)
+			}
+			month = now.Format("2006-01")
+		}
+		return &grovegrid.ReaderSource{R: os.Stdin, Name: month, Format: c.inFormat}, nil
+	}
+	if isURL(c.in) {
+		return &grovegrid.URLSource{
+			URL:     c.in,
+			Client:  &http.Client{Timeout: c.httpTimeout},
+			Retries: c.httpRetries,
+		}, nil
+	}
+	if c.promURL != "" {
+		if c.promQuery == "" || c.promX == "" || c.promY == "" {
+			return nil, withCode(exitUsage, fmt.Errorf("-prom-url needs -prom-query, -prom-x-label and -prom-y-label"))
+		}
+		end := time.Now()
+		if c.promEnd != "" {
+			t, err := grovegrid.ParseDateIn(c.promEnd, c.location)
+			if err != nil {
+				return nil, withCode(exitUsage, fmt.Errorf("-prom-end: %w", err))
+			}
+			end = t
+		}
+		start := end.Add(-c.promRange)
+		if c.promStart != "" {
+			t, err := grovegrid.ParseDateIn(c.promStart, c.location)
+			if err != nil {
+				return nil, withCode(exitUsage, fmt.Errorf("-prom-start: %w", err))
+			}
+			start = t
+		}
+		return &grovegrid.PrometheusSource{
+			URL:    c.promURL,
+			Query:  c.promQuery,
+			Start:  start,
+			End:    end,
+			Step:   c.promStep,
+			XLabel: c.promX,
+			YLabel: c.promY,
+			Client: &http.Client{Timeout: c.httpTimeout},
+		}, nil
+	}
+	if c.influxURL != "" {
+		if c.influxQuery == "" {
+			return nil, withCode(exitUsage, fmt.Errorf("-influx-url needs -influx-query"))
+		}
+		token := c.influxToken
+		if token == "" {
+			token = os.Getenv("INFLUX_TOKEN")
+		}
+		return &grovegrid.InfluxSource{
+			URL:    c.influxURL,
+			Org:    c.influxOrg,
+			Token:  token,
+			Query:  c.influxQuery,
+			Client: &http.Client{Timeout: c.httpTimeout},
+		}, nil
+	}
+	if c.sheetsID != "" {
+		if c.sheetsKey == "" {
+			return nil, withCode(exitUsage, fmt.Errorf("-sheets-id needs -sheets-key"))
+		}
+		key, err := os.ReadFile(c.sheetsKey)
+		if err != nil {
+			return nil, withCode(exitUsage, fmt.Errorf("-sheets-key: %w", err))
+		}
+		var tabs []string
+		for _, t := range strings.Split(c.sheetsTabs, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				tabs = append(tabs, t)
+			}
+		}
+		return &grovegrid.SheetsSource{
+			SpreadsheetID: c.sheetsID,
+			Tabs:          tabs,
+			Credentials:   key,
+			Client:        &http.Client{Timeout: c.httpTimeout},
+		}, nil
+	}
+	if c.sqlite == "" && c.dsn == "" {
+		return nil, nil
+	}
+	if c.sqlite != "" && c.dsn != "" {
+		return nil, withCode(exitUsage, fmt.Errorf("use either -sqlite or -dsn"))
+	}
+	if c.query == "" {
+		return nil, withCode(exitUsage, fmt.Errorf("-sqlite and -dsn need -query"))
+	}
+	driver, dsn := "sqlite", sqliteURI(c.sqlite)
+	if c.sqlite != "" {
+		if _, err := os.Stat(c.sqlite); err != nil {
+			return nil, fmt.Errorf("%w: %w", grovegrid.ErrInputDir, err)
+		}
+	} else {
+		var err error
+		if driver, dsn, err = splitDSN(c.dsn); err != nil {
+			return nil, withCode(exitUsage, err)
+		}
+	}
+	query, args, err := expandQuery(c.query, c.queryVars, driver == "pgx")
+	if err != nil {
+		return nil, withCode(exitUsage, err)
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	c.db = db
+	return &grovegrid.SQLSource{DB: db, Query: query, Args: args, PeriodColumn: c.monthCol, DateColumn: c.dateCol}, nil
+}
+
+// close closes the database -sqlite or -dsn opened, if any.
+func (c *commonFlags) close() {
+	if c.db != nil {
+		c.db.Close()
+	}
+}
+
+// sqliteURI returns the read-only URI of the SQLite file at path, with
+// characters such as ?, # and % in the path escaped.
+func sqliteURI(path string) string {
+	p := filepath.ToSlash(path)
+	if filepath.IsAbs(path) && !strings.HasPrefix(p, "/") {
+		p = "/" + p // C:/grove.db
+	}
+	return (&url.URL{Scheme: "file", Path: p, RawQuery: "mode=ro"}).String()
+}
+
+// splitDSN picks the database/sql driver from the DSN scheme.
+func splitDSN(dsn string) (driver, rest string, err error) {
+	switch {
+	case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+		return "pgx", dsn, nil
+	case strings.HasPrefix(dsn, "mysql://"):
+		return "mysql", strings.TrimPrefix(dsn, "mysql://"), nil
+	}
+	return "", "", fmt.Errorf("-dsn must start with postgres://, postgresql:// or mysql://")
+}
+
+// expandQuery renders the query as a text/template with the -query-var
+// values. The values are not pasted into the SQL: each {{.key}} becomes a
+// placeholder, $1, $2, … if numbered is set (PostgreSQL) and ? otherwise,
+// and the values are returned as the arguments they bind, in order.
+func expandQuery(query string, vars map[string]string, numbered bool) (string, []any, error) {
+	t, err := template.New("query").Option("missingkey=error").Parse(query)
+	if err != nil {
+		return "", nil, fmt.Errorf("-query: %w", err)
+	}
+	// each value is first rendered as a marker holding its key, which
+	// cannot occur in the query text or in a flag value
+	marked := make(map[string]string, len(vars))
+	for k := range vars {
+		marked[k] = "\x00" + k + "\x00"
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, marked); err != nil {
+		return "", nil, fmt.Errorf("-query: %w", err)
+	}
+	parts := strings.Split(b.String(), "\x00")
+	var (
+		out  strings.Builder
+		args []any
+		nums = map[string]int{}
+	)
+	for i, part := range parts {
+		if i%2 == 0 {
+			out.WriteString(part)
+			continue
+		}
+		if prev := parts[i-1]; strings.HasSuffix(prev, "'") || strings.HasSuffix(prev, `"`) {
+			return "", nil, fmt.Errorf("-query: {{.%s}} is bound as a parameter; remove the quotes around it", part)
+		}
+		if !numbered {
+			args = append(args, vars[part])
+			out.WriteString("?")
+			continue
+		}
+		n, ok := nums[part]
+		if !ok {
+			args = append(args, vars[part])
+			n = len(args)
+			nums[part] = n
+		}
+		fmt.Fprintf(&out, "$%d", n)
+	}
+	return out.String(), args, nil
+}
+
+// listFlag collects repeated flags; a comma also separates values.
+type listFlag []string
+
+func (f *listFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *listFlag) Set(s string) error {
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			*f = append(*f, part)
+		}
+	}
+	return nil
+}
+
+// kvFlag collects repeated key=value flags.
+type kvFlag map[string]string
+
+func (f kvFlag) String() string {
+	parts := make([]string, 0, len(f))
+	for k, v := range f {
+		parts = append(parts, k+"="+v)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f kvFlag) Set(s string) error {
+	for _, part := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(part, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return fmt.Errorf("want key=value, got %q", part)
+		}
+		f[strings.TrimSpace(k)] = v
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aplgr/grovegrid/pkg/grovegrid"
+)
+
+func TestExpandQuery(t *testing.T) {
+	vars := map[string]string{"since": "2024-01-01", "site": "x' OR '1'='1"}
+	const query = "SELECT * FROM trees WHERE day >= {{.since}} AND site = {{.site}} OR day = {{.since}}"
+	tests := []struct {
+		numbered bool
+		want     string
+		args     []any
+	}{
+		{false, "SELECT * FROM trees WHERE day >= ? AND site = ? OR day = ?", []any{"2024-01-01", "x' OR '1'='1", "2024-01-01"}},
+		{true, "SELECT * FROM trees WHERE day >= $1 AND site = $2 OR day = $1", []any{"2024-01-01", "x' OR '1'='1"}},
+	}
+	for _, tt := range tests {
+		got, args, err := expandQuery(query, vars, tt.numbered)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("numbered %v: got %q %v, want %q %v", tt.numbered, got, args, tt.want, tt.args)
+		}
+	}
+	if _, _, err := expandQuery("SELECT * FROM trees WHERE day >= '{{.since}}'", vars, false); err == nil {
+		t.Error("quoted {{.since}}: got no error")
+	}
+	if _, _, err := expandQuery("SELECT {{.missing}}", vars, false); err == nil {
+		t.Error("{{.missing}}: got no error")
+	}
+}
+
+func TestSQLiteSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grove.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE trees (taken DATE, site TEXT, row INTEGER, position INTEGER, value REAL)",
+		"INSERT INTO trees VALUES ('2024-03-05', 'north', 1, 1, 3), ('2024-03-20', 'north', 1, 2, 4), ('2024-04-02', 'south', 2, 1, 5)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	fs, f := newBuildFlags()
+	opts, err := f.parse(fs, []string{
+		"-sqlite", path, "-month-col", "taken", "-period", "day",
+		"-query", "SELECT taken, row, position, value FROM trees WHERE site = {{.site}}",
+		"-query-var", "site=north",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.close()
+	out, err := grovegrid.Build(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the DATE values name days, and only the north rows match
+	if got := out.Meta.Months; !reflect.DeepEqual(got, []string{"2024-03-05", "2024-03-20"}) {
+		t.Fatalf("slices %v, want [2024-03-05 2024-03-20]", got)
+	}
+
+	// a value is bound, not pasted into the SQL
+	fs, f = newBuildFlags()
+	opts, err = f.parse(fs, []string{
+		"-sqlite", path, "-month-col", "taken",
+		"-query", "SELECT taken, row, position, value FROM trees WHERE site = {{.site}}",
+		"-query-var", "site=x' OR '1'='1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.close()
+	if out, err := grovegrid.Build(opts); err == nil && len(out.Meta.Months) > 0 {
+		t.Errorf("injected value matched rows: months %v", out.Meta.Months)
+	}
+}
+
+func TestWatchRemoteInput(t *testing.T) {
+	for _, args := range [][]string{
+		{"-in", "-"},
+		{"-in", "https://example.com/grove.csv"},
+		{"-dsn", "postgres://grove@db/grove", "-query", "SELECT 1"},
+		{"-prom-url", "http://prometheus:9090", "-prom-query", "up", "-prom-x-label", "a", "-prom-y-label", "b"},
+		{"-influx-url", "http://influx:8086", "-influx-query", "from(bucket: \"b\")"},
+		{"-sheets-id", "abc", "-sheets-key", "key.json"},
+	} {
+		fs, f := newBuildFlags()
+		_, err := f.parse(fs, append([]string{"-watch"}, args...))
+		if err == nil || exitCode(err) != exitUsage || !strings.Contains(err.Error(), "-watch") {
+			t.Errorf("%v: got %v, want a -watch usage error", args, err)
+		}
+		f.close()
+	}
+
+	fs, f := newBuildFlags()
+	if _, err := f.parse(fs, []string{"-watch", "-in", t.TempDir()}); err != nil {
+		t.Errorf("-watch -in dir: %v", err)
+	}
+}
+
+func TestSQLitePath(t *testing.T) {
+	dir := t.TempDir()
+	plain, path := filepath.Join(dir, "grove.db"), filepath.Join(dir, "grove?v=1#x%41.db")
+	db, err := sql.Open("sqlite", plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE trees (month TEXT, row INTEGER, position INTEGER, value REAL)",
+		"INSERT INTO trees VALUES ('2024-03', 1, 1, 3)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+	if err := os.Rename(plain, path); err != nil {
+		t.Fatal(err)
+	}
+
+	fs, f := newBuildFlags()
+	opts, err := f.parse(fs, []string{"-sqlite", path, "-query", "SELECT month, row, position, value FROM trees"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.close()
+	out, err := grovegrid.Build(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out.Meta.Months; len(got) != 1 || got[0] != "2024-03" {
+		t.Errorf("months %v, want [2024-03]", got)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	integrityRe   = regexp.MustCompile(`(?is)\bintegrity\s*=`)
+	crossoriginRe = regexp.MustCompile(`(?is)\bcrossorigin\b`)
+)
+
+// sriInjector adds Subresource Integrity to the scripts and stylesheets a
+// rendered page loads from other origins (-sri): each is fetched at build
+// time and its tag given the integrity value of its content, so browsers
+// refuse it if the CDN serves something else later. With a lock file
+// (-sri-lock) the values are recorded on the first build and later builds
+// fail if an asset no longer matches its recorded value.
+type sriInjector struct {
+	assets   *assetInliner
+	lockPath string
+	// lock maps the URLs of the assets to their integrity values.
+	lock  map[string]string
+	added bool
+}
+
+// newSRIInjector returns an sriInjector with the lock file at lockPath
+// loaded, if lockPath is set and the file exists.
+func newSRIInjector(timeout time.Duration, lockPath string) (*sriInjector, error) {
+	s := &sriInjector{assets: newAssetInliner(timeout), lockPath: lockPath, lock: map[string]string{}}
+	if lockPath == "" {
+		return s, nil
+	}
+	b, err := os.ReadFile(lockPath)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, withCode(exitInput, err)
+	}
+	if err := json.Unmarshal(b, &s.lock); err != nil {
+		return nil, withCode(exitParse, fmt.Errorf("-sri-lock %s: %w", lockPath, err))
+	}
+	return s, nil
+}
+
+// inject returns html with integrity and crossorigin attributes added to
+// its remote <script src> and <link rel="stylesheet"> tags. Tags that
+// declare an integrity value already are left as they are.
+func (s *sriInjector) inject(html []byte) ([]byte, error) {
+	var err error
+	html = assetTagRe.ReplaceAllFunc(html, func(tag []byte) []byte {
+		if err != nil {
+			return tag
+		}
+		m := assetTagRe.FindSubmatch(tag)
+		switch {
+		case m[1] != nil: // <script>
+			src := srcRe.FindSubmatch(m[1])
+			if src == nil || integrityRe.Match(m[1]) {
+				return tag
+			}
+			ref := string(src[2]) + string(src[3])
+			if !isRemoteAsset(ref) {
+				return tag
+			}
+			var attrs string
+			if attrs, err = s.attrs(ref, m[1]); err != nil {
+				return tag
+			}
+			// the attributes go at the end of the opening tag
+			return append([]byte("<script"+string(m[1])+attrs), tag[len("<script")+len(m[1]):]...)
+		case bytes.HasPrefix(bytes.ToLower(tag), []byte("<link")):
+			href := hrefRe.FindSubmatch(tag)
+			if href == nil || !relRe.Match(tag) || integrityRe.Match(tag) {
+				return tag
+			}
+			ref := string(href[2]) + string(href[3])
+			if !isRemoteAsset(ref) {
+				return tag
+			}
+			var attrs string
+			if attrs, err = s.attrs(ref, tag); err != nil {
+				return tag
+			}
+			open, end := strings.TrimSuffix(string(tag), ">"), ">"
+			if strings.HasSuffix(open, "/") {
+				open, end = strings.TrimSuffix(open, "/"), " />"
+			}
+			return []byte(strings.TrimRight(open, " ") + attrs + end)
+		}
+		return tag
+	})
+	return html, err
+}
+
+// attrs returns the attributes that pin the asset at ref, whose tag has
+// the attributes tag: its integrity value and, because browsers check
+// cross-origin assets only when fetched with CORS, crossorigin if the tag
+// does not set it.
+func (s *sriInjector) attrs(ref string, tag []byte) (string, error) {
+	sri, err := s.integrity(ref)
+	if err != nil {
+		return "", err
+	}
+	attrs := ` integrity="` + sri + `"`
+	if !crossoriginRe.Match(tag) {
+		attrs += ` crossorigin="anonymous"`
+	}
+	return attrs, nil
+}
+
+// integrity returns the integrity value of the asset at ref and checks it
+// against the lock file.
+func (s *sriInjector) integrity(ref string) (string, error) {
+	b, err := s.assets.asset(ref)
+	if err != nil {
+		return "", withCode(exitTemplate, fmt.Errorf("-sri: %w", err))
+	}
+	sum := sha512.Sum384(b)
+	sri := "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+	if s.lockPath == "" {
+		return sri, nil
+	}
+	u := absoluteURL(ref)
+	locked, ok := s.lock[u]
+	switch {
+	case !ok:
+		s.lock[u] = sri
+		s.added = true
+	case locked != sri:
+		return "", withCode(exitTemplate, fmt.Errorf("-sri-lock: %s changed: got %s, locked %s in %s", u, sri, locked, s.lockPath))
+	}
+	return sri, nil
+}
+
+// save writes the lock file if a build recorded assets it did not list.
+func (s *sriInjector) save() error {
+	if s.lockPath == "" || !s.added {
+		return nil
+	}
+	return writeJSON(s.lockPath, s.lock, false)
+}
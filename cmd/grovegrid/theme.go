@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"sort"
+
+	"github.com/aplgr/grovegrid/internal/web"
+	"github.com/aplgr/grovegrid/pkg/grovegrid"
+)
+
+// themeDefaults are the palette defaults of a theme; empty keeps the
+// library default.
+type themeDefaults struct {
+	zero, nodata string
+}
+
+// themes lists the built-in themes. All but classic live in
+// templates/themes/<name>.html and fill the "theme" block of the page.
+var themes = map[string]themeDefaults{
+	"classic": {},
+	"dark":    {},
+	"minimal": {zero: "#8c959f", nodata: "#eef1f4"},
+	"print":   {zero: "#8c959f", nodata: "#f2f2f2"},
+}
+
+func themeNames() []string {
+	names := make([]string, 0, len(themes))
+	for n := range themes {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func checkTheme(name string) error {
+	if _, ok := themes[name]; !ok {
+		return withCode(exitUsage, fmt.Errorf("-theme %q: want one of %v", name, themeNames()))
+	}
+	return nil
+}
+
+// applyTheme adds the theme's stylesheet to t.
+func applyTheme(t *template.Template, name string) error {
+	if name == "classic" {
+		return nil
+	}
+	src, err := fs.ReadFile(web.Templates, "templates/themes/"+name+".html")
+	if err != nil {
+		return withCode(exitTemplate, err)
+	}
+	if _, err := t.New("themes/" + name + ".html").Parse(string(src)); err != nil {
+		return withCode(exitTemplate, err)
+	}
+	return nil
+}
+
+// themePalette fills the palette colors the user left unset.
+func themePalette(opts *grovegrid.Options, name string) {
+	d := themes[name]
+	if opts.ZeroColor == "" {
+		opts.ZeroColor = d.zero
+	}
+	if opts.NoDataColor == "" {
+		opts.NoDataColor = d.nodata
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/aplgr/grovegrid/pkg/grovegrid"
+)
+
+type validateFlags struct {
+	commonFlags
+	format string
+	bounds grovegrid.ValueRange
+}
+
+func newValidateFlags() (*flag.FlagSet, *validateFlags) {
+	fs := newFlagSet("grovegrid validate")
+	f := &validateFlags{}
+	f.register(fs, groupInput)
+	fs.StringVar(&f.format, "format", "text", "report format: text (one line per issue) or json")
+	fs.BoolVar(&f.compact, "compact", false, "write minified JSON with -format json")
+	bound := func(b **float64) func(string) error {
+		return func(s string) error {
+			v, err := strconv.ParseFloat(s, 64)
+			*b = &v
+			return err
+		}
+	}
+	fs.Func("value-min", "report values below this as out of range", bound(&f.bounds.Min))
+	fs.Func("value-max", "report values above this as out of range", bound(&f.bounds.Max))
+	return fs, f
+}
+
+// runValidate checks the inputs without writing output and prints the
+// issues found; it fails with exitInvalid if there are any.
+func runValidate(args []string) error {
+	fs, f := newValidateFlags()
+	opts, err := f.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	defer f.close()
+	if f.format != "text" && f.format != "json" {
+		return withCode(exitUsage, fmt.Errorf("-format %q: want text or json", f.format))
+	}
+	v, err := grovegrid.Validate(opts, f.bounds)
+	if err != nil {
+		return err
+	}
+
+	if f.format == "json" {
+		b, err := marshalJSON(v, f.compact)
+		if err != nil {
+			return err
+		}
+		os.Stdout.Write(append(b, '\n'))
+	} else {
+		for _, is := range v.Issues {
+			where := is.Path
+			switch {
+			case where == "":
+				where = is.Slice
+			case is.Slice != "":
+				where += " (" + is.Slice + ")"
+			}
+			fmt.Printf("%s: %s: %s\n", where, is.Kind, is.Message)
+		}
+		fmt.Printf("%d files, %d slices, %d records: %d issues\n", v.Files, v.Slices, v.Records, len(v.Issues))
+	}
+	if len(v.Issues) > 0 {
+		return &exitError{code: exitInvalid, err: fmt.Errorf("%d issues", len(v.Issues)), quiet: true}
+	}
+	return nil
+}
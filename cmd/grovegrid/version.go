@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/aplgr/grovegrid/pkg/grovegrid"
+)
+
+// version, commit and date identify the build; release builds set them
+// with -ldflags "-X main.version=v1.2.0 -X main.commit=... -X main.date=...".
+// Unset ones are taken from the module and VCS information Go embeds.
+var version, commit, date string
+
+// generator returns the build of the binary, carried in Meta.Generator.
+func generator() *grovegrid.Generator {
+	g := &grovegrid.Generator{Version: version, Commit: commit, Date: date}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if g.Version == "" && bi.Main.Version != "(devel)" {
+			g.Version = bi.Main.Version
+		}
+		dirty := false
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if commit == "" {
+					g.Commit = s.Value
+				}
+			case "vcs.time":
+				if g.Date == "" {
+					g.Date = s.Value
+				}
+			case "vcs.modified":
+				dirty = s.Value == "true"
+			}
+		}
+		if dirty && commit == "" && g.Commit != "" {
+			g.Commit += "-dirty"
+		}
+	}
+	if g.Version == "" {
+		g.Version = "devel"
+	}
+	return g
+}
+
+// printVersion prints the build of the binary, for -version.
+func printVersion() {
+	g := generator()
+	fmt.Print("grovegrid ", g.Version)
+	if g.Commit != "" {
+		fmt.Print(" (commit ", g.Commit)
+		if g.Date != "" {
+			fmt.Print(", ", g.Date)
+		}
+		fmt.Print(")")
+	} else if g.Date != "" {
+		fmt.Print(" (", g.Date, ")")
+	}
+	fmt.Println()
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// snapshotDir records size and mtime of every regular file directly in dir.
+func snapshotDir(dir string) map[string]fileStamp {
+	snap := map[string]fileStamp{}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return snap
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		snap[filepath.Join(dir, e.Name())] = fileStamp{size: info.Size(), modTime: info.ModTime()}
+	}
+	return snap
+}
+
+func sameSnapshot(a, b map[string]fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || !v.modTime.Equal(w.modTime) || v.size != w.size {
+			return false
+		}
+	}
+	return true
+}
+
+// watchDir polls dir every interval and calls onChange once the files have
+// changed and then stayed unchanged for one more interval, so half-written
+// drops are not picked up. It returns when ctx is done.
+func watchDir(ctx context.Context, dir string, interval time.Duration, onChange func()) {
+	last := snapshotDir(dir)
+	pending := false
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		cur := snapshotDir(dir)
+		switch {
+		case !sameSnapshot(cur, last):
+			pending = true
+		case pending:
+			pending = false
+			onChange()
+		}
+		last = cur
+	}
+}
@@ -0,0 +1,20 @@
+// Package web bundles the default page template and the vendored JavaScript
+// libraries into the binary, so a built grovegrid works outside the repo.
+package web
+
+import "embed"
+
+// Templates holds the default templates (templates/index.html).
+//
+//go:embed templates
+var Templates embed.FS
+
+// AlpineJS is the vendored Alpine.js build (see scripts/sync-vendor-assets.mjs).
+//
+//go:embed vendor/alpinejs/cdn.min.js
+var AlpineJS []byte
+
+// EChartsJS is the vendored ECharts build (see scripts/sync-vendor-assets.mjs).
+//
+//go:embed vendor/echarts/echarts.min.js
+var EChartsJS []byte
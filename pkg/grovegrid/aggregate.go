@@ -0,0 +1,31 @@
+package grovegrid
+
+// AggregateFuncs lists the functions of Options.Aggregates. Each combines
+// the records of a cell across all slices like the duplicate policy of the
+// same name.
+var AggregateFuncs = []string{DupMean, DupSum, DupMax}
+
+// AggregateName names the slice of an aggregate function, e.g. "ALL (mean)".
+func AggregateName(fn string) string {
+	return "ALL (" + fn + ")"
+}
+
+// aggregates returns one slice per function in fns, combining each cell
+// across slices.
+func aggregates(slices []Slice, fns []string) []Slice {
+	if len(fns) == 0 || len(slices) == 0 {
+		return nil
+	}
+	var all []Record
+	for _, sl := range slices {
+		all = append(all, sl.Records...)
+	}
+	out := make([]Slice, 0, len(fns))
+	for _, fn := range fns {
+		recs, _ := mergeDuplicates(all, fn)
+		// every slice needs records of its own, categorize updates them
+		recs = append([]Record(nil), recs...)
+		out = append(out, Slice{Name: AggregateName(fn), Records: recs, Labels: slices[0].Labels})
+	}
+	return out
+}
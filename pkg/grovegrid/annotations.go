@@ -0,0 +1,104 @@
+package grovegrid
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Annotation is a callout on one cell of a slice, such as "incident
+// #1234", drawn by the page in Color or else its default color.
+type Annotation struct {
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+	Text  string `json:"text"`
+	Color string `json:"color,omitempty"`
+	// xName and yName hold the coordinates of categorical axes, resolved
+	// to X and Y by their category names; see Record.XName.
+	xName, yName string
+}
+
+// LoadAnnotations reads an annotations CSV with the columns month, x, y,
+// text and an optional color (a header row is optional) into the
+// annotations of each slice. A coordinate that is not an integer names a
+// category of a categorical axis.
+func LoadAnnotations(path string) (map[string][]Annotation, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r := csv.NewReader(strings.NewReader(string(b)))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	out := map[string][]Annotation{}
+	for n, row := range rows {
+		if n == 0 && strings.EqualFold(strings.TrimSpace(row[0]), "month") {
+			continue // header
+		}
+		if len(row) < 4 || len(row) > 5 {
+			return nil, fmt.Errorf("%s: line %d: %d columns: want month, x, y, text and an optional color", path, n+1, len(row))
+		}
+		a := Annotation{Text: row[3]}
+		if len(row) == 5 {
+			a.Color = strings.TrimSpace(row[4])
+		}
+		for _, c := range []struct {
+			s    string
+			i    *int
+			name *string
+		}{{row[1], &a.X, &a.xName}, {row[2], &a.Y, &a.yName}} {
+			s := strings.TrimSpace(c.s)
+			if *c.i, err = strconv.Atoi(s); err != nil {
+				*c.name = s
+			}
+		}
+		month := strings.TrimSpace(row[0])
+		out[month] = append(out[month], a)
+	}
+	return out, nil
+}
+
+// addAnnotations sets the Annotations of the datasets named in annotations,
+// with the categorical coordinates resolved by Meta.AxisNames. Annotations
+// of other slices are left out; unknown categories are an error.
+func addAnnotations(out *Output, annotations map[string][]Annotation) error {
+	index := func(names map[int]string, name string) (int, bool) {
+		for i, n := range names {
+			if n == name {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+	var xs, ys map[int]string
+	if an := out.Meta.AxisNames; an != nil {
+		xs, ys = an.X, an.Y
+	}
+	for name, as := range annotations {
+		md := out.Datasets[name]
+		if md == nil {
+			continue
+		}
+		for _, a := range as {
+			var ok bool
+			if a.xName != "" {
+				if a.X, ok = index(xs, a.xName); !ok {
+					return fmt.Errorf("annotation of %s: x %q is not a category", name, a.xName)
+				}
+			}
+			if a.yName != "" {
+				if a.Y, ok = index(ys, a.yName); !ok {
+					return fmt.Errorf("annotation of %s: y %q is not a category", name, a.yName)
+				}
+			}
+			md.Annotations = append(md.Annotations, a)
+		}
+	}
+	return nil
+}
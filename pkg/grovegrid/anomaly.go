@@ -0,0 +1,103 @@
+package grovegrid
+
+import (
+	"math"
+	"sort"
+)
+
+// The measures of spread of Options.AnomalyMethod.
+const (
+	// AnomalyStdDev measures deviations from the mean in standard
+	// deviations.
+	AnomalyStdDev = "stddev"
+	// AnomalyMAD measures deviations from the median in median absolute
+	// deviations, scaled by 1.4826 to match the standard deviation of
+	// normal data; a few extreme months barely move it.
+	AnomalyMAD = "mad"
+)
+
+// AnomalyMethods lists the supported anomaly methods.
+var AnomalyMethods = []string{AnomalyStdDev, AnomalyMAD}
+
+// minAnomalyHistory is the number of slices a cell needs data in before
+// any of its values is flagged.
+const minAnomalyHistory = 3
+
+// Anomaly describes the flagging of Options.Anomaly.
+type Anomaly struct {
+	K      float64 `json:"k"`
+	Method string  `json:"method"`
+}
+
+// addAnomalies sets "anomaly" on the points of the input slices names whose
+// value deviates more than k spreads (see method) from the values of the
+// same cell across those slices, and describes it in Meta.Anomaly. Cells
+// with data in fewer than minAnomalyHistory slices, or whose values do not
+// spread, are not flagged.
+func addAnomalies(out *Output, names []string, k float64, method string) {
+	out.Meta.Anomaly = &Anomaly{K: k, Method: method}
+	history := map[[2]int][]map[string]interface{}{}
+	for _, name := range names {
+		md := out.Datasets[name]
+		if md == nil {
+			continue
+		}
+		for _, p := range md.Points {
+			if v, _ := p["value"].(float64); v >= 0 || out.Meta.Signed {
+				x, _ := p["x"].(int)
+				y, _ := p["y"].(int)
+				history[[2]int{x, y}] = append(history[[2]int{x, y}], p)
+			}
+		}
+	}
+	for _, ps := range history {
+		if len(ps) < minAnomalyHistory {
+			continue
+		}
+		vs := make([]float64, len(ps))
+		for i, p := range ps {
+			vs[i] = p["value"].(float64)
+		}
+		center, spread := spreadOf(vs, method)
+		if spread == 0 {
+			continue
+		}
+		for i, p := range ps {
+			if math.Abs(vs[i]-center) > k*spread {
+				p["anomaly"] = true
+			}
+		}
+	}
+}
+
+// spreadOf returns the center and spread of vs by method: the mean and
+// population standard deviation, or the median and scaled median absolute
+// deviation.
+func spreadOf(vs []float64, method string) (center, spread float64) {
+	if method == AnomalyMAD {
+		center = median(vs)
+		dev := make([]float64, len(vs))
+		for i, v := range vs {
+			dev[i] = math.Abs(v - center)
+		}
+		return center, 1.4826 * median(dev)
+	}
+	for _, v := range vs {
+		center += v
+	}
+	center /= float64(len(vs))
+	for _, v := range vs {
+		spread += (v - center) * (v - center)
+	}
+	return center, math.Sqrt(spread / float64(len(vs)))
+}
+
+// median returns the median of vs, which it sorts.
+func median(vs []float64) float64 {
+	sort.Float64s(vs)
+	n := len(vs)
+	if n%2 == 1 {
+		return vs[n/2]
+	}
+	return (vs[n/2-1] + vs[n/2]) / 2
+}
@@ -0,0 +1,73 @@
+package grovegrid
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// AxisNames maps coordinates to display names per axis, e.g. X 1 to
+// "Berlin". Coordinates without a name are shown as numbers.
+type AxisNames struct {
+	X map[int]string `json:"x,omitempty"`
+	Y map[int]string `json:"y,omitempty"`
+}
+
+// Name returns the display name of coordinate i on axis ("x" or "y").
+func (a *AxisNames) Name(axis string, i int) string {
+	if a != nil {
+		names := a.X
+		if axis == "y" {
+			names = a.Y
+		}
+		if n, ok := names[i]; ok {
+			return n
+		}
+	}
+	return strconv.Itoa(i)
+}
+
+// LoadAxisNames reads a lookup file: JSON like {"x": {"1": "Berlin"}} or
+// CSV with the columns axis, index and name (a header row is optional).
+func LoadAxisNames(path string) (*AxisNames, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	names := &AxisNames{X: map[int]string{}, Y: map[int]string{}}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := json.Unmarshal(b, names); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return names, nil
+	}
+	r := csv.NewReader(strings.NewReader(string(b)))
+	r.FieldsPerRecord = 3
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for n, row := range rows {
+		i, err := strconv.Atoi(strings.TrimSpace(row[1]))
+		if err != nil {
+			if n == 0 {
+				continue // header
+			}
+			return nil, fmt.Errorf("%s: line %d: index %q is not an integer", path, n+1, row[1])
+		}
+		switch strings.ToLower(strings.TrimSpace(row[0])) {
+		case "x":
+			names.X[i] = row[2]
+		case "y":
+			names.Y[i] = row[2]
+		default:
+			return nil, fmt.Errorf("%s: line %d: axis %q: want x or y", path, n+1, row[0])
+		}
+	}
+	return names, nil
+}
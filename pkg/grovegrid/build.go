@@ -0,0 +1,812 @@
+package grovegrid
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+var (
+	// ErrInputDir is returned by Build when the input directory cannot be read.
+	ErrInputDir = errors.New("bad input directory")
+	// ErrNoInput is returned by Build when the input directory holds no input files.
+	ErrNoInput = errors.New("no input files found")
+	// ErrDuplicateSlice is returned by Build when two inputs yield slices
+	// of the same name, such as 2025-01.csv and 2025-01.jsonl.
+	ErrDuplicateSlice = errors.New("duplicate slice")
+)
+
+// ParseError reports an input file that could not be parsed.
+type ParseError struct {
+	Path string
+	Err  error
+}
+
+func (e *ParseError) Error() string { return "parse " + e.Path + ": " + e.Err.Error() }
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// Options configures Build.
+type Options struct {
+	// InDir is the directory with one input file per slice (e.g. 2025-01.csv).
+	// See Parsers for the supported file types.
+	InDir string
+	// Title is the page title carried in Meta, with the placeholders of
+	// TitleFields, such as "Errors {period_range}", filled in.
+	Title string
+	// Columns selects input columns by header name (positional if empty).
+	Columns Columns
+	// SizeColumns, if it names more than one column, lists size columns
+	// to switch between: the first is the Size column, the others stay
+	// extras whose numbers are added to the points, see Meta.SizeMetrics.
+	SizeColumns []string
+	// ValueColumns, if it names more than one column, builds a payload
+	// per column with it as the Value column: the first is returned, the
+	// others are in Output.Metrics. The inputs are read once; the other
+	// columns are extras there, and Columns.Filter sees the first as Value.
+	ValueColumns []string
+	// Strict fails the build on coordinate, value and size cells that do
+	// not hold numbers, reporting the file, line and column, instead of
+	// silently reading them as categories or 0. See Columns.Strict.
+	Strict bool
+	// Signed makes negative values data, such as profit and loss, instead
+	// of no data: the inputs' cells without a value are skipped, and every
+	// dataset is drawn like the Diff one, on a diverging scale centered on
+	// 0 (Scale, Thresholds, Center and Clamp aside). See Columns.Signed.
+	Signed bool
+	// ViewExtras lets the page color and size the points by the numeric
+	// extras columns, those whose non-empty cells all hold numbers, instead
+	// of Value and Size; see Meta.ColorMetrics and Meta.SizeMetrics.
+	ViewExtras bool
+	// TypedExtras infers the type of every extras column (see
+	// Meta.ExtraTypes), so that the JSON payload holds numbers, booleans
+	// and ISO 8601 dates instead of the text of the cells.
+	TypedExtras bool
+	// Duplicates is the policy for a cell given more than once in a slice,
+	// one of DupPolicies; DupLast if empty.
+	Duplicates string
+	// Aggregates adds a slice per function, one of AggregateFuncs, that
+	// combines each cell across all input slices (after the policy of
+	// Duplicates, without filled gaps). They are named by AggregateName
+	// and listed after the other slices.
+	Aggregates []string
+	// Smooth, if above 0, adds a slice per input slice with the rolling
+	// mean of each cell over the Smooth months ending with it (slices if
+	// they are not months), see SmoothName. They are listed after the
+	// other slices, before the aggregates.
+	Smooth int
+	// Diff, if set, names two slices, from and to: a dataset of the
+	// change of each cell between them is added, see Meta.Diff.
+	Diff [2]string
+	// Gaps is the policy for months missing between monthly slices, one
+	// of GapPolicies; GapsKeep if empty.
+	Gaps string
+	// YoY adds a dataset of the change of each cell from the same month a
+	// year earlier for each input month that has one, see Meta.YoY.
+	YoY bool
+	// Trend adds a dataset of the linear trend of each cell across the
+	// input slices, see Meta.Trend.
+	Trend bool
+	// Highlight, if above 0, marks the points with the Highlight highest
+	// and lowest values of each slice, see Meta.Highlight.
+	Highlight int
+	// Normalize, one of Normalizations, replaces the values of each slice
+	// by their normalization before coloring; the points keep the raw
+	// values as "raw". The datasets combining slices, such as Diff, use
+	// the normalized ones.
+	Normalize string
+	// Anomaly, if above 0, flags the points of the input slices whose value
+	// deviates more than Anomaly times the spread of AnomalyMethod, one of
+	// AnomalyMethods (AnomalyStdDev if empty), from the values of the same
+	// cell in all input slices, see Meta.Anomaly.
+	Anomaly       float64
+	AnomalyMethod string
+	// Marginals, if set, adds the sum and mean of every row and column to
+	// each slice, see MonthData.Marginals, and has the page plot this one
+	// of MarginalKinds along the axes.
+	Marginals string
+	// Workers bounds how many input files are parsed at once,
+	// runtime.GOMAXPROCS(0) if 0.
+	Workers int
+	// CacheDir, if set, keeps the parsed records of each input file there;
+	// later builds parse only files whose content (by SHA-256) or column
+	// settings changed.
+	CacheDir string
+	// Logger receives progress: the input files found, records parsed and
+	// rows skipped per file at Debug level, and the time of each phase at
+	// Info level. Nothing is logged if nil.
+	Logger *slog.Logger
+
+	// ZeroColor, NoDataColor and GradColors override the default palette.
+	ZeroColor   string
+	NoDataColor string
+	GradColors  []string
+	// LightZeroColor and LightNoDataColor override the colors of the light
+	// page scheme; they default to ZeroColor and NoDataColor if those are
+	// set, else to the light defaults.
+	LightZeroColor   string
+	LightNoDataColor string
+	// Scale selects the color scale, ScaleLinear if empty.
+	Scale string
+	// Bins, if > 0, resamples the gradient to that many colors.
+	Bins int
+	// Thresholds are the ascending lower bounds of the classes of
+	// ScaleThreshold; the gradient is resampled to one color per class.
+	Thresholds []float64
+	// Center is the pivot of ScaleDiverging, the middle of the value range
+	// if nil.
+	Center *float64
+	// Range selects whether colors and sizes are scaled to the range of all
+	// slices (RangeGlobal, the default) or of each slice (RangeMonth).
+	Range string
+	// Clamp, if set, holds the low and high percentiles (0..100) of the
+	// values > 0 the color scale spans instead of their whole range, see
+	// Meta.Clamp.
+	Clamp [2]float64
+	// Legend is carried in Meta.Legend.
+	Legend Legend
+	// Tooltip, if set, is the text of the page's tooltips for cells with
+	// data, with placeholders such as "{{.Value}} errors on
+	// {{.Extras.host}}": .X, .Y, .Value, .Size, .Month and
+	// .Extras.<column>, formatted like the default tooltip; a newline
+	// starts a new line. See CheckTooltip.
+	Tooltip string
+	// XLabel, YLabel, ValueLabel and SizeLabel, if set, replace the labels
+	// derived from the input headers.
+	XLabel, YLabel, ValueLabel, SizeLabel string
+	// AxisNames is carried in Meta.AxisNames. The category names of
+	// categorical axes (see Record.XName) replace its entries.
+	AxisNames *AxisNames
+	// URLColumn names the extra column whose http(s) or relative URLs
+	// link the cells of the page, such as to drill-down dashboards,
+	// DefaultURLColumn if empty. The points get them as "url".
+	URLColumn string
+	// Annotations, if set, holds the callouts of the slices by name,
+	// carried in MonthData.Annotations; see LoadAnnotations.
+	Annotations map[string][]Annotation
+	// ValueFormat and SizeFormat are carried in Meta.Labels.
+	ValueFormat *Format
+	SizeFormat  *Format
+	// Sparse lists only the present cells in MonthData.Heat instead of the
+	// full grid; see Meta.Sparse.
+	Sparse bool
+	// Layout is the dataset encoding of the JSON payload, LayoutRows if
+	// empty; see Meta.Layout.
+	Layout string
+	// Generator is carried in Meta.Generator.
+	Generator *Generator
+	// Notes are merged over the generated Meta.Notes.
+	Notes map[string]string
+	// GeneratedAt, if not zero, is the build time in Meta.GeneratedAt
+	// instead of the current time, such as SOURCE_DATE_EPOCH.
+	GeneratedAt time.Time
+	// Reproducible leaves Meta.GeneratedAt empty unless GeneratedAt is
+	// set, so that the same input always builds the same payload.
+	Reproducible bool
+	// Location is the time zone of GeneratedAt, of dates without a zone
+	// and of the periods dates are bucketed into. If nil, GeneratedAt is
+	// in local time and dates are in UTC.
+	Location *time.Location
+	// Period is the period, one of Periods, that the records of
+	// long-format input are bucketed into by Columns.Date, and SQLSource
+	// rows by its DateColumn; PeriodMonth if empty.
+	Period string
+	// PeriodPattern, if set, is a regular expression deriving the slice
+	// name of an input file from its name without extension, instead of
+	// using that name as it is; names that do not match fail the build.
+	// Named groups year with quarter, week, or month and optionally day
+	// build a key such as 2025-Q1, 2025-W07, 2025-03 or 2025-03-14 (a
+	// month may be a number or an English name such as Mar); else the
+	// group named period, the first group or the whole match is the name.
+	// Either way slices sort chronologically; see Meta.Months.
+	PeriodPattern string
+	// Months holds per-slice overrides keyed by slice name.
+	Months map[string]MonthOptions
+
+	// Source, if set, replaces reading InDir.
+	Source Source
+}
+
+// Source loads slices from somewhere other than an input directory.
+type Source interface {
+	Load(opts Options) ([]Slice, error)
+}
+
+// MonthOptions overrides Options for a single slice.
+type MonthOptions struct {
+	// Columns fields that are set replace Options.Columns for this slice.
+	Columns Columns
+	// Notes is attached to the slice's MonthData.
+	Notes string
+}
+
+func (o Options) columnsFor(month string) Columns {
+	cols := o.Columns
+	cols.Strict = o.Strict
+	cols.Signed = o.Signed
+	mo := o.Months[month].Columns
+	if mo.X != "" {
+		cols.X = mo.X
+	}
+	if mo.Y != "" {
+		cols.Y = mo.Y
+	}
+	if mo.Value != "" {
+		cols.Value = mo.Value
+	}
+	if mo.Size != "" {
+		cols.Size = mo.Size
+	}
+	return cols
+}
+
+func (o Options) logger() *slog.Logger {
+	if o.Logger == nil {
+		return slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return o.Logger
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// Build parses all input files in opts.InDir (or loads opts.Source) and
+// assembles the Output payload.
+func Build(opts Options) (*Output, error) {
+	if len(opts.ValueColumns) > 1 {
+		return buildMetrics(opts)
+	}
+	slices, err := loadSlices(opts)
+	if err != nil {
+		return nil, err
+	}
+	return assembleSlices(opts, slices)
+}
+
+// loadSlices loads opts.Source, or else the files in opts.InDir, and
+// checks the slice names.
+func loadSlices(opts Options) ([]Slice, error) {
+	if opts.Source != nil {
+		start := time.Now()
+		ss, err := opts.Source.Load(opts)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Columns.Date != "" {
+			if ss, err = bucketByDate(ss, opts.Columns.Date, opts.Period, opts.Location); err != nil {
+				return nil, err
+			}
+		}
+		if err := checkSliceNames(ss); err != nil {
+			return nil, err
+		}
+		if len(ss) == 0 {
+			return nil, ErrNoInput
+		}
+		records, _ := counts(ss)
+		opts.logger().Info("loaded source", "slices", len(ss), "records", records, "elapsed", time.Since(start))
+		return ss, nil
+	}
+	return loadDir(opts)
+}
+
+// assembleSlices merges the duplicates of the loaded slices and builds
+// the payload with the derived datasets.
+func assembleSlices(opts Options, slices []Slice) (*Output, error) {
+	log := opts.logger()
+	policy := orDefault(opts.Duplicates, DupLast)
+	for i := range slices {
+		if opts.Signed {
+			// the Source or the cache may not have marked them
+			markSigned(slices[i].Records)
+		}
+		recs, err := mergeDuplicates(slices[i].Records, policy)
+		if err != nil {
+			return nil, &ParseError{Path: slices[i].Name, Err: err}
+		}
+		slices[i].Records = recs
+	}
+	names := make([]string, len(slices))
+	for i, sl := range slices {
+		names[i] = sl.Name
+	}
+	gaps := missingMonths(names)
+	aggs := aggregates(slices, opts.Aggregates)
+	smooth, smoothNotes := smoothed(slices, opts.Smooth)
+	fill := orDefault(opts.Gaps, GapsKeep)
+	if fill != GapsKeep {
+		slices = fillGaps(slices, gaps, fill)
+	}
+	slices = append(slices, smooth...)
+	slices = append(slices, aggs...)
+	if opts.Normalize != "" {
+		normalize(slices, opts.Normalize)
+	}
+	start := time.Now()
+	out := assemble(opts, slices)
+	out.Meta.Gaps = gaps
+	out.Meta.Normalize = opts.Normalize
+	out.Meta.Signed = opts.Signed
+	if opts.Signed {
+		signedSlices(out, slices, len(out.Meta.GradColors), out.Meta.Range == RangeGlobal)
+		out.Meta.Notes["value_info"] = out.Meta.Labels.Value + ": <0 below, >0 above 0; empty no data"
+	} else if opts.Normalize == NormalizeZScore {
+		signedNormalized(out, slices, len(out.Meta.GradColors))
+	}
+	if len(smooth)+len(aggs) > 0 {
+		// the smoothed slices and aggregates follow the slices they combine
+		derived := map[string]bool{}
+		for _, sl := range smooth {
+			derived[sl.Name] = true
+			out.Meta.Smoothed = append(out.Meta.Smoothed, sl.Name)
+			if md := out.Datasets[sl.Name]; md.Notes == "" {
+				md.Notes = smoothNotes[sl.Name]
+			}
+		}
+		for _, a := range aggs {
+			derived[a.Name] = true
+			out.Meta.Aggregates = append(out.Meta.Aggregates, a.Name)
+		}
+		months := out.Meta.Months[:0]
+		for _, m := range out.Meta.Months {
+			if !derived[m] {
+				months = append(months, m)
+			}
+		}
+		months = append(months, out.Meta.Smoothed...)
+		out.Meta.Months = append(months, out.Meta.Aggregates...)
+	}
+	records := make(map[string][]Record, len(slices))
+	for _, sl := range slices {
+		records[sl.Name] = sl.Records
+	}
+	if opts.Diff != [2]string{} {
+		if err := addDiff(out, records, opts.Diff[0], opts.Diff[1], len(out.Meta.GradColors)); err != nil {
+			return nil, err
+		}
+	}
+	// over the input slices only, not filled gaps or aggregates
+	sortPeriods(names)
+	var date string
+	if at, err := time.Parse(time.RFC3339, out.Meta.GeneratedAt); err == nil {
+		date = at.Format(time.DateOnly)
+	}
+	out.Meta.Title = expandTitle(out.Meta.Title, names, date)
+	if opts.YoY {
+		addYoY(out, records, names, len(out.Meta.GradColors))
+	}
+	if opts.Trend {
+		addTrend(out, records, names, len(out.Meta.GradColors))
+	}
+	if fill != GapsKeep {
+		for _, g := range gaps {
+			if md := out.Datasets[g]; md.Notes == "" {
+				md.Notes = gapNotes[fill]
+			}
+		}
+	}
+	addStats(out, names)
+	if len(opts.SizeColumns) > 1 {
+		addSizeMetrics(out, opts.SizeColumns)
+	}
+	if opts.ViewExtras {
+		addViewExtras(out)
+	}
+	if opts.Highlight > 0 {
+		addHighlights(out, opts.Highlight)
+	}
+	if opts.Anomaly > 0 {
+		addAnomalies(out, names, opts.Anomaly, orDefault(opts.AnomalyMethod, AnomalyStdDev))
+	}
+	if opts.Marginals != "" {
+		addMarginals(out, opts.Marginals)
+	}
+	if opts.TypedExtras {
+		addExtraTypes(out)
+	}
+	addLinks(out, orDefault(opts.URLColumn, DefaultURLColumn))
+	if opts.Annotations != nil {
+		if err := addAnnotations(out, opts.Annotations); err != nil {
+			return nil, err
+		}
+	}
+	log.Info("assembled payload", "slices", len(out.Meta.Months), "columns", out.Meta.columns(), "rows", out.Meta.rows(), "elapsed", time.Since(start))
+	return out, nil
+}
+
+// listInputs returns the input files in dir, or an ErrInputDir or
+// ErrNoInput error.
+func listInputs(dir string) ([]string, error) {
+	if st, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInputDir, err)
+	} else if !st.IsDir() {
+		return nil, fmt.Errorf("%w: %s is not a directory", ErrInputDir, dir)
+	}
+	files, err := inputFiles(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInputDir, err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w in %s", ErrNoInput, dir)
+	}
+	return files, nil
+}
+
+// counts returns the number of records and skipped rows of slices.
+func counts(slices []Slice) (records, skipped int) {
+	for _, sl := range slices {
+		records += len(sl.Records)
+		skipped += sl.Skipped
+	}
+	return records, skipped
+}
+
+// loadDir parses every input file in opts.InDir.
+func loadDir(opts Options) ([]Slice, error) {
+	files, err := listInputs(opts.InDir)
+	if err != nil {
+		return nil, err
+	}
+	log := opts.logger()
+	log.Info("found input files", "dir", opts.InDir, "files", len(files))
+	start := time.Now()
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	parsed := make([][]Slice, len(files))
+	errs := make([]error, len(files))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(files)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fileStart := time.Now()
+				parsed[i], errs[i] = opts.loadFileCached(files[i])
+				if errs[i] == nil {
+					records, skipped := counts(parsed[i])
+					log.Debug("parsed file", "path", files[i], "slices", len(parsed[i]), "records", records, "skipped", skipped, "elapsed", time.Since(fileStart))
+				}
+			}
+		}()
+	}
+	for i := range files {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	// merge in file order, so results and the reported error don't depend
+	// on scheduling
+	var slices []Slice
+	for i, f := range files {
+		if errs[i] != nil {
+			return nil, &ParseError{Path: f, Err: errs[i]}
+		}
+	}
+	if err := addSidecarNotes(files, parsed); err != nil {
+		return nil, err
+	}
+	for i, f := range files {
+		for _, sl := range parsed[i] {
+			sl.Origin = joinOrigin(f, sl.Origin)
+			slices = append(slices, sl)
+		}
+	}
+	records, skipped := counts(slices)
+	log.Info("parsed input files", "files", len(files), "records", records, "skipped", skipped, "elapsed", time.Since(start))
+	if opts.Columns.Date != "" {
+		// the slices are regrouped by date, so their names don't matter
+		if slices, err = bucketByDate(slices, opts.Columns.Date, opts.Period, opts.Location); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkSliceNames(slices); err != nil {
+		return nil, err
+	}
+	return slices, nil
+}
+
+// checkSliceNames reports the first slice whose name cannot name an output
+// file (see checkSliceName) and the first two slices sharing a name, which
+// would overwrite each other, with where each was read from. Every source
+// goes through it, since the writers join the names into file paths.
+func checkSliceNames(slices []Slice) error {
+	seen := make(map[string]int, len(slices))
+	for i, sl := range slices {
+		if err := checkSliceName(sl.Name); err != nil {
+			return &ParseError{Path: orDefault(sl.Origin, "the source"), Err: err}
+		}
+		j, ok := seen[sl.Name]
+		if !ok {
+			seen[sl.Name] = i
+			continue
+		}
+		first, second := orDefault(slices[j].Origin, "the source"), orDefault(sl.Origin, "the source")
+		return fmt.Errorf("%w %s: read from %s and %s", ErrDuplicateSlice, sl.Name, first, second)
+	}
+	return nil
+}
+
+// checkSliceName rejects a slice name that would not stay a plain file
+// name in the output directories: one that is empty, starts with a dot
+// (including "." and "..") or contains a path separator or a control
+// character.
+func checkSliceName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("empty slice name")
+	case strings.HasPrefix(name, "."):
+		return fmt.Errorf("slice name %q starts with a dot", name)
+	case strings.ContainsAny(name, `/\`):
+		return fmt.Errorf("slice name %q contains a path separator", name)
+	case strings.IndexFunc(name, unicode.IsControl) >= 0:
+		return fmt.Errorf("slice name %q contains a control character", name)
+	}
+	return nil
+}
+
+// joinOrigin locates inner, an Origin within the input outer, in outer.
+func joinOrigin(outer, inner string) string {
+	if inner == "" {
+		return outer
+	}
+	return outer + ", " + inner
+}
+
+// assemble computes the global ranges and builds the payload from slices.
+func assemble(opts Options, slices []Slice) *Output {
+	now := time.Now()
+	if !opts.GeneratedAt.IsZero() {
+		now = opts.GeneratedAt
+	}
+	if opts.Location != nil {
+		now = now.In(opts.Location)
+	}
+	var generatedAt string
+	if !opts.Reproducible || !opts.GeneratedAt.IsZero() {
+		generatedAt = now.Format(time.RFC3339)
+	}
+	all := make(map[string][]Record)
+	notes := make(map[string]string)
+	var labels Labels
+	haveLabels := false
+	xMin, yMin := 1, 1 // the grid starts at 1 unless coordinates go lower
+	xMax, yMax := math.MinInt, math.MinInt
+	scale := orDefault(opts.Scale, ScaleLinear)
+	if scale == ScaleThreshold && len(opts.Thresholds) == 0 {
+		scale = ScaleLinear
+	}
+	clamped := opts.Clamp != [2]float64{}
+	keep := scale == ScaleQuantile || clamped
+	axisNames := categorize(slices, opts.AxisNames)
+	global := newRanges()
+	monthly := map[string]*ranges{}
+
+	for _, sl := range slices {
+		if !haveLabels {
+			labels, haveLabels = sl.Labels, true
+		}
+		all[sl.Name] = sl.Records
+		notes[sl.Name] = sl.Notes
+		mr := monthly[sl.Name]
+		if mr == nil {
+			mr = newRanges()
+			monthly[sl.Name] = mr
+		}
+		for _, r := range sl.Records {
+			xMin, xMax = min(xMin, r.X), max(xMax, r.X)
+			yMin, yMax = min(yMin, r.Y), max(yMax, r.Y)
+			global.add(r, keep)
+			mr.add(r, keep)
+		}
+	}
+	if xMax == math.MinInt {
+		xMax, yMax = 0, 0
+	}
+	global.finish()
+	if clamped {
+		global.clamp(opts.Clamp[0], opts.Clamp[1])
+	}
+	labels.X = orDefault(opts.XLabel, labels.X)
+	labels.Y = orDefault(opts.YLabel, labels.Y)
+	labels.Value = orDefault(opts.ValueLabel, labels.Value)
+	labels.Size = orDefault(opts.SizeLabel, labels.Size)
+	labels.ValueFormat, labels.SizeFormat = opts.ValueFormat, opts.SizeFormat
+
+	gradColors := opts.GradColors
+	if len(gradColors) == 0 {
+		gradColors = DefaultGradColors
+	}
+	gradColors = ResampleColors(gradColors, opts.Bins)
+	var classes []string
+	var center *float64
+	switch scale {
+	case ScaleThreshold:
+		gradColors = ResampleColors(gradColors, len(opts.Thresholds))
+		classes = thresholdClasses(opts.Thresholds, opts.ValueFormat)
+	case ScaleDiverging:
+		c := (math.Max(global.zMinPos, minPositive) + global.zMax) / 2
+		if opts.Center != nil {
+			c = *opts.Center
+		}
+		center = &c
+	}
+	breaksOf := func(rg *ranges) []float64 {
+		switch scale {
+		case ScaleQuantile:
+			sort.Float64s(rg.positive)
+			return quantileBreaks(rg.positive, len(gradColors))
+		case ScaleThreshold:
+			return append([]float64(nil), opts.Thresholds...)
+		case ScaleDiverging:
+			return divergingBreaks(*center, math.Max(rg.zMinPos, minPositive), rg.zMax, len(gradColors))
+		}
+		return scaleBreaks(scale, rg.zMinPos, rg.zMax, len(gradColors))
+	}
+
+	out := &Output{
+		Meta: Meta{
+			Sparse:      opts.Sparse,
+			Layout:      orDefault(opts.Layout, LayoutRows),
+			XMin:        xMin,
+			YMin:        yMin,
+			XMax:        xMax,
+			YMax:        yMax,
+			ValueMinPos: global.zMinPos,
+			ValueMax:    global.zMax,
+			ZeroColor:   orDefault(opts.ZeroColor, DefaultZeroColor),
+			NoDataColor: orDefault(opts.NoDataColor, DefaultNoDataColor),
+			GradColors:  append([]string(nil), gradColors...),
+			Scale:       scale,
+			Breaks:      breaksOf(global),
+			Classes:     classes,
+			Center:      center,
+			Range:       orDefault(opts.Range, RangeGlobal),
+			Legend:      opts.Legend,
+			Tooltip:     opts.Tooltip,
+			AxisNames:   axisNames,
+			SizeMin:     global.gMin,
+			SizeMax:     global.gMax,
+			GeneratedAt: generatedAt,
+			Generator:   opts.Generator,
+			Notes: map[string]string{
+				"x_axis":     fmt.Sprintf("%s (%d..%d)", labels.X, xMin, xMax),
+				"y_axis":     fmt.Sprintf("%s (%d..%d)", labels.Y, yMin, yMax),
+				"value_info": labels.Value + ": 0=zero, >0 better; <0 no data",
+				"size_info":  labels.Size + ": circle size",
+			},
+			Title:  opts.Title,
+			Labels: labels,
+			Schemes: map[string]SchemeColors{
+				"dark": {
+					ZeroColor:   orDefault(opts.ZeroColor, DefaultZeroColor),
+					NoDataColor: orDefault(opts.NoDataColor, DefaultNoDataColor),
+				},
+				"light": {
+					ZeroColor:   orDefault(opts.LightZeroColor, orDefault(opts.ZeroColor, DefaultLightZeroColor)),
+					NoDataColor: orDefault(opts.LightNoDataColor, orDefault(opts.NoDataColor, DefaultLightNoDataColor)),
+				},
+			},
+		},
+		Datasets: map[string]*MonthData{},
+	}
+	if clamped {
+		out.Meta.Clamp = &Clamp{Low: opts.Clamp[0], High: opts.Clamp[1], Min: global.zMinPos, Max: global.zMax}
+	}
+	for k, v := range opts.Notes {
+		out.Meta.Notes[k] = v
+	}
+
+	months := make([]string, 0, len(all))
+	for m := range all {
+		months = append(months, m)
+	}
+	sortPeriods(months)
+	out.Meta.Months = months
+
+	// Build datasets
+	for _, m := range months {
+		md := buildMonth(all[m], &out.Meta)
+		md.Notes = opts.Months[m].Notes
+		if md.Notes == "" {
+			md.Notes = notes[m]
+		}
+		rg := monthly[m]
+		rg.finish()
+		if clamped {
+			rg.clamp(opts.Clamp[0], opts.Clamp[1])
+		}
+		md.ValueMinPos, md.ValueMax = rg.zMinPos, rg.zMax
+		md.SizeMin, md.SizeMax = rg.gMin, rg.gMax
+		md.Breaks = breaksOf(rg)
+		out.Datasets[m] = md
+	}
+
+	return out
+}
+
+// ranges tracks the value and size ranges of a set of records.
+type ranges struct {
+	zMinPos, zMax float64
+	gMin, gMax    float64
+	positive      []float64 // values > 0, kept for ScaleQuantile and Clamp
+}
+
+func newRanges() *ranges {
+	return &ranges{zMinPos: 1e12, zMax: -1, gMin: 1e12, gMax: -1}
+}
+
+func (rg *ranges) add(r Record, keep bool) {
+	if r.Size > 0 {
+		rg.gMin = math.Min(rg.gMin, r.Size)
+		rg.gMax = math.Max(rg.gMax, r.Size)
+	}
+	if r.Value > 0 {
+		rg.zMinPos = math.Min(rg.zMinPos, r.Value)
+		rg.zMax = math.Max(rg.zMax, r.Value)
+		if keep {
+			rg.positive = append(rg.positive, r.Value)
+		}
+	}
+}
+
+// finish applies the fallbacks for record sets without sizes or values.
+func (rg *ranges) finish() {
+	if rg.gMin == 1e12 {
+		rg.gMin = 0
+		rg.gMax = 0
+	}
+	if rg.zMinPos == 1e12 {
+		rg.zMinPos = 0
+	}
+	if rg.zMax < 0 {
+		rg.zMax = 0
+	}
+}
+
+func buildMonth(recs []Record, m *Meta) *MonthData {
+	md := &MonthData{Points: []map[string]interface{}{}}
+	if m.Sparse {
+		md.Heat = SparseHeat(recs)
+	} else {
+		md.Heat = DenseHeat(recs, m.XMin, m.XMax, m.YMin, m.YMax)
+	}
+
+	// points: present only
+	for _, r := range recs {
+		md.Points = append(md.Points, point(r))
+	}
+	return md
+}
+
+// point returns the point of r; a normalized one keeps its raw value.
+func point(r Record) map[string]interface{} {
+	p := map[string]interface{}{
+		"x":      r.X,
+		"y":      r.Y,
+		"value":  r.Value,
+		"size":   r.Size,
+		"extras": r.Extras,
+	}
+	if r.raw != nil {
+		p["raw"] = *r.raw
+	}
+	return p
+}
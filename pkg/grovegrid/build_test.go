@@ -0,0 +1,75 @@
+package grovegrid
+
+import (
+	"archive/zip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBuildDuplicateSlice(t *testing.T) {
+	in := t.TempDir()
+	writeFiles(t, in, map[string]string{
+		"2025-01.csv":   "x,y,value\n1,1,3\n",
+		"2025-01.jsonl": `{"x": 1, "y": 1, "value": 4}` + "\n",
+	})
+	_, err := Build(Options{InDir: in})
+	if !errors.Is(err, ErrDuplicateSlice) {
+		t.Fatalf("got %v, want ErrDuplicateSlice", err)
+	}
+	for _, name := range []string{"2025-01.csv", "2025-01.jsonl"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("%q does not name %s", err, name)
+		}
+	}
+}
+
+func TestBuildDuplicateZipMember(t *testing.T) {
+	in := t.TempDir()
+	writeFiles(t, in, map[string]string{"2025-02.csv": "x,y,value\n1,1,3\n"})
+	f, err := os.Create(filepath.Join(in, "more.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	w, err := zw.Create("2025-02.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("x,y,value\n1,1,5\n"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	_, err = Build(Options{InDir: in})
+	if !errors.Is(err, ErrDuplicateSlice) || !strings.Contains(err.Error(), "more.zip, 2025-02.csv") {
+		t.Fatalf("got %v, want ErrDuplicateSlice naming the zip member", err)
+	}
+}
+
+func TestBuildAxisNotes(t *testing.T) {
+	in := t.TempDir()
+	writeFiles(t, in, map[string]string{"2025-01.csv": "row,position,value\n0,-2,3\n4,1,5\n"})
+	out, err := Build(Options{InDir: in})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out.Meta.Notes["x_axis"]; got != "row (0..4)" {
+		t.Errorf("x_axis %q, want row (0..4)", got)
+	}
+	if got := out.Meta.Notes["y_axis"]; got != "position (-2..1)" {
+		t.Errorf("y_axis %q, want position (-2..1)", got)
+	}
+}
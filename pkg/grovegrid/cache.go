@@ -0,0 +1,134 @@
+package grovegrid
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// cacheEntry is the cached parse result of one input file, valid while the
+// file's content, by its SHA-256, and the column settings are unchanged.
+type cacheEntry struct {
+	Size     int64
+	Hash     string
+	Settings string
+	Slices   []Slice
+}
+
+// loadFileCached is loadFile, but reuses the result of an earlier run from
+// o.CacheDir if the file has not changed since. Each input file has one
+// cache file, named by a hash of its absolute path, which is replaced
+// when the input changes. The content is hashed rather than trusting the
+// modification time, which an edit within its granularity, touch -r or
+// git checkout leave unchanged.
+func (o Options) loadFileCached(path string) ([]Slice, error) {
+	if o.CacheDir == "" {
+		return o.loadFile(path)
+	}
+	size, hash, err := hashFile(path)
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(abs))
+	cacheFile := filepath.Join(o.CacheDir, hex.EncodeToString(sum[:16])+".gob")
+	monthCols := make(map[string]Columns, len(o.Months))
+	for m, mo := range o.Months {
+		monthCols[m] = mo.Columns
+	}
+	settings, err := json.Marshal(struct {
+		Columns       Columns
+		Strict        bool
+		Signed        bool
+		PeriodPattern string
+		Months        map[string]Columns
+		Metrics       []string
+	}{o.Columns, o.Strict, o.Signed, o.PeriodPattern, monthCols, o.Columns.metrics})
+	if err != nil {
+		return nil, err
+	}
+
+	if e, err := readCacheEntry(cacheFile); err == nil && e.Size == size && e.Hash == hash && e.Settings == string(settings) {
+		o.logger().Debug("cache hit", "path", path)
+		return e.Slices, nil
+	}
+	slices, err := o.loadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	e := cacheEntry{Size: size, Hash: hash, Settings: string(settings), Slices: slices}
+	if err := writeCacheEntry(cacheFile, e); err != nil {
+		return nil, fmt.Errorf("cache: %w", err)
+	}
+	return slices, nil
+}
+
+// hashFile returns the size and the hex SHA-256 of the file at path.
+func hashFile(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func readCacheEntry(path string) (cacheEntry, error) {
+	var e cacheEntry
+	f, err := os.Open(path)
+	if err != nil {
+		return e, err
+	}
+	defer f.Close()
+	if err := gob.NewDecoder(f).Decode(&e); err != nil {
+		return e, err
+	}
+	// gob drops empty maps and slices; restore them so the output matches
+	// an uncached build
+	for i := range e.Slices {
+		sl := &e.Slices[i]
+		if sl.Labels.Extras == nil {
+			sl.Labels.Extras = []string{}
+		}
+		for j := range sl.Records {
+			if sl.Records[j].Extras == nil {
+				sl.Records[j].Extras = map[string]string{}
+			}
+		}
+	}
+	return e, nil
+}
+
+// writeCacheEntry writes e through a temporary file, so a concurrent or
+// interrupted run never reads a partial entry.
+func writeCacheEntry(path string, e cacheEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := gob.NewEncoder(f).Encode(e); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
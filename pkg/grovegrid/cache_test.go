@@ -0,0 +1,46 @@
+package grovegrid
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheSameSizeAndModTime(t *testing.T) {
+	in := t.TempDir()
+	path := filepath.Join(in, "2024-01.csv")
+	mtime := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	write := func(data string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts := Options{InDir: in, CacheDir: t.TempDir()}
+	value := func() float64 {
+		t.Helper()
+		out, err := Build(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		heat := out.Datasets["2024-01"].Heat
+		if len(heat) != 1 {
+			t.Fatalf("got %d cells, want 1", len(heat))
+		}
+		return heat[0][2]
+	}
+
+	write("row,position,value\n1,1,3\n")
+	if got := value(); got != 3 {
+		t.Fatalf("first build: got %v, want 3", got)
+	}
+	// same size, same modification time, different value
+	write("row,position,value\n1,1,4\n")
+	if got := value(); got != 4 {
+		t.Errorf("after edit: got %v, want 4", got)
+	}
+}
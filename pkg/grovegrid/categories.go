@@ -0,0 +1,57 @@
+package grovegrid
+
+import "strconv"
+
+// categorize turns every axis holding a non-integer coordinate into a
+// categorical axis: its coordinates are numbered 1..N in order of first
+// appearance across the slices, and the category names replace that
+// axis's entries of names in the result. Integer coordinates on such an
+// axis are categories named by their number. names is returned as is if
+// no axis is categorical.
+func categorize(slices []Slice, names *AxisNames) *AxisNames {
+	var catX, catY bool
+	for _, sl := range slices {
+		for _, r := range sl.Records {
+			catX = catX || r.XName != ""
+			catY = catY || r.YName != ""
+		}
+	}
+	if !catX && !catY {
+		return names
+	}
+	out := &AxisNames{}
+	if names != nil {
+		out.X, out.Y = names.X, names.Y
+	}
+	index := func(name string, n int, seen map[string]int, cats map[int]string) int {
+		if name == "" {
+			name = strconv.Itoa(n)
+		}
+		i, ok := seen[name]
+		if !ok {
+			i = len(seen) + 1
+			seen[name] = i
+			cats[i] = name
+		}
+		return i
+	}
+	seenX, seenY := map[string]int{}, map[string]int{}
+	if catX {
+		out.X = map[int]string{}
+	}
+	if catY {
+		out.Y = map[int]string{}
+	}
+	for _, sl := range slices {
+		for i := range sl.Records {
+			r := &sl.Records[i]
+			if catX {
+				r.X = index(r.XName, r.X, seenX, out.X)
+			}
+			if catY {
+				r.Y = index(r.YName, r.Y, seenY, out.Y)
+			}
+		}
+	}
+	return out
+}
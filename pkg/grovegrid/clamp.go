@@ -0,0 +1,33 @@
+package grovegrid
+
+import (
+	"math"
+	"sort"
+)
+
+// Clamp describes the color scale clamping of Options.Clamp.
+type Clamp struct {
+	// Low and High are the percentiles of the values > 0 the scale spans.
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+	// Min and Max are the values at Low and High over all slices; with
+	// RangeMonth each dataset has its own in ValueMinPos and ValueMax.
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// clamp narrows the value range of rg to the nearest-rank percentiles low
+// and high of its values > 0, so that a few extreme values do not compress
+// the scale; the values beyond take the end colors. It sorts rg.positive.
+func (rg *ranges) clamp(low, high float64) {
+	n := len(rg.positive)
+	if n == 0 {
+		return
+	}
+	sort.Float64s(rg.positive)
+	at := func(p float64) float64 {
+		i := int(math.Ceil(p/100*float64(n))) - 1
+		return rg.positive[min(max(i, 0), n-1)]
+	}
+	rg.zMinPos, rg.zMax = at(low), at(high)
+}
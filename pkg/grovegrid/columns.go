@@ -0,0 +1,154 @@
+package grovegrid
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Columns selects the X, Y, Value and Size columns by header name. Empty
+// fields fall back to the positional layout (X, Y, Value, Size = columns 1..4).
+// Matching ignores case, umlauts and punctuation, like the page does.
+type Columns struct {
+	X     string `yaml:"x"`
+	Y     string `yaml:"y"`
+	Value string `yaml:"value"`
+	Size  string `yaml:"size"`
+	// Date names the column with each record's date in long-format input,
+	// which holds several slices in one file: positional columns skip it,
+	// it is read as an extra and Build buckets the records by it into the
+	// slices of Options.Period. SQLSource has DateColumn instead.
+	Date string `yaml:"date"`
+	// Computed adds columns computed per row, each defined as
+	// "name = expression" over the numbers of other columns (header names
+	// or JSON keys, earlier computed columns included) with + - * / and
+	// parentheses, e.g. "rate = errors / requests". A computed column is
+	// like any other: an extra, unless selected as X, Y, Value or Size.
+	// A row lacking a number it uses, or dividing by 0, leaves it empty.
+	Computed []string `yaml:"computed"`
+	// Filter keeps only the records for which it holds, e.g.
+	// `value > 0 && extras.region == "EU"`: comparisons (== != < <= > >=)
+	// of the fields x, y, value, size and extras.<column> (or just the
+	// column), numbers, quoted strings and arithmetic as in Computed,
+	// joined with && || ! and parentheses. Two numbers compare
+	// numerically, anything else as text; a comparison with no data or an
+	// empty extra is false. The parsers apply it to every record, after
+	// the computed columns.
+	Filter string `yaml:"-"`
+	// Strict makes the parsers reject X and Y cells that are not integers
+	// and Value and Size cells that are not numbers, instead of reading
+	// them as categories or 0. Build sets it from Options.Strict.
+	Strict bool `yaml:"-"`
+	// Signed makes the parsers skip the records without a value, and read
+	// negative values as data. Build sets it from Options.Signed.
+	Signed bool `yaml:"-"`
+	// rejected, if set, collects the rows strict mode rejects, which are
+	// then skipped instead of failing the parse; see Validate.
+	rejected *[]error
+	// metrics lists the value columns after the first, see
+	// Options.ValueColumns; they stay extras, not the positional Size.
+	metrics []string
+}
+
+// layout holds the resolved column indexes of one input file. size is -1
+// when the file has no Size column; extras lists every remaining column.
+type layout struct {
+	header            []string
+	x, y, value, size int
+	extras            []int
+}
+
+func resolveLayout(header []string, cols Columns) (layout, error) {
+	l := layout{header: header, x: 0, y: 1, value: 2, size: 3}
+	if cols.Date != "" {
+		d := headerIndex(header, cols.Date)
+		if d < 0 {
+			return layout{}, fmt.Errorf("date column %q not found in header", cols.Date)
+		}
+		// the positional columns are counted without the date column
+		for _, idx := range []*int{&l.x, &l.y, &l.value, &l.size} {
+			if *idx >= d {
+				*idx++
+			}
+		}
+	}
+	for _, c := range []struct {
+		name string
+		idx  *int
+	}{{cols.X, &l.x}, {cols.Y, &l.y}, {cols.Value, &l.value}, {cols.Size, &l.size}} {
+		if c.name == "" {
+			continue
+		}
+		i := headerIndex(header, c.name)
+		if i < 0 {
+			return layout{}, fmt.Errorf("column %q not found in header", c.name)
+		}
+		*c.idx = i
+	}
+	if d := headerIndex(header, cols.Date); cols.Date != "" && (d == l.x || d == l.y || d == l.value || d == l.size) {
+		return layout{}, fmt.Errorf("date column %q is also mapped as X, Y, Value or Size", cols.Date)
+	}
+	if cols.Size == "" && (l.size >= len(header) || l.size == l.x || l.size == l.y || l.size == l.value) {
+		l.size = -1
+	}
+	for _, m := range cols.metrics {
+		if cols.Size == "" && l.size >= 0 && headerIndex(header, m) == l.size {
+			l.size = -1
+		}
+	}
+	if l.x == l.y || l.x == l.value || l.y == l.value || (l.size >= 0 && (l.size == l.x || l.size == l.y || l.size == l.value)) {
+		return layout{}, fmt.Errorf("a column is mapped more than once (x=%d, y=%d, value=%d, size=%d)", l.x+1, l.y+1, l.value+1, l.size+1)
+	}
+	if l.x >= len(header) || l.y >= len(header) || l.value >= len(header) {
+		return layout{}, fmt.Errorf("need at least 3 columns: X, Y, Value")
+	}
+	for i := range header {
+		if i != l.x && i != l.y && i != l.value && i != l.size {
+			l.extras = append(l.extras, i)
+		}
+	}
+	return l, nil
+}
+
+func (l layout) labels() Labels {
+	labels := Labels{X: "X", Y: "Y", Value: "Value", Size: "Size", Extras: []string{}}
+	// blank header cells keep the generic label
+	set := func(dst *string, i int) {
+		if h := strings.TrimSpace(l.header[i]); h != "" {
+			*dst = h
+		}
+	}
+	set(&labels.X, l.x)
+	set(&labels.Y, l.y)
+	set(&labels.Value, l.value)
+	if l.size >= 0 {
+		set(&labels.Size, l.size)
+	}
+	for _, i := range l.extras {
+		labels.Extras = append(labels.Extras, strings.TrimSpace(l.header[i]))
+	}
+	return labels
+}
+
+func headerIndex(header []string, name string) int {
+	want := normalizeHeader(name)
+	for i, h := range header {
+		if normalizeHeader(h) == want {
+			return i
+		}
+	}
+	return -1
+}
+
+var umlauts = strings.NewReplacer("ä", "ae", "ö", "oe", "ü", "ue", "ß", "ss")
+
+// normalizeHeader mirrors normalizeFieldName in the page template.
+func normalizeHeader(s string) string {
+	s = umlauts.Replace(strings.ToLower(strings.TrimSpace(s)))
+	var b strings.Builder
+	for _, r := range s {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,348 @@
+package grovegrid
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// number is the pattern of a number in a cell, with either decimal
+// separator and an optional exponent, such as "12,5" or "1.5E-3".
+const number = `[0-9]+(?:[.,][0-9]+)?(?:[eE][-+]?[0-9]+)?`
+
+var (
+	// numRe matches the number in a cell, such as "12,5" in "12,5 kWh",
+	// without a sign: "-5" reads as 5.
+	numRe = regexp.MustCompile(number)
+	// signedNumRe keeps the sign, for Options.Signed.
+	signedNumRe = regexp.MustCompile(`-?` + number)
+	// strictNumRe matches the cells strict mode takes for numbers: a
+	// number and nothing else, such as "-12,5" but not "12 kWh".
+	strictNumRe = regexp.MustCompile(`^-?` + number + `$`)
+)
+
+// valueRe returns the pattern of the numbers in value cells: numRe, or
+// signedNumRe with signed.
+func valueRe(signed bool) *regexp.Regexp {
+	if signed {
+		return signedNumRe
+	}
+	return numRe
+}
+
+// ParseCSVFile parses one CSV file. See ParseCSV.
+func ParseCSVFile(path string, cols Columns) ([]Record, Labels, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, Labels{}, err
+	}
+	defer f.Close()
+	return ParseCSV(f, cols)
+}
+
+// ParseCSV reads one slice from r and returns its records and the labels
+// taken from its header. The delimiter (`,`, `;` or tab) is detected from the
+// header line; cols selects X, Y, Value and Size (optional), every other
+// column becomes an extra. Rows are converted as they are read, so only the
+// records are held in memory.
+func ParseCSV(rd io.Reader, cols Columns) ([]Record, Labels, error) {
+	recs, labels, _, err := parseCSV(rd, cols)
+	return recs, labels, err
+}
+
+// parseCSV is ParseCSV, but also returns the number of blank rows skipped.
+func parseCSV(rd io.Reader, cols Columns) ([]Record, Labels, int, error) {
+	br := bufio.NewReader(rd)
+	headerLine, err := br.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, Labels{}, 0, err
+	}
+	// detect delimiter
+	delim := ','
+	if strings.Count(headerLine, ";") > strings.Count(headerLine, ",") {
+		delim = ';'
+	} else if strings.Contains(headerLine, "\t") {
+		delim = '\t'
+	}
+
+	r := csv.NewReader(io.MultiReader(strings.NewReader(headerLine), br))
+	r.Comma = delim
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, Labels{}, 0, fmt.Errorf("empty file")
+	}
+	if err != nil {
+		return nil, Labels{}, 0, err
+	}
+	conv, err := newRowConverter(header, cols)
+	if err != nil {
+		return nil, Labels{}, 0, err
+	}
+	// the converter keeps header, so reuse row buffers only from here on
+	r.ReuseRecord = true
+	var out []Record
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, Labels{}, 0, err
+		}
+		rec, ok, err := conv.record(row)
+		if err != nil {
+			line, _ := r.FieldPos(0)
+			if err := conv.reject(fmt.Errorf("line %d, %w", line, err)); err != nil {
+				return nil, Labels{}, 0, err
+			}
+		}
+		if ok {
+			out = append(out, rec)
+		}
+	}
+	return out, conv.labels(), conv.skipped, nil
+}
+
+// recordsFromRows converts a header row plus data rows into records; it
+// also returns the number of blank rows skipped.
+func recordsFromRows(rows [][]string, cols Columns) ([]Record, Labels, int, error) {
+	if len(rows) == 0 {
+		return nil, Labels{}, 0, fmt.Errorf("empty file")
+	}
+
+	conv, err := newRowConverter(rows[0], cols)
+	if err != nil {
+		return nil, Labels{}, 0, err
+	}
+	out := make([]Record, 0, len(rows)-1)
+	for i, row := range rows[1:] {
+		rec, ok, err := conv.record(row)
+		if err != nil {
+			if err := conv.reject(fmt.Errorf("row %d, %w", i+2, err)); err != nil {
+				return nil, Labels{}, 0, err
+			}
+		}
+		if ok {
+			out = append(out, rec)
+		}
+	}
+	return out, conv.labels(), conv.skipped, nil
+}
+
+// rowConverter turns tabular rows into records using a resolved layout.
+type rowConverter struct {
+	layout
+	strict   bool
+	rejected *[]error // see Columns.rejected
+	skipped  int      // blank rows
+	// computed columns follow the raw columns of the header; index maps
+	// the normalized header names to their columns
+	computed []computed
+	raw      int
+	index    map[string]int
+	cells    []string
+	filter   filter
+	signed   bool
+	// num matches the numbers in cells, see valueRe.
+	num *regexp.Regexp
+}
+
+func newRowConverter(header []string, cols Columns) (*rowConverter, error) {
+	comp, err := compileComputed(cols.Computed)
+	if err != nil {
+		return nil, err
+	}
+	keep, err := compileFilter(cols.Filter)
+	if err != nil {
+		return nil, err
+	}
+	raw := len(header)
+	if len(comp) > 0 {
+		header = append([]string(nil), header...)
+		for _, c := range comp {
+			header = append(header, c.name)
+		}
+	}
+	// Need at least 3 columns: X, Y, Value; Size optional
+	l, err := resolveLayout(header, cols)
+	if err != nil {
+		return nil, err
+	}
+	c := &rowConverter{layout: l, strict: cols.Strict, rejected: cols.rejected, computed: comp, raw: raw, filter: keep, signed: cols.Signed, num: valueRe(cols.Signed)}
+	if len(comp) > 0 {
+		c.index = map[string]int{}
+		for i := len(header) - 1; i >= 0; i-- {
+			c.index[normalizeHeader(header[i])] = i
+		}
+		c.cells = make([]string, len(header))
+	}
+	return c, nil
+}
+
+// withComputed returns row with the computed columns appended, in order,
+// so each can use the ones before it. Cells whose expression has no
+// result are empty.
+func (c *rowConverter) withComputed(row []string) []string {
+	cells := c.cells[:c.raw]
+	clear(cells)
+	copy(cells, row)
+	lookup := func(col string) (float64, bool) {
+		i, ok := c.index[normalizeHeader(col)]
+		if !ok || i >= len(cells) || strings.TrimSpace(cells[i]) == "" {
+			return 0, false
+		}
+		v, err := parseNumber(cells[i], c.num)
+		return v, err == nil
+	}
+	for _, cm := range c.computed {
+		s := ""
+		if v, ok := cm.eval(lookup); ok && !math.IsInf(v, 0) && !math.IsNaN(v) {
+			s = strconv.FormatFloat(v, 'g', -1, 64)
+		}
+		cells = append(cells, s)
+	}
+	return cells
+}
+
+// reject returns err, a rejected row with its position, unless rejected
+// rows are collected: then err is recorded and nil returned.
+func (c *rowConverter) reject(err error) error {
+	return collectRejected(c.rejected, err)
+}
+
+func collectRejected(rejected *[]error, err error) error {
+	if rejected == nil {
+		return err
+	}
+	*rejected = append(*rejected, err)
+	return nil
+}
+
+// record converts one row; blank rows and rows Columns.Filter drops are
+// reported as not ok. In strict mode a cell that is not a number is
+// returned as a *cellError.
+func (c *rowConverter) record(row []string) (Record, bool, error) {
+	if len(strings.TrimSpace(strings.Join(row, ""))) == 0 {
+		c.skipped++
+		return Record{}, false, nil
+	}
+	if len(c.computed) > 0 {
+		row = c.withComputed(row)
+	}
+	if c.strict {
+		if err := c.check(row); err != nil {
+			return Record{}, false, err
+		}
+	}
+	rec := Record{Extras: map[string]string{}, signed: c.signed}
+	rec.X, rec.XName = coordSafe(row, c.x)
+	rec.Y, rec.YName = coordSafe(row, c.y)
+	if c.value < len(row) {
+		// empty cell - no data
+		if strings.TrimSpace(row[c.value]) == "" {
+			if c.signed {
+				return Record{}, false, nil
+			}
+			rec.Value = -1
+		} else {
+			rec.Value = atofSmart(row[c.value], c.num)
+		}
+	}
+	if c.size >= 0 && c.size < len(row) {
+		rec.Size = atofSmart(row[c.size], c.num)
+	}
+
+	// extras: every column not mapped to X/Y/Value/Size
+	for _, i := range c.extras {
+		if i < len(row) {
+			rec.Extras[strings.TrimSpace(c.header[i])] = strings.TrimSpace(row[i])
+		}
+	}
+	if c.filter != nil && !c.filter(rec) {
+		return Record{}, false, nil
+	}
+	return rec, true, nil
+}
+
+// check reports the first cell of row that strict mode rejects: X and Y
+// must be integers, Value and Size empty or a number (see strictNumRe).
+func (c *rowConverter) check(row []string) error {
+	cell := func(i int) string {
+		if i >= 0 && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	for _, i := range []int{c.x, c.y} {
+		if _, err := strconv.Atoi(cell(i)); err != nil {
+			return c.cellError(i, cell(i), "an integer")
+		}
+	}
+	if v := cell(c.value); v != "" && !strictNumRe.MatchString(v) {
+		return c.cellError(c.value, v, "a number")
+	}
+	if v := cell(c.size); v != "" && !strictNumRe.MatchString(v) {
+		return c.cellError(c.size, v, "a number")
+	}
+	return nil
+}
+
+func (c *rowConverter) cellError(i int, value, want string) error {
+	name := ""
+	if i < len(c.header) {
+		name = strings.TrimSpace(c.header[i])
+	}
+	return &cellError{column: i + 1, name: name, value: value, want: want}
+}
+
+// cellError reports a cell rejected in strict mode; column counts from 1.
+type cellError struct {
+	column      int
+	name, value string
+	want        string
+}
+
+func (e *cellError) Error() string {
+	if e.value == "" {
+		return fmt.Sprintf("column %d (%s): empty, want %s", e.column, e.name, e.want)
+	}
+	return fmt.Sprintf("column %d (%s): %q is not %s", e.column, e.name, e.value, e.want)
+}
+
+// coordSafe reads a coordinate: an integer, or else the category name.
+func coordSafe(row []string, i int) (int, string) {
+	if i < 0 || i >= len(row) {
+		return 0, ""
+	}
+	s := strings.TrimSpace(row[i])
+	if v, err := strconv.Atoi(s); err == nil || s == "" {
+		return v, ""
+	}
+	return 0, s
+}
+
+func atofSmart(s string, re *regexp.Regexp) float64 {
+	v, _ := parseNumber(s, re)
+	return v
+}
+
+// parseNumber reads the first number in s (matched by re, e.g. "12,5" in
+// "12,5 kWh"), with either decimal separator.
+func parseNumber(s string, re *regexp.Regexp) (float64, error) {
+	s = strings.TrimSpace(s)
+	if re != nil {
+		if m := re.FindString(s); m != "" {
+			s = m
+		}
+	}
+	s = strings.ReplaceAll(s, ",", ".")
+	return strconv.ParseFloat(s, 64)
+}
@@ -0,0 +1,71 @@
+package grovegrid
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCSVSign(t *testing.T) {
+	const in = "x,y,value\n1,1,-5\n1,2,-2.5 kWh\n"
+	for _, tc := range []struct {
+		signed bool
+		want   []float64
+	}{
+		{false, []float64{5, 2.5}},
+		{true, []float64{-5, -2.5}},
+	} {
+		recs, _, err := ParseCSV(strings.NewReader(in), Columns{Signed: tc.signed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(recs) != len(tc.want) {
+			t.Fatalf("signed %v: %d records, want %d", tc.signed, len(recs), len(tc.want))
+		}
+		for i, r := range recs {
+			if r.Value != tc.want[i] {
+				t.Errorf("signed %v: record %d value %v, want %v", tc.signed, i, r.Value, tc.want[i])
+			}
+		}
+	}
+}
+
+func TestParseJSONSign(t *testing.T) {
+	const in = `[{"x": 1, "y": 1, "value": "-5"}]`
+	for _, tc := range []struct {
+		signed bool
+		want   float64
+	}{{false, 5}, {true, -5}} {
+		recs, _, err := ParseJSON(strings.NewReader(in), Columns{Signed: tc.signed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(recs) != 1 || recs[0].Value != tc.want {
+			t.Errorf("signed %v: got %+v, want value %v", tc.signed, recs, tc.want)
+		}
+	}
+}
+
+func TestParseCSVStrict(t *testing.T) {
+	for _, cell := range []string{"12abc", "approx 7", "12 kWh", "1.5E-3x"} {
+		_, _, err := ParseCSV(strings.NewReader("x,y,value\n1,1,"+cell+"\n"), Columns{Strict: true})
+		if err == nil || !strings.Contains(err.Error(), "is not a number") {
+			t.Errorf("%q: got %v, want it rejected", cell, err)
+		}
+	}
+	recs, _, err := ParseCSV(strings.NewReader("x,y,value\n1,1,1.5E-3\n1,2,\"12,5\"\n1,3,-3\n"), Columns{Strict: true, Signed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []float64{0.0015, 12.5, -3} {
+		if recs[i].Value != want {
+			t.Errorf("record %d: value %v, want %v", i, recs[i].Value, want)
+		}
+	}
+}
+
+func TestParseJSONStrict(t *testing.T) {
+	_, _, err := ParseJSON(strings.NewReader(`[{"x": 1, "y": 1, "value": "12abc"}]`), Columns{Strict: true})
+	if err == nil {
+		t.Error("12abc: want it rejected")
+	}
+}
@@ -0,0 +1,105 @@
+package grovegrid
+
+import (
+	"fmt"
+	"math"
+)
+
+// Diff describes a dataset of per-cell deltas, added by Options.Diff or
+// Options.YoY.
+// Unlike the slices, its values may be negative: its heat lists only the
+// cells with data in both slices, and its Breaks split -ValueMax..ValueMax
+// into the bins of a scale diverging at 0. It is not one of Meta.Months,
+// so the static outputs leave it out.
+type Diff struct {
+	// Name is the key of the dataset in Output.Datasets.
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// DiffName names the delta dataset between two slices, e.g.
+// "DIFF (2025-06 - 2025-01)".
+func DiffName(from, to string) string {
+	return "DIFF (" + to + " - " + from + ")"
+}
+
+// addDiff adds the dataset of the deltas from slice from to slice to to
+// out; records holds the records of each slice.
+func addDiff(out *Output, records map[string][]Record, from, to string, bins int) error {
+	for _, m := range []string{from, to} {
+		if _, ok := out.Datasets[m]; !ok {
+			return fmt.Errorf("diff: no slice %q", m)
+		}
+	}
+	name := DiffName(from, to)
+	out.Datasets[name] = deltaMonth(records[from], records[to], bins, fmt.Sprintf("Change from %s to %s per cell with data in both.", from, to))
+	out.Meta.Diff = &Diff{Name: name, From: from, To: to}
+	return nil
+}
+
+// deltaMonth builds the signed dataset of the change of each cell with
+// data in both from and to.
+func deltaMonth(from, to []Record, bins int, notes string) *MonthData {
+	before := map[[2]int]float64{}
+	for _, r := range from {
+		if r.hasData() {
+			before[[2]int{r.X, r.Y}] = r.Value
+		}
+	}
+	var deltas []Record
+	for _, r := range to {
+		v, ok := before[[2]int{r.X, r.Y}]
+		if !ok || !r.hasData() {
+			continue
+		}
+		r.Value -= v
+		r.raw = nil // of the normalized value, not the delta
+		deltas = append(deltas, r)
+	}
+	return signedMonth(deltas, bins, notes)
+}
+
+// signedMonth builds the dataset of recs, whose values may be negative:
+// its heat lists only recs, its breaks split -max..max of the absolute
+// values into bins diverging at 0.
+func signedMonth(recs []Record, bins int, notes string) *MonthData {
+	md := &MonthData{Points: []map[string]interface{}{}, Notes: notes}
+	rg := newRanges()
+	var maxAbs float64
+	for _, r := range recs {
+		maxAbs = math.Max(maxAbs, math.Abs(r.Value))
+		rg.add(r, false)
+		md.Heat = append(md.Heat, [3]float64{float64(r.X), float64(r.Y), r.Value})
+		md.Points = append(md.Points, point(r))
+	}
+	rg.finish()
+	if maxAbs == 0 {
+		maxAbs = minPositive
+	}
+	md.ValueMinPos, md.ValueMax = -maxAbs, maxAbs
+	md.SizeMin, md.SizeMax = rg.gMin, rg.gMax
+	md.Breaks = divergingBreaks(0, -maxAbs, maxAbs, bins)
+	return md
+}
+
+// signed reports whether the dataset name holds signed values, such as
+// the deltas or any dataset with Signed or NormalizeZScore; see
+// signedMonth.
+func (m *Meta) signed(name string) bool {
+	return m.Signed || m.Normalize == NormalizeZScore || m.delta(name)
+}
+
+// delta reports whether the dataset name holds the changes of Diff, Trend
+// or YoY.
+func (m *Meta) delta(name string) bool {
+	if (m.Diff != nil && name == m.Diff.Name) || (m.Trend != nil && name == m.Trend.Name) {
+		return true
+	}
+	for _, d := range m.YoY {
+		if name == d.Name {
+			return true
+		}
+	}
+	return false
+}
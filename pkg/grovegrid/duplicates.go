@@ -0,0 +1,86 @@
+package grovegrid
+
+import "fmt"
+
+// Policies for cells given more than once in a slice, see
+// Options.Duplicates.
+const (
+	// DupError fails the build.
+	DupError = "error"
+	// DupFirst and DupLast keep the first or last record of the cell.
+	DupFirst = "first"
+	DupLast  = "last"
+	// DupSum and DupMean add up or average the values (ignoring no data)
+	// and the sizes; the extras are taken from the first record.
+	DupSum  = "sum"
+	DupMean = "mean"
+	// DupMax keeps the record with the highest value.
+	DupMax = "max"
+)
+
+// DupPolicies lists the supported duplicate policies.
+var DupPolicies = []string{DupError, DupFirst, DupLast, DupSum, DupMean, DupMax}
+
+// mergeDuplicates returns recs with the records sharing a cell merged into
+// one according to policy, in order of first appearance. recs is returned
+// as is if no cell is given twice.
+func mergeDuplicates(recs []Record, policy string) ([]Record, error) {
+	type cell struct {
+		x, y         int
+		xName, yName string
+	}
+	type acc struct {
+		sum, size float64
+		n, nSize  int
+	}
+	index := make(map[cell]int, len(recs))
+	out := make([]Record, 0, len(recs))
+	var accs []acc
+	for _, r := range recs {
+		k := cell{r.X, r.Y, r.XName, r.YName}
+		i, seen := index[k]
+		if !seen {
+			i = len(out)
+			index[k] = i
+			out = append(out, r)
+			accs = append(accs, acc{})
+		} else {
+			switch policy {
+			case DupError:
+				return nil, fmt.Errorf("cell (%s, %s) is given more than once", coordText(r.X, r.XName), coordText(r.Y, r.YName))
+			case DupLast:
+				out[i] = r
+			case DupMax:
+				if r.Value > out[i].Value {
+					out[i] = r
+				}
+			}
+		}
+		a := &accs[i]
+		if r.hasData() {
+			a.sum += r.Value
+			a.n++
+		}
+		a.size += r.Size
+		a.nSize++
+	}
+	if len(out) == len(recs) {
+		return recs, nil
+	}
+	if policy == DupSum || policy == DupMean {
+		for i := range out {
+			a := accs[i]
+			out[i].Value, out[i].Size = -1, a.size
+			if a.n > 0 {
+				out[i].Value = a.sum
+			}
+			if policy == DupMean {
+				if a.n > 0 {
+					out[i].Value /= float64(a.n)
+				}
+				out[i].Size /= float64(a.nSize)
+			}
+		}
+	}
+	return out, nil
+}
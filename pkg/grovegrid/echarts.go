@@ -0,0 +1,128 @@
+package grovegrid
+
+import "fmt"
+
+// EChartsOption returns the ECharts option of one slice as the page builds
+// it: a heatmap series, a scatter series sized by Size and a piecewise
+// visualMap over the palette. Data is inlined; coordinates are zero-based
+// category indexes from XMin and YMin. Per-point sizes replace the page's symbolSize callback.
+func EChartsOption(out *Output, month string) (map[string]interface{}, error) {
+	md, ok := out.Datasets[month]
+	if !ok {
+		return nil, fmt.Errorf("no slice %q", month)
+	}
+	m := out.metaFor(month)
+	lb := m.Labels
+
+	heat := make([][3]float64, 0, m.columns()*m.rows())
+	for _, h := range m.heat(md) {
+		heat = append(heat, [3]float64{h[0] - float64(m.XMin), h[1] - float64(m.YMin), h[2]})
+	}
+	points := make([]map[string]interface{}, 0, len(md.Points))
+	for _, p := range md.Points {
+		x, _ := p["x"].(int)
+		y, _ := p["y"].(int)
+		size, _ := p["size"].(float64)
+		points = append(points, map[string]interface{}{
+			"value":      []interface{}{x - m.XMin, y - m.YMin, p["value"], size},
+			"symbolSize": m.pointDiameter(size, 24),
+			"extras":     p["extras"],
+		})
+	}
+
+	axis := func(name, key string, lo, hi int) map[string]interface{} {
+		data := make([]string, 0, max(0, hi-lo+1))
+		for i := lo; i <= hi; i++ {
+			data = append(data, m.AxisNames.Name(key, i))
+		}
+		return map[string]interface{}{
+			"type":          "category",
+			"data":          data,
+			"name":          name,
+			"nameTextStyle": map[string]interface{}{"color": chartAxisName},
+			"axisLine":      map[string]interface{}{"lineStyle": map[string]interface{}{"color": chartAxisLine}},
+			"axisLabel":     map[string]interface{}{"color": chartText},
+			"splitArea":     map[string]interface{}{"show": false},
+			"splitLine":     map[string]interface{}{"show": false},
+		}
+	}
+	title := month
+	if m.Title != "" {
+		title = m.Title + " – " + month
+	}
+	return map[string]interface{}{
+		"backgroundColor": chartBackground,
+		"title":           map[string]interface{}{"text": title, "textStyle": map[string]interface{}{"color": chartText}},
+		"tooltip":         map[string]interface{}{"trigger": "item"},
+		"grid":            map[string]interface{}{"left": 50, "right": 20, "top": 60, "bottom": 40, "containLabel": true},
+		"xAxis":           axis(lb.X, "x", m.XMin, m.XMax),
+		"yAxis":           axis(lb.Y, "y", m.YMin, m.YMax),
+		"visualMap": []interface{}{map[string]interface{}{
+			"type":        "piecewise",
+			"dimension":   2,
+			"orient":      "horizontal",
+			"right":       20,
+			"top":         8,
+			"pieces":      m.echartsPieces(),
+			"seriesIndex": 0,
+			"textStyle":   map[string]interface{}{"color": chartText},
+		}},
+		"series": []interface{}{
+			map[string]interface{}{
+				"name":      lb.Value,
+				"type":      "heatmap",
+				"data":      heat,
+				"label":     map[string]interface{}{"show": false},
+				"itemStyle": map[string]interface{}{"borderWidth": 1, "borderColor": chartBackground},
+			},
+			map[string]interface{}{
+				"name":      lb.Size,
+				"type":      "scatter",
+				"data":      points,
+				"itemStyle": map[string]interface{}{"color": chartPoint, "borderColor": "#000", "borderWidth": 0.8},
+				"encode":    map[string]interface{}{"x": 0, "y": 1},
+			},
+		},
+	}, nil
+}
+
+// echartsPieces mirrors buildPieces in the page template.
+func (m *Meta) echartsPieces() []map[string]interface{} {
+	pieces := []map[string]interface{}{
+		{"value": -1, "label": "no data", "color": m.NoDataColor},
+		{"value": 0, "label": "0", "color": m.ZeroColor},
+	}
+	if m.Scale == ScaleThreshold {
+		breaks := m.breaks()
+		for i, lo := range breaks {
+			p := map[string]interface{}{"gte": lo, "color": m.GradColors[i]}
+			if i == 0 {
+				p = map[string]interface{}{"gt": 0, "color": m.GradColors[i]}
+			}
+			if i+1 < len(breaks) {
+				p["lt"] = breaks[i+1]
+			}
+			pieces = append(pieces, p)
+		}
+		return pieces
+	}
+	start := m.ValueMinPos
+	if start <= 0 {
+		start = minPositive
+	}
+	breaks := m.breaks()
+	for i, end := range breaks {
+		p := map[string]interface{}{"gt": start - 1e-12, "lte": end + 1e-12, "color": m.GradColors[i]}
+		if m.Clamp != nil {
+			if i == 0 {
+				p["gt"] = 0
+			}
+			if i == len(breaks)-1 {
+				delete(p, "lte")
+			}
+		}
+		pieces = append(pieces, p)
+		start = end
+	}
+	return pieces
+}
@@ -0,0 +1,227 @@
+package grovegrid
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// computed is a compiled Columns.Computed definition.
+type computed struct {
+	name string
+	eval expr
+}
+
+// expr evaluates an expression; lookup returns the number of a column. The
+// result is not ok if a column it uses has no number or it divides by 0.
+type expr func(lookup func(col string) (float64, bool)) (float64, bool)
+
+// CheckComputed reports the first definition of Columns.Computed that does
+// not parse.
+func CheckComputed(defs []string) error {
+	_, err := compileComputed(defs)
+	return err
+}
+
+func compileComputed(defs []string) ([]computed, error) {
+	out := make([]computed, 0, len(defs))
+	for _, d := range defs {
+		name, src, ok := strings.Cut(d, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("computed column %q: want name = expression", d)
+		}
+		e, err := parseExpr(src)
+		if err != nil {
+			return nil, fmt.Errorf("computed column %s: %w", name, err)
+		}
+		out = append(out, computed{name: name, eval: e})
+	}
+	return out, nil
+}
+
+// parseExpr parses an arithmetic expression of numbers, column names, the
+// operators + - * / and parentheses. Names are matched like headers (see
+// normalizeHeader); one with spaces or symbols can be quoted in backticks.
+func parseExpr(src string) (expr, error) {
+	p := &exprParser{src: src}
+	p.next()
+	e, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok != "" {
+		return nil, fmt.Errorf("unexpected %q in %q", p.tok, strings.TrimSpace(src))
+	}
+	return e, nil
+}
+
+// exprParser is a recursive descent parser; tok is the current token, ""
+// at the end, and kind its type: 'n' number, 'i' name, 's' quoted string,
+// else the operator, see operators.
+type exprParser struct {
+	src  string
+	pos  int
+	tok  string
+	kind rune
+}
+
+func (p *exprParser) next() {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+	p.tok, p.kind = "", 0
+	if p.pos >= len(p.src) {
+		return
+	}
+	start := p.pos
+	c := rune(p.src[p.pos])
+	switch {
+	case c == '`':
+		end := strings.IndexByte(p.src[start+1:], '`')
+		if end < 0 {
+			p.pos = len(p.src)
+			p.tok, p.kind = p.src[start:], '?'
+			return
+		}
+		p.pos = start + end + 2
+		p.tok, p.kind = p.src[start+1:start+1+end], 'i'
+	case c >= '0' && c <= '9' || c == '.':
+		for p.pos < len(p.src) && (p.src[p.pos] >= '0' && p.src[p.pos] <= '9' || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		p.tok, p.kind = p.src[start:p.pos], 'n'
+	case unicode.IsLetter(c) || c == '_':
+		for p.pos < len(p.src) {
+			r := rune(p.src[p.pos])
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.' && r < 0x80 {
+				break
+			}
+			p.pos++
+		}
+		p.tok, p.kind = p.src[start:p.pos], 'i'
+	case c == '"' || c == '\'':
+		end := strings.IndexByte(p.src[start+1:], byte(c))
+		if end < 0 {
+			p.pos = len(p.src)
+			p.tok, p.kind = p.src[start:], '?'
+			return
+		}
+		p.pos = start + end + 2
+		p.tok, p.kind = p.src[start+1:start+1+end], 's'
+	default:
+		if op, ok := operators[p.src[start:min(start+2, len(p.src))]]; ok {
+			p.pos += 2
+			p.tok, p.kind = p.src[start:p.pos], op
+			return
+		}
+		p.pos++
+		p.tok, p.kind = string(c), c
+	}
+}
+
+// operators are the kinds of the two-character operators.
+var operators = map[string]rune{"&&": '&', "||": '|', "==": '=', "!=": '≠', "<=": '≤', ">=": '≥'}
+
+// sum = product {("+" | "-") product}
+func (p *exprParser) sum() (expr, error) {
+	l, err := p.product()
+	if err != nil {
+		return nil, err
+	}
+	for p.kind == '+' || p.kind == '-' {
+		op := p.kind
+		p.next()
+		r, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+		l = binaryExpr(op, l, r)
+	}
+	return l, nil
+}
+
+// product = unary {("*" | "/") unary}
+func (p *exprParser) product() (expr, error) {
+	l, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.kind == '*' || p.kind == '/' {
+		op := p.kind
+		p.next()
+		r, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l = binaryExpr(op, l, r)
+	}
+	return l, nil
+}
+
+// unary = ["-"] unary | number | name | "(" sum ")"
+func (p *exprParser) unary() (expr, error) {
+	switch p.kind {
+	case '-':
+		p.next()
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(lookup func(string) (float64, bool)) (float64, bool) {
+			v, ok := e(lookup)
+			return -v, ok
+		}, nil
+	case 'n':
+		v, err := strconv.ParseFloat(p.tok, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", p.tok)
+		}
+		p.next()
+		return func(func(string) (float64, bool)) (float64, bool) { return v, true }, nil
+	case 'i':
+		name := p.tok
+		p.next()
+		return func(lookup func(string) (float64, bool)) (float64, bool) { return lookup(name) }, nil
+	case '(':
+		p.next()
+		e, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		if p.kind != ')' {
+			return nil, fmt.Errorf("missing ) in %q", strings.TrimSpace(p.src))
+		}
+		p.next()
+		return e, nil
+	case 0:
+		return nil, fmt.Errorf("unexpected end of %q", strings.TrimSpace(p.src))
+	}
+	return nil, fmt.Errorf("unexpected %q in %q", p.tok, strings.TrimSpace(p.src))
+}
+
+func binaryExpr(op rune, l, r expr) expr {
+	return func(lookup func(string) (float64, bool)) (float64, bool) {
+		a, ok := l(lookup)
+		if !ok {
+			return 0, false
+		}
+		b, ok := r(lookup)
+		if !ok {
+			return 0, false
+		}
+		switch op {
+		case '+':
+			return a + b, true
+		case '-':
+			return a - b, true
+		case '*':
+			return a * b, true
+		}
+		if b == 0 {
+			return 0, false
+		}
+		return a / b, true
+	}
+}
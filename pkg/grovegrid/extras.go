@@ -0,0 +1,212 @@
+package grovegrid
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The types of the extras columns, see Options.TypedExtras.
+const (
+	ExtraNumber  = "number"
+	ExtraDate    = "date"
+	ExtraBoolean = "boolean"
+	ExtraString  = "string"
+)
+
+// addExtraTypes sets Meta.ExtraTypes, see extraTypes. Nested Metrics keep
+// their own.
+func addExtraTypes(out *Output) {
+	out.Meta.ExtraTypes = extraTypes(out)
+}
+
+// extraTypes returns the type of every extras column: the first of number,
+// boolean and date all its non-empty values read as, else string.
+func extraTypes(out *Output) map[string]string {
+	types := map[string]string{}
+	for _, e := range out.Meta.Labels.Extras {
+		types[e] = ""
+	}
+	for _, md := range out.Datasets {
+		for _, p := range md.Points {
+			ex, _ := p["extras"].(map[string]string)
+			for e, s := range ex {
+				if s = strings.TrimSpace(s); s != "" {
+					types[e] = widenType(types[e], s)
+				}
+			}
+		}
+	}
+	for e, t := range types {
+		if t == "" {
+			types[e] = ExtraString
+		}
+	}
+	return types
+}
+
+// ColorMetric is a column the page can color the cells by, with its range
+// and color breaks over all slices, see Options.ViewExtras.
+type ColorMetric struct {
+	// Name is the column, the key of the point's color_metrics.
+	Name        string    `json:"name"`
+	Label       string    `json:"label"`
+	ValueMinPos float64   `json:"value_min_pos"`
+	ValueMax    float64   `json:"value_max"`
+	Breaks      []float64 `json:"breaks"`
+}
+
+// addViewExtras lists the Value column and then the numeric extras columns
+// as Meta.ColorMetrics, adding their numbers to every point as
+// "color_metrics" keyed by column, and adds those columns to
+// Meta.SizeMetrics; cells without a number leave them out. The breaks use
+// Meta.Scale if it is ScaleLog or ScaleQuantile, else ScaleLinear; the
+// classes of ScaleThreshold are the Value column's, so it has no
+// ColorMetrics.
+func addViewExtras(out *Output) {
+	m := &out.Meta
+	types := extraTypes(out)
+	var cols []string
+	for _, e := range m.Labels.Extras {
+		if types[e] == ExtraNumber {
+			cols = append(cols, e)
+		}
+	}
+	colors := cols
+	if m.Scale == ScaleThreshold {
+		colors = nil
+	}
+	if len(colors) > 0 {
+		m.ColorMetrics = []ColorMetric{{Name: m.Labels.Value, Label: m.Labels.Value, ValueMinPos: m.ValueMinPos, ValueMax: m.ValueMax, Breaks: m.Breaks}}
+	}
+	bins := len(m.GradColors)
+	for _, col := range colors {
+		rg := newRanges()
+		for _, md := range out.Datasets {
+			for _, p := range md.Points {
+				ex, _ := p["extras"].(map[string]string)
+				v, ok := extraNumber(strings.TrimSpace(ex[col]))
+				if !ok {
+					continue
+				}
+				cm, _ := p["color_metrics"].(map[string]float64)
+				if cm == nil {
+					cm = map[string]float64{}
+					p["color_metrics"] = cm
+				}
+				cm[col] = v
+				rg.add(Record{Value: v}, true)
+			}
+		}
+		rg.finish()
+		c := ColorMetric{Name: col, Label: col, ValueMinPos: rg.zMinPos, ValueMax: rg.zMax}
+		switch m.Scale {
+		case ScaleQuantile:
+			sort.Float64s(rg.positive)
+			c.Breaks = quantileBreaks(rg.positive, bins)
+		case ScaleLog:
+			c.Breaks = scaleBreaks(ScaleLog, rg.zMinPos, rg.zMax, bins)
+		default:
+			c.Breaks = scaleBreaks(ScaleLinear, rg.zMinPos, rg.zMax, bins)
+		}
+		m.ColorMetrics = append(m.ColorMetrics, c)
+	}
+	// the size columns of Options.SizeColumns come first
+	sizes := []string{m.Labels.Size}
+	if len(m.SizeMetrics) > 0 {
+		sizes = sizes[:0]
+		for _, sm := range m.SizeMetrics {
+			sizes = append(sizes, sm.Name)
+		}
+	}
+	for _, col := range cols {
+		if !slices.Contains(sizes, col) {
+			sizes = append(sizes, col)
+		}
+	}
+	if len(sizes) > 1 {
+		addSizeMetrics(out, sizes)
+	}
+}
+
+// widenType returns the first type from t on that s reads as, t being ""
+// for a column without values so far.
+func widenType(t, s string) string {
+	switch t {
+	case "", ExtraNumber:
+		if _, ok := extraNumber(s); ok {
+			return ExtraNumber
+		}
+		if t == ExtraNumber {
+			return ExtraString
+		}
+		fallthrough
+	case ExtraBoolean:
+		if _, ok := extraBoolean(s); ok {
+			return ExtraBoolean
+		}
+		if t == ExtraBoolean {
+			return ExtraString
+		}
+		fallthrough
+	case ExtraDate:
+		if _, err := ParseDate(s); err == nil {
+			return ExtraDate
+		}
+	}
+	return ExtraString
+}
+
+// typedExtras returns ex with its values of type types[column]: numbers
+// and booleans as such, dates as "2006-01-02" or RFC 3339 if they have a
+// time of day, empty ones as nil, and strings as they are.
+func typedExtras(ex map[string]string, types map[string]string) map[string]interface{} {
+	out := make(map[string]interface{}, len(ex))
+	for e, s := range ex {
+		out[e] = typedExtra(s, types[e])
+	}
+	return out
+}
+
+func typedExtra(s, typ string) interface{} {
+	t := strings.TrimSpace(s)
+	if typ == ExtraString || typ == "" {
+		return s
+	}
+	if t == "" {
+		return nil
+	}
+	switch typ {
+	case ExtraNumber:
+		v, _ := extraNumber(t)
+		return v
+	case ExtraBoolean:
+		v, _ := extraBoolean(t)
+		return v
+	}
+	d, _ := ParseDate(t)
+	if d.Hour() == 0 && d.Minute() == 0 && d.Second() == 0 && d.Nanosecond() == 0 {
+		return d.Format(time.DateOnly)
+	}
+	return d.Format(time.RFC3339Nano)
+}
+
+// extraNumber reads s as a number, with either decimal separator.
+func extraNumber(s string) (float64, bool) {
+	v, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+	return v, err == nil && !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// extraBoolean reads true, false, yes and no, in any case.
+func extraBoolean(s string) (bool, bool) {
+	switch strings.ToLower(s) {
+	case "true", "yes":
+		return true, true
+	case "false", "no":
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,223 @@
+package grovegrid
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// filter reports whether a record is kept, see Columns.Filter.
+type filter func(r Record) bool
+
+// cond evaluates a condition; text returns the text of a field.
+type cond func(text func(field string) (string, bool)) bool
+
+// CheckFilter reports whether src, a Columns.Filter, does not parse.
+func CheckFilter(src string) error {
+	_, err := compileFilter(src)
+	return err
+}
+
+// compileFilter compiles src, returning nil if it is empty.
+func compileFilter(src string) (filter, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, nil
+	}
+	p := &exprParser{src: src}
+	p.next()
+	c, err := p.or()
+	if err != nil {
+		return nil, fmt.Errorf("filter: %w", err)
+	}
+	if p.tok != "" {
+		return nil, fmt.Errorf("filter: unexpected %q in %q", p.tok, strings.TrimSpace(src))
+	}
+	return func(r Record) bool {
+		return c(func(field string) (string, bool) { return fieldText(r, field) })
+	}, nil
+}
+
+// fieldText returns the text of a field of r: x, y, value, size or an
+// extra, named extras.<column> or just <column>. No data and empty or
+// missing extras have none.
+func fieldText(r Record, field string) (string, bool) {
+	switch field {
+	case "x":
+		return coordText(r.X, r.XName), true
+	case "y":
+		return coordText(r.Y, r.YName), true
+	case "value":
+		if !r.hasData() {
+			return "", false
+		}
+		return strconv.FormatFloat(r.Value, 'g', -1, 64), true
+	case "size":
+		return strconv.FormatFloat(r.Size, 'g', -1, 64), true
+	}
+	want := normalizeHeader(strings.TrimPrefix(field, "extras."))
+	for k, v := range r.Extras {
+		if normalizeHeader(k) == want {
+			v = strings.TrimSpace(v)
+			return v, v != ""
+		}
+	}
+	return "", false
+}
+
+// or = and {"||" and}
+func (p *exprParser) or() (cond, error) {
+	l, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.kind == '|' {
+		p.next()
+		r, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		a := l
+		l = func(text func(string) (string, bool)) bool { return a(text) || r(text) }
+	}
+	return l, nil
+}
+
+// and = not {"&&" not}
+func (p *exprParser) and() (cond, error) {
+	l, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.kind == '&' {
+		p.next()
+		r, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		a := l
+		l = func(text func(string) (string, bool)) bool { return a(text) && r(text) }
+	}
+	return l, nil
+}
+
+// not = "!" not | "(" or ")" | comparison
+func (p *exprParser) not() (cond, error) {
+	switch p.kind {
+	case '!':
+		p.next()
+		c, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return func(text func(string) (string, bool)) bool { return !c(text) }, nil
+	case '(':
+		// a parenthesized condition, else the start of an operand
+		saved := *p
+		p.next()
+		if c, err := p.or(); err == nil && p.kind == ')' {
+			p.next()
+			return c, nil
+		}
+		*p = saved
+	}
+	return p.comparison()
+}
+
+// operand is one side of a comparison: a string has only text, an
+// arithmetic expression only num, a field both.
+type operand struct {
+	text func(text func(string) (string, bool)) (string, bool)
+	num  expr
+}
+
+func (p *exprParser) operand() (operand, error) {
+	if p.kind == 's' {
+		s := p.tok
+		p.next()
+		return operand{text: func(func(string) (string, bool)) (string, bool) { return s, true }}, nil
+	}
+	field := ""
+	if p.kind == 'i' {
+		// a field alone also has text
+		peek := *p
+		peek.next()
+		if !strings.ContainsRune("+-*/", peek.kind) {
+			field = p.tok
+		}
+	}
+	e, err := p.sum()
+	if err != nil {
+		return operand{}, err
+	}
+	o := operand{num: e}
+	if field != "" {
+		o.text = func(text func(string) (string, bool)) (string, bool) { return text(field) }
+	}
+	return o, nil
+}
+
+// comparison = operand ("==" | "!=" | "<" | "<=" | ">" | ">=") operand
+//
+// Two numbers compare numerically, anything else as text; a side without
+// a value, such as a field with no data, makes the comparison false.
+func (p *exprParser) comparison() (cond, error) {
+	l, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	op := p.kind
+	switch op {
+	case '=', '≠', '<', '≤', '>', '≥':
+	case 0:
+		return nil, fmt.Errorf("unexpected end of %q, want a comparison", strings.TrimSpace(p.src))
+	default:
+		return nil, fmt.Errorf("unexpected %q in %q, want a comparison", p.tok, strings.TrimSpace(p.src))
+	}
+	p.next()
+	r, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	if (l.num == nil && r.text == nil) || (r.num == nil && l.text == nil) {
+		return nil, fmt.Errorf("%q compares a string with an expression", strings.TrimSpace(p.src))
+	}
+	return func(text func(string) (string, bool)) bool {
+		if l.num != nil && r.num != nil {
+			num := func(col string) (float64, bool) {
+				t, ok := text(col)
+				if !ok {
+					return 0, false
+				}
+				v, err := parseNumber(t, numRe)
+				return v, err == nil
+			}
+			a, okA := l.num(num)
+			b, okB := r.num(num)
+			if okA && okB {
+				return compare(op, a, b)
+			}
+			if l.text == nil || r.text == nil {
+				return false
+			}
+		}
+		a, okA := l.text(text)
+		b, okB := r.text(text)
+		return okA && okB && compare(op, a, b)
+	}, nil
+}
+
+func compare[T float64 | string](op rune, a, b T) bool {
+	switch op {
+	case '=':
+		return a == b
+	case '≠':
+		return a != b
+	case '<':
+		return a < b
+	case '≤':
+		return a <= b
+	case '>':
+		return a > b
+	}
+	return a >= b
+}
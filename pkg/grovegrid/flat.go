@@ -0,0 +1,174 @@
+package grovegrid
+
+import "encoding/json"
+
+// The dataset layouts of the JSON payload, see Options.Layout.
+const (
+	// LayoutRows encodes each slice as heat triples and point objects.
+	LayoutRows = "rows"
+	// LayoutFlat encodes each slice as parallel arrays, which is much
+	// smaller and faster to parse for big grids.
+	LayoutFlat = "flat"
+)
+
+// flatMonth is the LayoutFlat encoding of a MonthData. Heat is reduced to
+// its values in DenseHeat order, with the coordinates only if the payload
+// is sparse; points become one array per field.
+type flatMonth struct {
+	*MonthData
+	Heat   [][3]float64             `json:"heat,omitempty"`
+	Points []map[string]interface{} `json:"points,omitempty"`
+
+	HeatValues []float64                `json:"heat_values"`
+	HeatX      []int                    `json:"heat_x,omitempty"`
+	HeatY      []int                    `json:"heat_y,omitempty"`
+	Xs         []int                    `json:"xs"`
+	Ys         []int                    `json:"ys"`
+	Values     []float64                `json:"values"`
+	Sizes      []float64                `json:"sizes"`
+	Extras     map[string][]interface{} `json:"extras,omitempty"`
+	// SizeMetrics holds the size_metrics of the points, 0 if missing.
+	SizeMetrics map[string][]float64 `json:"size_metrics,omitempty"`
+	// ColorMetrics holds the color_metrics of the points, -1 if missing.
+	ColorMetrics map[string][]float64 `json:"color_metrics,omitempty"`
+	// Highlights holds the highlight of the points, "" if none.
+	Highlights []string `json:"highlights,omitempty"`
+	// Anomalies holds the anomaly flags of the points.
+	Anomalies []bool `json:"anomalies,omitempty"`
+	// Raws holds the raw values of normalized points, null if none.
+	Raws []*float64 `json:"raws,omitempty"`
+	// URLs holds the links of the points, "" if none.
+	URLs []string `json:"urls,omitempty"`
+}
+
+// MarshalJSON encodes the datasets in the layout named by Meta.Layout, with
+// the extras of the points typed by Meta.ExtraTypes if set.
+func (out Output) MarshalJSON() ([]byte, error) {
+	type plain Output
+	if out.Meta.Layout != LayoutFlat {
+		if out.Meta.ExtraTypes != nil {
+			out.Datasets = typedDatasets(out.Datasets, out.Meta.ExtraTypes)
+		}
+		return json.Marshal(plain(out))
+	}
+	datasets := make(map[string]flatMonth, len(out.Datasets))
+	for name, md := range out.Datasets {
+		datasets[name] = flatten(md, out.Meta.Sparse || out.Meta.signed(name), out.Meta.ExtraTypes)
+	}
+	return json.Marshal(struct {
+		Meta     Meta                 `json:"meta"`
+		Datasets map[string]flatMonth `json:"datasets"`
+		Metrics  map[string]*Output   `json:"metrics,omitempty"`
+	}{out.Meta, datasets, out.Metrics})
+}
+
+// typedDatasets returns copies of datasets whose points have typed extras,
+// see typedExtras.
+func typedDatasets(datasets map[string]*MonthData, types map[string]string) map[string]*MonthData {
+	out := make(map[string]*MonthData, len(datasets))
+	for name, md := range datasets {
+		c := *md
+		c.Points = make([]map[string]interface{}, len(md.Points))
+		for i, p := range md.Points {
+			q := make(map[string]interface{}, len(p))
+			for k, v := range p {
+				q[k] = v
+			}
+			ex, _ := p["extras"].(map[string]string)
+			q["extras"] = typedExtras(ex, types)
+			c.Points[i] = q
+		}
+		out[name] = &c
+	}
+	return out
+}
+
+func flatten(md *MonthData, sparse bool, types map[string]string) flatMonth {
+	f := flatMonth{
+		MonthData:  md,
+		HeatValues: make([]float64, len(md.Heat)),
+		Xs:         make([]int, len(md.Points)),
+		Ys:         make([]int, len(md.Points)),
+		Values:     make([]float64, len(md.Points)),
+		Sizes:      make([]float64, len(md.Points)),
+	}
+	if sparse {
+		f.HeatX, f.HeatY = make([]int, len(md.Heat)), make([]int, len(md.Heat))
+	}
+	for i, h := range md.Heat {
+		f.HeatValues[i] = h[2]
+		if sparse {
+			f.HeatX[i], f.HeatY[i] = int(h[0]), int(h[1])
+		}
+	}
+	for i, p := range md.Points {
+		f.Xs[i], _ = p["x"].(int)
+		f.Ys[i], _ = p["y"].(int)
+		f.Values[i], _ = p["value"].(float64)
+		f.Sizes[i], _ = p["size"].(float64)
+		// the extras of a slice are the columns of its own input
+		ex, _ := p["extras"].(map[string]string)
+		for e, v := range ex {
+			if f.Extras[e] == nil {
+				if f.Extras == nil {
+					f.Extras = map[string][]interface{}{}
+				}
+				f.Extras[e] = make([]interface{}, len(md.Points))
+				if t := types[e]; t == "" || t == ExtraString {
+					for j := range f.Extras[e] {
+						f.Extras[e][j] = ""
+					}
+				}
+			}
+			f.Extras[e][i] = typedExtra(v, types[e])
+		}
+		sm, _ := p["size_metrics"].(map[string]float64)
+		for k, v := range sm {
+			if f.SizeMetrics[k] == nil {
+				if f.SizeMetrics == nil {
+					f.SizeMetrics = map[string][]float64{}
+				}
+				f.SizeMetrics[k] = make([]float64, len(md.Points))
+			}
+			f.SizeMetrics[k][i] = v
+		}
+		cm, _ := p["color_metrics"].(map[string]float64)
+		for k, v := range cm {
+			if f.ColorMetrics[k] == nil {
+				if f.ColorMetrics == nil {
+					f.ColorMetrics = map[string][]float64{}
+				}
+				f.ColorMetrics[k] = make([]float64, len(md.Points))
+				for j := range f.ColorMetrics[k] {
+					f.ColorMetrics[k][j] = -1
+				}
+			}
+			f.ColorMetrics[k][i] = v
+		}
+		if h, _ := p["highlight"].(string); h != "" {
+			if f.Highlights == nil {
+				f.Highlights = make([]string, len(md.Points))
+			}
+			f.Highlights[i] = h
+		}
+		if a, _ := p["anomaly"].(bool); a {
+			if f.Anomalies == nil {
+				f.Anomalies = make([]bool, len(md.Points))
+			}
+			f.Anomalies[i] = true
+		}
+		if r, ok := p["raw"].(float64); ok {
+			if f.Raws == nil {
+				f.Raws = make([]*float64, len(md.Points))
+			}
+			f.Raws[i] = &r
+		}
+		if u, _ := p["url"].(string); u != "" {
+			if f.URLs == nil {
+				f.URLs = make([]string, len(md.Points))
+			}
+			f.URLs[i] = u
+		}
+	}
+	return f
+}
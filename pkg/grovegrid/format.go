@@ -0,0 +1,100 @@
+package grovegrid
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Format describes how the page and the static charts print a number,
+// e.g. 1234567 as "1.2 M €" with Decimals 1, SI and Unit " €".
+type Format struct {
+	// Decimals fixes the number of decimal places; nil rounds to four
+	// significant digits.
+	Decimals *int `yaml:"decimals" json:"decimals,omitempty"`
+	// Thousands separates groups of three integer digits, e.g. "," or " ".
+	Thousands string `yaml:"thousands" json:"thousands,omitempty"`
+	// Decimal is the decimal separator, "." if empty.
+	Decimal string `yaml:"decimal" json:"decimal,omitempty"`
+	// Unit is appended as is, so include a leading space if wanted.
+	Unit string `yaml:"unit" json:"unit,omitempty"`
+	// SI scales by powers of 1000 and appends k, M, G, T (or m, µ for
+	// small values).
+	SI bool `yaml:"si" json:"si,omitempty"`
+	// Percent multiplies by 100 and appends "%".
+	Percent bool `yaml:"percent" json:"percent,omitempty"`
+}
+
+// siPrefixes are the prefixes of SI, by power of 1000 from -2.
+var siPrefixes = []string{"µ", "m", "", "k", "M", "G", "T"}
+
+// Apply formats v. A nil Format prints v with up to four significant
+// digits.
+func (f *Format) Apply(v float64) string {
+	if f == nil {
+		return strconv.FormatFloat(v, 'g', 4, 64)
+	}
+	suffix := ""
+	if f.Percent {
+		v *= 100
+		suffix = "%"
+	}
+	if f.SI && v != 0 {
+		p := int(math.Floor(math.Log10(math.Abs(v)) / 3))
+		p = max(-2, min(p, len(siPrefixes)-3))
+		if p != 0 {
+			v /= math.Pow(1000, float64(p))
+			suffix = " " + siPrefixes[p+2] + suffix
+		}
+	}
+	var num string
+	if f.Decimals != nil {
+		num = strconv.FormatFloat(v, 'f', *f.Decimals, 64)
+	} else {
+		// four significant digits, but never in exponent notation
+		r, _ := strconv.ParseFloat(strconv.FormatFloat(v, 'g', 4, 64), 64)
+		num = strconv.FormatFloat(r, 'f', -1, 64)
+	}
+	intPart, frac, hasFrac := strings.Cut(num, ".")
+	if f.Thousands != "" {
+		sign := ""
+		if strings.HasPrefix(intPart, "-") {
+			sign, intPart = "-", intPart[1:]
+		}
+		var b strings.Builder
+		for i, d := range intPart {
+			if i > 0 && (len(intPart)-i)%3 == 0 {
+				b.WriteString(f.Thousands)
+			}
+			b.WriteRune(d)
+		}
+		intPart = sign + b.String()
+	}
+	if hasFrac {
+		intPart += orDefault(f.Decimal, ".") + frac
+	}
+	return intPart + suffix + f.Unit
+}
+
+// formatRaw is f.Apply, but prints v in full when f is nil.
+func formatRaw(f *Format, v float64) string {
+	if f == nil {
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return f.Apply(v)
+}
+
+// valueFormat is the format of the legend values: Labels.ValueFormat,
+// with Legend.Unit as unit if that format has none.
+func (m *Meta) valueFormat() *Format {
+	f := m.Labels.ValueFormat
+	if m.Legend.Unit != "" && (f == nil || f.Unit == "") {
+		withUnit := Format{}
+		if f != nil {
+			withUnit = *f
+		}
+		withUnit.Unit = m.Legend.Unit
+		f = &withUnit
+	}
+	return f
+}
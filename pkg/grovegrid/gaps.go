@@ -0,0 +1,116 @@
+package grovegrid
+
+import (
+	"sort"
+	"time"
+)
+
+// Policies for months missing from a sequence of monthly slices, see
+// Options.Gaps. The missing months are listed in Meta.Gaps either way.
+const (
+	// GapsKeep leaves the missing months out.
+	GapsKeep = "keep"
+	// GapsEmpty inserts a slice without data for each missing month.
+	GapsEmpty = "empty"
+	// GapsInterpolate inserts a slice for each missing month whose cells
+	// are interpolated linearly between the months around the gap; only
+	// cells with data in both of them are filled.
+	GapsInterpolate = "interpolate"
+)
+
+// GapPolicies lists the supported gap policies.
+var GapPolicies = []string{GapsKeep, GapsEmpty, GapsInterpolate}
+
+// gapNotes are the notes of the slices filled in for missing months,
+// unless Options.Months sets one.
+var gapNotes = map[string]string{
+	GapsEmpty:       "No data: this month is missing from the input.",
+	GapsInterpolate: "Interpolated: this month is missing from the input.",
+}
+
+// missingMonths returns the calendar months missing between the first and
+// the last of names, or nil unless every name is a month such as 2025-03.
+func missingMonths(names []string) []string {
+	have := map[string]bool{}
+	var first, last time.Time
+	for _, n := range names {
+		t, err := time.Parse("2006-01", n)
+		if err != nil {
+			return nil
+		}
+		if len(have) == 0 || t.Before(first) {
+			first = t
+		}
+		if len(have) == 0 || t.After(last) {
+			last = t
+		}
+		have[n] = true
+	}
+	var gaps []string
+	for t := first; t.Before(last); t = t.AddDate(0, 1, 0) {
+		if m := monthKey(t); !have[m] {
+			gaps = append(gaps, m)
+		}
+	}
+	return gaps
+}
+
+// fillGaps returns slices plus one slice for each month in gaps, filled
+// according to policy. The names of slices must be months.
+func fillGaps(slices []Slice, gaps []string, policy string) []Slice {
+	byName := make(map[string]*Slice, len(slices))
+	names := make([]string, 0, len(slices))
+	for i := range slices {
+		byName[slices[i].Name] = &slices[i]
+		names = append(names, slices[i].Name)
+	}
+	sort.Strings(names)
+	out := slices
+	for _, g := range gaps {
+		// month keys sort chronologically
+		i := sort.SearchStrings(names, g)
+		prev, next := byName[names[i-1]], byName[names[i]]
+		sl := Slice{Name: g, Labels: prev.Labels}
+		if policy == GapsInterpolate {
+			t := float64(monthsBetween(prev.Name, g)) / float64(monthsBetween(prev.Name, next.Name))
+			sl.Records = interpolate(prev.Records, next.Records, t)
+		}
+		out = append(out, sl)
+	}
+	return out
+}
+
+// monthsBetween returns the number of months from month a to month b.
+func monthsBetween(a, b string) int {
+	ta, _ := time.Parse("2006-01", a)
+	tb, _ := time.Parse("2006-01", b)
+	return (tb.Year()-ta.Year())*12 + int(tb.Month()-ta.Month())
+}
+
+// interpolate returns the records of the cells with data in both a and b,
+// with Value and Size at t (0 = a, 1 = b) between them.
+func interpolate(a, b []Record, t float64) []Record {
+	type cell struct {
+		x, y         int
+		xName, yName string
+	}
+	later := make(map[cell]Record, len(b))
+	for _, r := range b {
+		later[cell{r.X, r.Y, r.XName, r.YName}] = r
+	}
+	var out []Record
+	for _, r := range a {
+		n, ok := later[cell{r.X, r.Y, r.XName, r.YName}]
+		if !ok || !r.hasData() || !n.hasData() {
+			continue
+		}
+		out = append(out, Record{
+			X: r.X, Y: r.Y, XName: r.XName, YName: r.YName,
+			Value:  r.Value + (n.Value-r.Value)*t,
+			Size:   r.Size + (n.Size-r.Size)*t,
+			Extras: map[string]string{},
+			signed: r.signed,
+		})
+	}
+	return out
+}
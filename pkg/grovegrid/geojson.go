@@ -0,0 +1,74 @@
+package grovegrid
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultLatColumns and DefaultLonColumns are the extras GeoJSON looks for
+// when no column is named. Matching ignores case and punctuation.
+var (
+	DefaultLatColumns = []string{"lat", "latitude", "breite", "breitengrad"}
+	DefaultLonColumns = []string{"lon", "lng", "long", "longitude", "laenge", "laengengrad"}
+)
+
+// GeoJSON returns a FeatureCollection with one Point feature per point of
+// the slice that has valid coordinates in its latitude and longitude extras.
+// Properties carry x, y, value, size and the remaining extras. latCol and
+// lonCol name the extras; empty falls back to DefaultLatColumns and
+// DefaultLonColumns. n is the number of features.
+func GeoJSON(out *Output, month, latCol, lonCol string) (fc map[string]interface{}, n int, err error) {
+	md, ok := out.Datasets[month]
+	if !ok {
+		return nil, 0, fmt.Errorf("no slice %q", month)
+	}
+	lats, lons := DefaultLatColumns, DefaultLonColumns
+	if latCol != "" {
+		lats = []string{latCol}
+	}
+	if lonCol != "" {
+		lons = []string{lonCol}
+	}
+
+	features := []interface{}{}
+	for _, p := range md.Points {
+		extras, _ := p["extras"].(map[string]string)
+		latKey, lat, ok1 := coordinate(extras, lats, 90)
+		lonKey, lon, ok2 := coordinate(extras, lons, 180)
+		if !ok1 || !ok2 {
+			continue
+		}
+		props := map[string]interface{}{"x": p["x"], "y": p["y"], "value": p["value"], "size": p["size"]}
+		for k, v := range extras {
+			if k != latKey && k != lonKey {
+				props[k] = v
+			}
+		}
+		features = append(features, map[string]interface{}{
+			"type":       "Feature",
+			"geometry":   map[string]interface{}{"type": "Point", "coordinates": []float64{lon, lat}},
+			"properties": props,
+		})
+	}
+	return map[string]interface{}{"type": "FeatureCollection", "features": features}, len(features), nil
+}
+
+// coordinate finds the first extra matching one of names and parses it as a
+// decimal degree within ±limit.
+func coordinate(extras map[string]string, names []string, limit float64) (string, float64, bool) {
+	for _, name := range names {
+		want := normalizeHeader(name)
+		for k, v := range extras {
+			if normalizeHeader(k) != want {
+				continue
+			}
+			f, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(v), ",", "."), 64)
+			if err != nil || f < -limit || f > limit {
+				return k, 0, false
+			}
+			return k, f, true
+		}
+	}
+	return "", 0, false
+}
@@ -0,0 +1,103 @@
+package grovegrid
+
+import "strings"
+
+// grafanaDatasource is the Infinity plugin, which reads JSON over HTTP.
+const grafanaDatasource = "yesoreyeram-infinity-datasource"
+
+// GrafanaDashboard returns a Grafana (10+) dashboard with one grid panel and
+// a month variable. The panel reads ${data_url}/${month}.json, a JSON array
+// of MonthData.Cells, through the Infinity data source; dataURL is the
+// default of the data_url variable. Grafana's heatmap panel needs time on
+// the X axis, so the grid is drawn as an XY chart: points colored with the
+// palette's thresholds and sized by Size.
+func GrafanaDashboard(out *Output, dataURL string) map[string]interface{} {
+	m := &out.Meta
+	lb := m.Labels
+
+	domain, colors := m.ColorThresholds()
+	steps := []map[string]interface{}{{"color": colors[0], "value": nil}}
+	for i, v := range domain {
+		steps = append(steps, map[string]interface{}{"color": colors[i+1], "value": v})
+	}
+
+	columns := []map[string]interface{}{
+		{"selector": "x", "text": lb.X, "type": "number"},
+		{"selector": "y", "text": lb.Y, "type": "number"},
+		{"selector": "value", "text": lb.Value, "type": "number"},
+		{"selector": "size", "text": lb.Size, "type": "number"},
+	}
+	for _, e := range lb.Extras {
+		columns = append(columns, map[string]interface{}{"selector": "extras." + e, "text": e, "type": "string"})
+	}
+
+	options := make([]map[string]interface{}, 0, len(m.Months))
+	for _, month := range m.Months {
+		options = append(options, map[string]interface{}{"text": month, "value": month, "selected": false})
+	}
+	current := map[string]interface{}{}
+	if n := len(options); n > 0 {
+		options[n-1]["selected"] = true
+		current = map[string]interface{}{"text": m.Months[n-1], "value": m.Months[n-1]}
+	}
+
+	title := m.Title
+	if title == "" {
+		title = "GroveGrid"
+	}
+	ds := map[string]interface{}{"type": grafanaDatasource, "uid": "${datasource}"}
+	return map[string]interface{}{
+		"title":         title,
+		"uid":           "",
+		"schemaVersion": 39,
+		"editable":      true,
+		"time":          map[string]interface{}{"from": "now-6h", "to": "now"},
+		"templating": map[string]interface{}{"list": []interface{}{
+			map[string]interface{}{"name": "datasource", "label": "Data source", "type": "datasource", "query": grafanaDatasource},
+			map[string]interface{}{"name": "data_url", "label": "Data URL", "type": "textbox", "query": dataURL,
+				"current": map[string]interface{}{"text": dataURL, "value": dataURL}},
+			map[string]interface{}{"name": "month", "label": "Month", "type": "custom", "query": strings.Join(m.Months, ","),
+				"options": options, "current": current},
+		}},
+		"panels": []interface{}{map[string]interface{}{
+			"id":         1,
+			"type":       "xychart",
+			"title":      "${month}",
+			"datasource": ds,
+			"gridPos":    map[string]interface{}{"x": 0, "y": 0, "w": 24, "h": 20},
+			"targets": []interface{}{map[string]interface{}{
+				"refId":            "A",
+				"datasource":       ds,
+				"type":             "json",
+				"source":           "url",
+				"format":           "table",
+				"parser":           "backend",
+				"url":              "${data_url}/${month}.json",
+				"url_options":      map[string]interface{}{"method": "GET"},
+				"root_selector":    "",
+				"columns":          columns,
+				"filterExpression": "value >= 0",
+			}},
+			"fieldConfig": map[string]interface{}{
+				"defaults": map[string]interface{}{
+					"color":      map[string]interface{}{"mode": "thresholds"},
+					"thresholds": map[string]interface{}{"mode": "absolute", "steps": steps},
+					"custom":     map[string]interface{}{"show": "points", "pointShape": "square"},
+				},
+				"overrides": []interface{}{},
+			},
+			"options": map[string]interface{}{
+				"seriesMapping": "manual",
+				"series": []interface{}{map[string]interface{}{
+					"name":       lb.Value,
+					"x":          lb.X,
+					"y":          lb.Y,
+					"pointColor": map[string]interface{}{"field": lb.Value},
+					"pointSize":  map[string]interface{}{"field": lb.Size, "min": 6, "max": 28},
+				}},
+				"legend":  map[string]interface{}{"showLegend": true, "displayMode": "list", "placement": "bottom"},
+				"tooltip": map[string]interface{}{"mode": "single"},
+			},
+		}},
+	}
+}
@@ -0,0 +1,220 @@
+package grovegrid
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DenseHeat expands the records of one month to the full xMin..xMax ×
+// yMin..yMax grid. Absent coordinates get value -1 ("no data").
+func DenseHeat(recs []Record, xMin, xMax, yMin, yMax int) [][3]float64 {
+	present := map[[2]int]Record{}
+	for _, r := range recs {
+		present[[2]int{r.X, r.Y}] = r
+	}
+
+	heat := make([][3]float64, 0, max(0, (xMax-xMin+1)*(yMax-yMin+1)))
+	for x := xMin; x <= xMax; x++ {
+		for y := yMin; y <= yMax; y++ {
+			val := -1.0
+			if r, ok := present[[2]int{x, y}]; ok {
+				val = r.Value // 0=zero, >0 better
+			}
+			heat = append(heat, [3]float64{float64(x), float64(y), val})
+		}
+	}
+	return heat
+}
+
+// SparseHeat lists the heat cells of the records present, in the order of
+// DenseHeat.
+func SparseHeat(recs []Record) [][3]float64 {
+	present := map[[2]int]Record{}
+	for _, r := range recs {
+		present[[2]int{r.X, r.Y}] = r
+	}
+	heat := make([][3]float64, 0, len(present))
+	for k, r := range present {
+		heat = append(heat, [3]float64{float64(k[0]), float64(k[1]), r.Value})
+	}
+	sort.Slice(heat, func(i, j int) bool {
+		if heat[i][0] != heat[j][0] {
+			return heat[i][0] < heat[j][0]
+		}
+		return heat[i][1] < heat[j][1]
+	})
+	return heat
+}
+
+// heat returns the heat of md on the full grid, expanding a sparse one.
+func (m *Meta) heat(md *MonthData) [][3]float64 {
+	if !m.Sparse {
+		return md.Heat
+	}
+	recs := make([]Record, 0, len(md.Heat))
+	for _, h := range md.Heat {
+		recs = append(recs, Record{X: int(h[0]), Y: int(h[1]), Value: h[2]})
+	}
+	return DenseHeat(recs, m.XMin, m.XMax, m.YMin, m.YMax)
+}
+
+// WriteGridCSV writes a dense grid as a wide matrix: the header row holds the
+// Y coordinates, the first column the X coordinates. No-data cells stay blank.
+func WriteGridCSV(path, corner string, heat [][3]float64, xMin, xMax, yMin, yMax int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	header := make([]string, 0, yMax-yMin+2)
+	header = append(header, corner)
+	for y := yMin; y <= yMax; y++ {
+		header = append(header, strconv.Itoa(y))
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	cells := map[[2]int]float64{}
+	for _, h := range heat {
+		cells[[2]int{int(h[0]), int(h[1])}] = h[2]
+	}
+	for x := xMin; x <= xMax; x++ {
+		row := make([]string, 0, yMax-yMin+2)
+		row = append(row, strconv.Itoa(x))
+		for y := yMin; y <= yMax; y++ {
+			v, ok := cells[[2]int{x, y}]
+			if !ok || v < 0 {
+				row = append(row, "")
+				continue
+			}
+			row = append(row, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// ParseGridCSV reads a wide matrix as WriteGridCSV writes it, one record per
+// non-blank cell: the header row holds the Y coordinates after the X label,
+// the first column the X coordinates. It is a Parser, so it can be added to
+// Parsers; cols.Strict rejects cells that are not numbers, the coordinates
+// must be integers.
+func ParseGridCSV(rd io.Reader, cols Columns) ([]Record, Labels, error) {
+	r := csv.NewReader(rd)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, Labels{}, fmt.Errorf("empty file")
+	}
+	if err != nil {
+		return nil, Labels{}, err
+	}
+	ys := make([]int, len(header))
+	for i := 1; i < len(header); i++ {
+		if ys[i], err = strconv.Atoi(strings.TrimSpace(header[i])); err != nil {
+			return nil, Labels{}, fmt.Errorf("header column %d: %q is not an integer", i+1, header[i])
+		}
+	}
+	labels := Labels{X: strings.TrimSpace(header[0]), Y: "Y", Value: "Value", Size: "Size", Extras: []string{}}
+	num := valueRe(cols.Signed)
+	var out []Record
+	for line := 2; ; line++ {
+		row, err := r.Read()
+		if err == io.EOF {
+			return out, labels, nil
+		}
+		if err != nil {
+			return nil, Labels{}, err
+		}
+		if len(strings.TrimSpace(strings.Join(row, ""))) == 0 {
+			continue
+		}
+		x, err := strconv.Atoi(strings.TrimSpace(row[0]))
+		if err != nil {
+			return nil, Labels{}, fmt.Errorf("line %d, column 1: %q is not an integer", line, row[0])
+		}
+		for i := 1; i < len(row) && i < len(header); i++ {
+			v := strings.TrimSpace(row[i])
+			if v == "" {
+				continue
+			}
+			if cols.Strict && !strictNumRe.MatchString(v) {
+				return nil, Labels{}, fmt.Errorf("line %d, column %d: %q is not a number", line, i+1, v)
+			}
+			out = append(out, Record{X: x, Y: ys[i], Value: atofSmart(v, num), Extras: map[string]string{}, signed: cols.Signed})
+		}
+	}
+}
+
+// columns and rows are the width and height of the grid in cells.
+func (m *Meta) columns() int { return m.XMax - m.XMin + 1 }
+
+func (m *Meta) rows() int { return m.YMax - m.YMin + 1 }
+
+// Cells expands md to one record per heat cell in heat order, X then Y:
+// every grid cell, or only the present ones if the payload is sparse.
+// Cells without data have Value -1 and Size 0.
+func (md *MonthData) Cells() []Record {
+	points := map[[2]int]map[string]interface{}{}
+	for _, p := range md.Points {
+		x, _ := p["x"].(int)
+		y, _ := p["y"].(int)
+		points[[2]int{x, y}] = p
+	}
+	out := make([]Record, 0, len(md.Heat))
+	for _, h := range md.Heat {
+		rec := Record{X: int(h[0]), Y: int(h[1]), Value: h[2]}
+		if p, ok := points[[2]int{rec.X, rec.Y}]; ok {
+			rec.Size, _ = p["size"].(float64)
+			rec.Extras, _ = p["extras"].(map[string]string)
+		}
+		out = append(out, rec)
+	}
+	return out
+}
+
+// WriteCellsCSV writes cells in long format, one row per cell: X, Y, Value,
+// Size and the extras, with the labels as header. No-data cells keep -1.
+func WriteCellsCSV(path string, labels Labels, cells []Record) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	header := append([]string{labels.X, labels.Y, labels.Value, labels.Size}, labels.Extras...)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	num := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	for _, c := range cells {
+		row := make([]string, 0, len(header))
+		row = append(row, strconv.Itoa(c.X), strconv.Itoa(c.Y), num(c.Value), num(c.Size))
+		for _, e := range labels.Extras {
+			row = append(row, c.Extras[e])
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,100 @@
+package grovegrid
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteGridCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grid.csv")
+	// out of grid order, with a no-data cell and a cell missing altogether
+	heat := [][3]float64{{2, 1, 0}, {1, 2, -1}, {1, 1, 1.5}, {1, 3, 7}}
+	if err := WriteGridCSV(path, "row", heat, 1, 2, 1, 3); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "row,1,2,3\n1,1.5,,7\n2,0,,\n"
+	if string(got) != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteGridCSVMonths(t *testing.T) {
+	in := t.TempDir()
+	files := map[string]string{
+		"2024-02.csv": "row,position,value\n1,1,3\n2,2,4\n",
+		"2023-12.csv": "row,position,value\n1,2,5\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(in, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out, err := Build(Options{InDir: in})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out.Meta.Months; len(got) != 2 || got[0] != "2023-12" || got[1] != "2024-02" {
+		t.Fatalf("months %v, want [2023-12 2024-02]", got)
+	}
+
+	want := map[string]string{
+		"2023-12": "row,1,2\n1,,5\n2,,\n",
+		"2024-02": "row,1,2\n1,3,\n2,,4\n",
+	}
+	dir := t.TempDir()
+	m := out.Meta
+	for _, month := range m.Months {
+		path := filepath.Join(dir, month+".csv")
+		if err := WriteGridCSV(path, m.Labels.X, out.Datasets[month].Heat, m.XMin, m.XMax, m.YMin, m.YMax); err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want[month] {
+			t.Errorf("%s: got\n%s\nwant\n%s", month, got, want[month])
+		}
+	}
+}
+
+func TestGridCSVRoundTrip(t *testing.T) {
+	// a 2x2 grid with one cell without data
+	in := t.TempDir()
+	writeFiles(t, in, map[string]string{"2024-01.csv": "row,position,value\n1,1,3\n1,2,0\n2,2,4.5\n"})
+	out, err := Build(Options{InDir: in})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := out.Meta
+	path := filepath.Join(t.TempDir(), "2024-01.csv")
+	if err := WriteGridCSV(path, m.Labels.X, out.Datasets["2024-01"].Heat, m.XMin, m.XMax, m.YMin, m.YMax); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	recs, labels, err := ParseGridCSV(f, Columns{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels.X != "row" {
+		t.Errorf("X label %q, want row", labels.X)
+	}
+	got := map[[2]int]float64{}
+	for _, r := range recs {
+		got[[2]int{r.X, r.Y}] = r.Value
+	}
+	want := map[[2]int]float64{{1, 1}: 3, {1, 2}: 0, {2, 2}: 4.5}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,217 @@
+// Package grovegrid turns per-month CSV snapshots of a grid (rows × positions)
+// into the data model rendered by the GroveGrid heatmap page.
+//
+// The CLI in cmd/grovegrid is a thin wrapper around Build; services can call
+// it directly to embed grid generation without shelling out.
+package grovegrid
+
+// Record is one parsed CSV row.
+type Record struct {
+	X      int               `json:"x"`
+	Y      int               `json:"y"`
+	Value  float64           `json:"value"` // < 0 means "no data" unless Options.Signed
+	Size   float64           `json:"size"`  // circle size
+	Extras map[string]string `json:"extras,omitempty"`
+	// XName and YName hold the raw coordinate if it is not an integer;
+	// Build maps such categories to the indexes 1..N.
+	XName string `json:"-"`
+	YName string `json:"-"`
+	// raw is the value before Options.Normalize, if normalized.
+	raw *float64
+	// signed makes a negative Value data, see Options.Signed.
+	signed bool
+}
+
+// MonthData holds the dense heat grid and the present points of one slice.
+type MonthData struct {
+	Heat   [][3]float64             `json:"heat"`
+	Points []map[string]interface{} `json:"points"`
+	Notes  string                   `json:"notes,omitempty"`
+	// The value and size ranges and color breaks of this slice alone; they
+	// replace the Meta ones when Meta.Range is RangeMonth.
+	ValueMinPos float64   `json:"value_min_pos"`
+	ValueMax    float64   `json:"value_max"`
+	SizeMin     float64   `json:"size_min"`
+	SizeMax     float64   `json:"size_max"`
+	Breaks      []float64 `json:"breaks"`
+	// Marginals, if Options.Marginals is set, aggregates the rows and
+	// columns of the slice.
+	Marginals *Marginals `json:"marginals,omitempty"`
+	// Annotations holds the callouts on cells of the slice, see
+	// Options.Annotations.
+	Annotations []Annotation `json:"annotations,omitempty"`
+}
+
+// Labels derived from CSV headers (not hard-coded).
+type Labels struct {
+	X      string   `json:"x"`
+	Y      string   `json:"y"`
+	Value  string   `json:"value"`
+	Size   string   `json:"size"`
+	Extras []string `json:"extras"`
+	// ValueFormat and SizeFormat, if set, format values and sizes in
+	// tooltips and legends.
+	ValueFormat *Format `json:"value_format,omitempty"`
+	SizeFormat  *Format `json:"size_format,omitempty"`
+}
+
+// Meta describes the grid, the color mapping and the available slices.
+type Meta struct {
+	// Sparse is set if MonthData.Heat lists only the cells present in the
+	// input; the other cells of the grid have no data.
+	Sparse bool `json:"sparse,omitempty"`
+	// Layout is how the JSON payload encodes the datasets: LayoutRows or
+	// LayoutFlat. The Go values always hold rows.
+	Layout string `json:"layout"`
+	// The grid spans XMin..XMax × YMin..YMax; XMin and YMin are 1 unless
+	// the input holds lower coordinates.
+	XMin        int      `json:"x_min"`
+	YMin        int      `json:"y_min"`
+	XMax        int      `json:"x_max"`
+	YMax        int      `json:"y_max"`
+	ValueMinPos float64  `json:"value_min_pos"`
+	ValueMax    float64  `json:"value_max"`
+	ZeroColor   string   `json:"zero_color"`
+	NoDataColor string   `json:"nodata_color"`
+	GradColors  []string `json:"grad_colors"`
+	// Scale is how values > 0 map to GradColors (ScaleLinear, ScaleLog, …);
+	// Breaks holds the resulting upper edge of each gradient bin, or the
+	// lower bound of each class for ScaleThreshold.
+	Scale  string    `json:"scale"`
+	Breaks []float64 `json:"breaks"`
+	// Classes are the legend labels of the ScaleThreshold classes.
+	Classes []string `json:"classes,omitempty"`
+	// Center is the pivot of ScaleDiverging.
+	Center *float64 `json:"center,omitempty"`
+	// Range is RangeGlobal or RangeMonth, see Options.Range.
+	Range string `json:"range"`
+	// Clamp, if set, describes the percentiles ValueMinPos and ValueMax
+	// are clamped to, see Options.Clamp.
+	Clamp  *Clamp `json:"clamp,omitempty"`
+	Legend Legend `json:"legend"`
+	// Tooltip is Options.Tooltip.
+	Tooltip string `json:"tooltip,omitempty"`
+	// AxisNames, if set, labels coordinates with names instead of numbers.
+	AxisNames *AxisNames `json:"axis_names,omitempty"`
+	SizeMin   float64    `json:"size_min"`
+	SizeMax   float64    `json:"size_max"`
+	// Months lists the slices in chronological order, see sortPeriods.
+	Months []string `json:"months"`
+	// Gaps lists the calendar months missing between the first and the
+	// last slice if the slices are months (e.g. 2025-03). Filled gaps
+	// (see Options.Gaps) are in Months as well.
+	Gaps []string `json:"gaps,omitempty"`
+	// Smoothed lists the rolling mean slices of Options.Smooth; they
+	// precede Aggregates at the end of Months.
+	Smoothed []string `json:"smoothed,omitempty"`
+	// Aggregates lists the slices combining all others, see
+	// Options.Aggregates; they are the last entries of Months.
+	Aggregates []string `json:"aggregates,omitempty"`
+	// Diff, if set, describes the delta dataset of Options.Diff.
+	Diff *Diff `json:"diff,omitempty"`
+	// Trend, if set, describes the trend dataset of Options.Trend.
+	Trend *Trend `json:"trend,omitempty"`
+	// YoY describes the year-over-year delta datasets of Options.YoY.
+	YoY []Diff `json:"yoy,omitempty"`
+	// Metrics lists the value columns of Options.ValueColumns, the first
+	// being the one this payload shows; see Output.Metrics.
+	Metrics []string `json:"metrics,omitempty"`
+	// SizeMetrics lists the Size column and the other columns of
+	// Options.SizeColumns the page can size points by instead.
+	SizeMetrics []SizeMetric `json:"size_metrics,omitempty"`
+	// ColorMetrics lists the Value column and the numeric extras columns
+	// the page can color the cells by instead, see Options.ViewExtras.
+	ColorMetrics []ColorMetric `json:"color_metrics,omitempty"`
+	// Highlight is the number of highest and lowest values of each slice
+	// whose points have a "highlight" of HighlightTop or HighlightBottom,
+	// signed datasets aside.
+	Highlight int `json:"highlight,omitempty"`
+	// Normalize is Options.Normalize.
+	Normalize string `json:"normalize,omitempty"`
+	// Signed is Options.Signed: every dataset is a signed one.
+	Signed bool `json:"signed,omitempty"`
+	// ExtraTypes, if set, maps every column of Labels.Extras to its type,
+	// ExtraNumber, ExtraDate, ExtraBoolean or ExtraString, and the JSON
+	// encoding of the points' extras holds values of that type; see
+	// Options.TypedExtras.
+	ExtraTypes map[string]string `json:"extra_types,omitempty"`
+	// Anomaly, if set, describes the flagging of the points with an
+	// "anomaly" of true, see Options.Anomaly.
+	Anomaly *Anomaly `json:"anomaly,omitempty"`
+	// Stats summarizes the values of each dataset, Summary those of all
+	// input slices, not filled gaps, smoothed slices or aggregates.
+	Stats   map[string]Stats `json:"stats"`
+	Summary Stats            `json:"summary"`
+	// Marginals, if set, is the aggregate of MonthData.Marginals the page
+	// plots as bars along the axes, one of MarginalKinds.
+	Marginals string `json:"marginals,omitempty"`
+	// GeneratedAt is the build time, RFC 3339; empty for a reproducible
+	// build without a fixed time (Options.Reproducible).
+	GeneratedAt string            `json:"generated_at,omitempty"`
+	Notes       map[string]string `json:"notes,omitempty"`
+	Title       string            `json:"title"`
+	Labels      Labels            `json:"labels"`
+	// Generator, if set, identifies the program that built the payload,
+	// see Options.Generator.
+	Generator *Generator `json:"generator,omitempty"`
+	// Pages maps slice names to page URLs when every slice has its own
+	// page; the page then navigates instead of switching in place.
+	Pages map[string]string `json:"pages,omitempty"`
+	// Metric is the column of Metrics this payload shows, on pages that
+	// show one metric each; the first if empty.
+	Metric string `json:"metric,omitempty"`
+	// MetricPages maps the columns of Metrics to the pages showing the
+	// slice of this payload by them, when every metric has its own pages.
+	MetricPages map[string]string `json:"metric_pages,omitempty"`
+	// Schemes holds the zero and no-data colors per page color scheme
+	// ("dark", "light"); ZeroColor and NoDataColor are the dark ones.
+	Schemes map[string]SchemeColors `json:"schemes"`
+}
+
+// Generator identifies a program build, so that outputs can be traced to
+// it. Empty fields are unknown.
+type Generator struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit,omitempty"`
+	// Date is the commit or build time, RFC 3339.
+	Date string `json:"date,omitempty"`
+}
+
+// Legend configures the color legend of the page and the static charts.
+type Legend struct {
+	// Title replaces the value label above the gradient.
+	Title string `json:"title,omitempty"`
+	// Unit is appended to every value shown, e.g. "ms" or " %".
+	Unit string `json:"unit,omitempty"`
+	// TickCount evenly spaced values are labeled on the gradient; Ticks,
+	// if set, lists the values to label instead.
+	TickCount int       `json:"tick_count,omitempty"`
+	Ticks     []float64 `json:"ticks,omitempty"`
+}
+
+// SchemeColors are the zero and no-data colors of one color scheme.
+type SchemeColors struct {
+	ZeroColor   string `json:"zero_color"`
+	NoDataColor string `json:"nodata_color"`
+}
+
+// Output is the complete payload inlined into the generated page.
+type Output struct {
+	Meta     Meta                  `json:"meta"`
+	Datasets map[string]*MonthData `json:"datasets"`
+	// Metrics holds the payloads of the value columns after the first,
+	// keyed by column, see Options.ValueColumns.
+	Metrics map[string]*Output `json:"metrics,omitempty"`
+}
+
+// Default colors of the heatmap. The light variants are used by the light
+// page color scheme, where the dark greys would read as data.
+const (
+	DefaultZeroColor        = "#555555"
+	DefaultNoDataColor      = "#222222"
+	DefaultLightZeroColor   = "#9e9e9e"
+	DefaultLightNoDataColor = "#e6e6e6"
+)
+
+// DefaultGradColors is the red → yellow → green gradient used for values > 0.
+var DefaultGradColors = []string{"#d73027", "#fdae61", "#fee08b", "#a6d96a", "#1a9850"}
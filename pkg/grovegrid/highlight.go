@@ -0,0 +1,48 @@
+package grovegrid
+
+import "sort"
+
+// The marks of highlighted points, see Options.Highlight.
+const (
+	// HighlightTop marks one of the n highest values of its slice.
+	HighlightTop = "top"
+	// HighlightBottom marks one of the n lowest values of its slice.
+	HighlightBottom = "bottom"
+)
+
+// addHighlights marks the points with the n highest and the n lowest values
+// of every dataset but the deltas and, unless Meta.Signed, the other signed
+// ones, as "highlight" HighlightTop or HighlightBottom; cells without data
+// are not ranked. Ties keep the order of the points, and a point among both
+// the highest and the lowest, in a slice with fewer than 2n values, is a
+// top one.
+func addHighlights(out *Output, n int) {
+	out.Meta.Highlight = n
+	for name, md := range out.Datasets {
+		if out.Meta.delta(name) || (out.Meta.signed(name) && !out.Meta.Signed) {
+			continue
+		}
+		var ranked []map[string]interface{}
+		for _, p := range md.Points {
+			if v, _ := p["value"].(float64); v >= 0 || out.Meta.Signed {
+				ranked = append(ranked, p)
+			}
+		}
+		byValue := func(desc bool) {
+			sort.SliceStable(ranked, func(i, j int) bool {
+				a, b := ranked[i]["value"].(float64), ranked[j]["value"].(float64)
+				return (desc && a > b) || (!desc && a < b)
+			})
+		}
+		byValue(true)
+		for _, p := range ranked[:min(n, len(ranked))] {
+			p["highlight"] = HighlightTop
+		}
+		byValue(false)
+		for _, p := range ranked[:min(n, len(ranked))] {
+			if p["highlight"] == nil {
+				p["highlight"] = HighlightBottom
+			}
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package grovegrid
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// InfluxSource runs a Flux query against InfluxDB 2.x and reads the result
+// tables like CSV rows: Options.Columns (and per-month overrides) name the
+// result columns holding X, Y, Value and Size, with Value defaulting to
+// _value. Rows are bucketed into calendar months by _time and averaged per
+// cell; tags and _field/_measurement become extras.
+type InfluxSource struct {
+	// URL is the InfluxDB base URL, e.g. http://influxdb:8086.
+	URL   string
+	Org   string
+	Token string
+	Query string
+	// Client defaults to an http.Client with a 30s timeout.
+	Client *http.Client
+}
+
+// influxSystemColumns are dropped from result tables before mapping.
+var influxSystemColumns = map[string]bool{"": true, "result": true, "table": true, "_start": true, "_stop": true, "_time": true}
+
+type influxTable struct {
+	header []string
+	// keep holds the indexes of header that are not system columns.
+	keep []int
+	rows map[string][][]string
+}
+
+// Load implements Source.
+func (s *InfluxSource) Load(opts Options) ([]Slice, error) {
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"query":   s.Query,
+		"type":    "flux",
+		"dialect": map[string]interface{}{"header": true, "annotations": []string{}},
+	})
+	u := strings.TrimSuffix(s.URL, "/") + "/api/v2/query?" + url.Values{"org": {s.Org}}.Encode()
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/csv")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Token "+s.Token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInputDir, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Message string `json:"message"`
+		}
+		b, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(b, &e) != nil || e.Message == "" {
+			e.Message = strings.TrimSpace(string(b))
+		}
+		return nil, fmt.Errorf("influxdb: %s: %s", resp.Status, e.Message)
+	}
+
+	tables, err := readInfluxTables(resp.Body, opts.Location)
+	if err != nil {
+		return nil, fmt.Errorf("influxdb: %w", err)
+	}
+	byMonth := map[string][]Record{}
+	labels := map[string]Labels{}
+	for _, t := range tables {
+		header := pick(t.header, t.keep)
+		for month, rows := range t.rows {
+			cols := opts.columnsFor(month)
+			if cols.X == "" || cols.Y == "" {
+				return nil, fmt.Errorf("influxdb: columns.x and columns.y must name result columns")
+			}
+			cols.Value = orDefault(cols.Value, "_value")
+			conv, err := newRowConverter(header, cols)
+			if err != nil {
+				return nil, &ParseError{Path: month, Err: err}
+			}
+			for i, row := range rows {
+				rec, ok, err := conv.record(pick(row, t.keep))
+				if err != nil {
+					return nil, &ParseError{Path: month, Err: fmt.Errorf("row %d, %w", i+1, err)}
+				}
+				if ok {
+					byMonth[month] = append(byMonth[month], rec)
+				}
+			}
+			if _, ok := labels[month]; !ok {
+				labels[month] = conv.labels()
+			}
+		}
+	}
+
+	months := make([]string, 0, len(byMonth))
+	for m := range byMonth {
+		months = append(months, m)
+	}
+	sort.Strings(months)
+	out := make([]Slice, 0, len(months))
+	for _, m := range months {
+		out = append(out, Slice{Name: m, Records: averageCells(byMonth[m]), Labels: labels[m]})
+	}
+	return out, nil
+}
+
+// readInfluxTables splits an annotation-free Flux CSV response into tables
+// and buckets their rows by the calendar month of _time in loc (UTC if
+// nil). A new header row
+// (",result,table,...") starts a new table.
+func readInfluxTables(r io.Reader, loc *time.Location) ([]*influxTable, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	var tables []*influxTable
+	var cur *influxTable
+	timeCol := -1
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(row) > 2 && row[1] == "result" && row[2] == "table" {
+			cur = &influxTable{header: row, rows: map[string][][]string{}}
+			timeCol = -1
+			for i, h := range row {
+				if h == "_time" {
+					timeCol = i
+				}
+				if !influxSystemColumns[h] {
+					cur.keep = append(cur.keep, i)
+				}
+			}
+			if timeCol < 0 {
+				return nil, fmt.Errorf("result table has no _time column")
+			}
+			tables = append(tables, cur)
+			continue
+		}
+		if cur == nil || timeCol >= len(row) {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, row[timeCol])
+		if err != nil {
+			return nil, fmt.Errorf("_time %q: %w", row[timeCol], err)
+		}
+		month := monthKey(inZone(t, loc))
+		cur.rows[month] = append(cur.rows[month], row)
+	}
+	return tables, nil
+}
+
+func pick(row []string, idx []int) []string {
+	out := make([]string, 0, len(idx))
+	for _, i := range idx {
+		if i < len(row) {
+			out = append(out, row[i])
+		} else {
+			out = append(out, "")
+		}
+	}
+	return out
+}
+
+// averageCells merges records sharing a cell into one, averaging Value
+// (ignoring no-data) and Size, sorted by cell. Extras come from the first
+// record.
+func averageCells(recs []Record) []Record {
+	out, _ := mergeDuplicates(recs, DupMean)
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.X != b.X {
+			return a.X < b.X
+		}
+		if a.Y != b.Y {
+			return a.Y < b.Y
+		}
+		if a.XName != b.XName {
+			return a.XName < b.XName
+		}
+		return a.YName < b.YName
+	})
+	return out
+}
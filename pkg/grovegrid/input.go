@@ -0,0 +1,284 @@
+package grovegrid
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Parser reads one slice from r. cols selects the X/Y/Value/Size fields.
+type Parser func(r io.Reader, cols Columns) ([]Record, Labels, error)
+
+// Parsers maps lower-case file extensions to the parser for that format.
+var Parsers = map[string]Parser{
+	".csv":    ParseCSV,
+	".json":   ParseJSON,
+	".jsonl":  ParseJSONL,
+	".ndjson": ParseJSONL,
+}
+
+// Slice is one named time slice read from an input.
+type Slice struct {
+	Name    string
+	Records []Record
+	Labels  Labels
+	// Skipped counts the input rows that held no record, such as blank
+	// lines, for inputs that report them.
+	Skipped int
+	// Notes, if set, is the note of the slice's dataset unless
+	// Options.Months has one, see MonthData.Notes. Build reads it from the
+	// sidecar notes next to the input files, such as 2025-03.notes.md.
+	Notes string
+	// Origin, if set, tells where the slice was read from, such as an
+	// input file, a member of a zip archive or a sheet of a workbook.
+	Origin string
+}
+
+// ReaderSource reads a single slice named Name from R, e.g. os.Stdin.
+type ReaderSource struct {
+	R    io.Reader
+	Name string
+	// Format is a Parsers key such as ".csv" (the default) or ".jsonl".
+	Format string
+}
+
+// Load implements Source.
+func (s *ReaderSource) Load(opts Options) ([]Slice, error) {
+	format := strings.ToLower(orDefault(s.Format, ".csv"))
+	if !strings.HasPrefix(format, ".") {
+		format = "." + format
+	}
+	parse := Parsers[format]
+	if parse == nil {
+		return nil, fmt.Errorf("unsupported input format %q", s.Format)
+	}
+	recs, labels, err := parse(s.R, opts.columnsFor(s.Name))
+	if err != nil {
+		return nil, &ParseError{Path: s.Name, Err: err}
+	}
+	return []Slice{{Name: s.Name, Records: recs, Labels: labels}}, nil
+}
+
+// skipCountingParsers parse like the Parsers of the same extension, but also
+// return the number of rows skipped; loadInput prefers them.
+var skipCountingParsers = map[string]func(r io.Reader, cols Columns) ([]Record, Labels, int, error){
+	".csv": parseCSV,
+}
+
+// parseStream parses r with the parser registered for ext.
+func parseStream(ext string, r io.Reader, cols Columns) ([]Record, Labels, int, error) {
+	if parse := skipCountingParsers[ext]; parse != nil {
+		return parse(r, cols)
+	}
+	recs, labels, err := Parsers[ext](r, cols)
+	return recs, labels, 0, err
+}
+
+// randomAccessParsers read formats that need random access to the data.
+var randomAccessParsers = map[string]func(r io.ReaderAt, size int64, cols Columns) ([]Record, Labels, error){
+	".parquet": ParseParquet,
+}
+
+// supportedInput reports whether name has an extension Build can read,
+// including gzip-compressed inputs (e.g. .csv.gz) and .zip archives.
+func supportedInput(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	switch ext {
+	case ".gz":
+		return supportedInput(strings.TrimSuffix(name, path.Ext(name)))
+	case ".zip", ".xlsx":
+		return true
+	}
+	_, ok := Parsers[ext]
+	return ok || randomAccessParsers[ext] != nil
+}
+
+// inputFiles lists the files in dir that have a registered parser (or are
+// Excel workbooks), sorted.
+func inputFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if supportedInput(e.Name()) {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// SliceNames returns the names of the slices of the input files in dir,
+// sorted, derived from the file names like Build does (see
+// Options.PeriodPattern) without reading the files. Archives and workbooks
+// count as the one slice named after the file, which they hold unless they
+// have several entries or sheets.
+func SliceNames(dir, pattern string) ([]string, error) {
+	files, err := inputFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var names []string
+	for _, f := range files {
+		base := strings.TrimSuffix(filepath.Base(f), ".gz")
+		name, err := periodName(pattern, strings.TrimSuffix(base, path.Ext(base)))
+		if err != nil {
+			return nil, err
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// loadFile returns the slices in the file at path. See loadInput.
+func (o Options) loadFile(path string) ([]Slice, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return o.loadInput(filepath.Base(path), f, st.Size())
+}
+
+// loadInput returns the slices in the input called name. Most inputs hold one
+// slice named after the file (see Options.PeriodPattern); a workbook with several sheets yields one slice
+// per sheet.
+func (o Options) loadInput(name string, r io.ReaderAt, size int64) ([]Slice, error) {
+	ext := strings.ToLower(path.Ext(name))
+	base := strings.TrimSuffix(name, path.Ext(name))
+	switch ext {
+	case ".gz":
+		if inner := strings.ToLower(path.Ext(base)); Parsers[inner] != nil {
+			// stream formats that need no random access
+			zr, err := gzip.NewReader(io.NewSectionReader(r, 0, size))
+			if err != nil {
+				return nil, err
+			}
+			defer zr.Close()
+			sliceName, err := periodName(o.PeriodPattern, strings.TrimSuffix(base, path.Ext(base)))
+			if err != nil {
+				return nil, err
+			}
+			recs, labels, skipped, err := parseStream(inner, zr, o.columnsFor(sliceName))
+			if err != nil {
+				return nil, err
+			}
+			return []Slice{{Name: sliceName, Records: recs, Labels: labels, Skipped: skipped}}, nil
+		}
+		b, err := gunzip(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return nil, err
+		}
+		return o.loadInput(base, bytes.NewReader(b), int64(len(b)))
+	case ".zip":
+		return o.loadZip(r, size)
+	}
+	if ext == ".xlsx" {
+		sheets, err := readXLSX(r, size)
+		if err != nil {
+			return nil, err
+		}
+		var out []Slice
+		for _, sh := range sheets {
+			sliceName := sh.name
+			if len(sheets) == 1 {
+				if sliceName, err = periodName(o.PeriodPattern, base); err != nil {
+					return nil, err
+				}
+			}
+			recs, labels, skipped, err := recordsFromRows(sh.rows, o.columnsFor(sliceName))
+			if err != nil {
+				return nil, fmt.Errorf("sheet %q: %w", sh.name, err)
+			}
+			out = append(out, Slice{Name: sliceName, Records: recs, Labels: labels, Skipped: skipped, Origin: fmt.Sprintf("sheet %q", sh.name)})
+		}
+		return out, nil
+	}
+
+	sliceName, err := periodName(o.PeriodPattern, base)
+	if err != nil {
+		return nil, err
+	}
+	var recs []Record
+	var labels Labels
+	var skipped int
+	if parse := randomAccessParsers[ext]; parse != nil {
+		recs, labels, err = parse(r, size, o.columnsFor(sliceName))
+	} else if Parsers[ext] != nil {
+		recs, labels, skipped, err = parseStream(ext, io.NewSectionReader(r, 0, size), o.columnsFor(sliceName))
+	} else {
+		return nil, fmt.Errorf("unsupported file type")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []Slice{{Name: sliceName, Records: recs, Labels: labels, Skipped: skipped}}, nil
+}
+
+// loadZip loads every supported member of a zip archive; member slices are
+// named after the member file.
+func (o Options) loadZip(r io.ReaderAt, size int64) ([]Slice, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]*zip.File, 0, len(zr.File))
+	for _, f := range zr.File {
+		if !f.FileInfo().IsDir() && supportedInput(f.Name) {
+			members = append(members, f)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Name < members[j].Name })
+
+	var out []Slice
+	for _, f := range members {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		ss, err := o.loadInput(path.Base(f.Name), bytes.NewReader(b), int64(len(b)))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		for i := range ss {
+			ss[i].Origin = joinOrigin(f.Name, ss[i].Origin)
+		}
+		out = append(out, ss...)
+	}
+	return out, nil
+}
+
+func gunzip(r io.Reader) ([]byte, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
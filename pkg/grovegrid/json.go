@@ -0,0 +1,287 @@
+package grovegrid
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Default keys for JSON input when Columns leaves a field empty.
+var defaultJSONKeys = Columns{X: "x", Y: "y", Value: "value", Size: "size"}
+
+// ParseJSON reads one slice from a JSON array of objects. Keys are selected
+// like CSV headers (cols, falling back to x/y/value/size); all other keys
+// become extras. Numbers are taken as-is, null or missing values mean no data.
+func ParseJSON(r io.Reader, cols Columns) ([]Record, Labels, error) {
+	var objs []map[string]json.RawMessage
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(&objs); err != nil {
+		return nil, Labels{}, err
+	}
+	return recordsFromObjects(objs, cols)
+}
+
+// ParseJSONL reads one slice from JSON Lines (one object per line). See ParseJSON.
+func ParseJSONL(r io.Reader, cols Columns) ([]Record, Labels, error) {
+	var objs []map[string]json.RawMessage
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		b := bytes.TrimSpace(sc.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(b, &obj); err != nil {
+			return nil, Labels{}, fmt.Errorf("line %d: %w", line, err)
+		}
+		objs = append(objs, obj)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, Labels{}, err
+	}
+	return recordsFromObjects(objs, cols)
+}
+
+func recordsFromObjects(objs []map[string]json.RawMessage, cols Columns) ([]Record, Labels, error) {
+	if len(objs) == 0 {
+		return nil, Labels{}, fmt.Errorf("empty file")
+	}
+	if err := computeObjects(objs, cols.Computed); err != nil {
+		return nil, Labels{}, err
+	}
+	keep, err := compileFilter(cols.Filter)
+	if err != nil {
+		return nil, Labels{}, err
+	}
+	keySet := map[string]bool{}
+	for _, o := range objs {
+		for k := range o {
+			keySet[k] = true
+		}
+	}
+	keys := make([]string, 0, len(keySet))
+	for k := range keySet {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	resolve := func(name, def string, required bool) (string, error) {
+		explicit := name != ""
+		if !explicit {
+			name = def
+		}
+		if i := headerIndex(keys, name); i >= 0 {
+			return keys[i], nil
+		}
+		if required || explicit {
+			return "", fmt.Errorf("key %q not found", name)
+		}
+		return "", nil
+	}
+	xKey, err := resolve(cols.X, defaultJSONKeys.X, true)
+	if err != nil {
+		return nil, Labels{}, err
+	}
+	yKey, err := resolve(cols.Y, defaultJSONKeys.Y, true)
+	if err != nil {
+		return nil, Labels{}, err
+	}
+	valueKey, err := resolve(cols.Value, defaultJSONKeys.Value, true)
+	if err != nil {
+		return nil, Labels{}, err
+	}
+	sizeKey, err := resolve(cols.Size, defaultJSONKeys.Size, false)
+	if err != nil {
+		return nil, Labels{}, err
+	}
+
+	labels := Labels{X: xKey, Y: yKey, Value: valueKey, Size: "Size", Extras: []string{}}
+	if sizeKey != "" {
+		labels.Size = sizeKey
+	}
+	for _, k := range keys {
+		if k != xKey && k != yKey && k != valueKey && k != sizeKey {
+			labels.Extras = append(labels.Extras, k)
+		}
+	}
+
+	out := make([]Record, 0, len(objs))
+	for i, o := range objs {
+		if cols.Strict {
+			if err := checkObject(o, xKey, yKey, valueKey, sizeKey); err != nil {
+				if err := collectRejected(cols.rejected, fmt.Errorf("record %d, %w", i+1, err)); err != nil {
+					return nil, Labels{}, err
+				}
+				continue
+			}
+		}
+		rec := Record{Extras: map[string]string{}, signed: cols.Signed}
+		var okX, okY bool
+		rec.X, rec.XName, okX = jsonCoord(o[xKey])
+		rec.Y, rec.YName, okY = jsonCoord(o[yKey])
+		if !okX || !okY {
+			return nil, Labels{}, fmt.Errorf("record %d: %s and %s must be numbers or strings", i+1, xKey, yKey)
+		}
+		rec.Value = -1
+		if v, ok := jsonNumber(o[valueKey], valueRe(cols.Signed)); ok {
+			rec.Value = v
+		} else if cols.Signed {
+			continue
+		}
+		if sizeKey != "" {
+			rec.Size, _ = jsonNumber(o[sizeKey], valueRe(cols.Signed))
+		}
+		for _, k := range labels.Extras {
+			if raw, ok := o[k]; ok {
+				rec.Extras[k] = jsonText(raw)
+			}
+		}
+		if keep != nil && !keep(rec) {
+			continue
+		}
+		out = append(out, rec)
+	}
+	return out, labels, nil
+}
+
+// computeObjects adds the keys of the computed columns defs to every
+// object, null where an expression has no result.
+func computeObjects(objs []map[string]json.RawMessage, defs []string) error {
+	comp, err := compileComputed(defs)
+	if err != nil || len(comp) == 0 {
+		return err
+	}
+	for _, o := range objs {
+		keys := make(map[string]string, len(o))
+		for k := range o {
+			keys[normalizeHeader(k)] = k
+		}
+		lookup := func(col string) (float64, bool) {
+			k, ok := keys[normalizeHeader(col)]
+			if !ok {
+				return 0, false
+			}
+			return jsonNumber(o[k], numRe)
+		}
+		for _, cm := range comp {
+			raw := json.RawMessage("null")
+			if v, ok := cm.eval(lookup); ok && !math.IsInf(v, 0) && !math.IsNaN(v) {
+				raw = json.RawMessage(strconv.FormatFloat(v, 'g', -1, 64))
+			}
+			o[cm.name] = raw
+			keys[normalizeHeader(cm.name)] = cm.name
+		}
+	}
+	return nil
+}
+
+// jsonNumber accepts JSON numbers and numeric strings, whose number re
+// matches; null and missing are reported as not ok.
+func jsonNumber(raw json.RawMessage, re *regexp.Regexp) (float64, bool) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, false
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if strings.TrimSpace(s) == "" {
+			return 0, false
+		}
+		return atofSmart(s, re), true
+	}
+	v, err := strconv.ParseFloat(string(raw), 64)
+	return v, err == nil
+}
+
+// checkObject reports the first field of o that strict mode rejects, like
+// rowConverter.check: x and y must be integers, value and size null,
+// missing or a number.
+func checkObject(o map[string]json.RawMessage, x, y, value, size string) error {
+	reject := func(k, want string) error {
+		if len(o[k]) == 0 {
+			return fmt.Errorf("key %s: missing, want %s", k, want)
+		}
+		return fmt.Errorf("key %s: %s is not %s", k, o[k], want)
+	}
+	for _, k := range []string{x, y} {
+		if !jsonInteger(o[k]) {
+			return reject(k, "an integer")
+		}
+	}
+	for _, k := range []string{value, size} {
+		raw := o[k]
+		if k == "" || len(raw) == 0 || string(raw) == "null" {
+			continue
+		}
+		if _, ok := jsonStrictNumber(raw); !ok {
+			return reject(k, "a number")
+		}
+	}
+	return nil
+}
+
+// jsonInteger reports whether raw is an integral number or a string holding
+// an integer.
+func jsonInteger(raw json.RawMessage) bool {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		_, err := strconv.Atoi(strings.TrimSpace(s))
+		return err == nil
+	}
+	v, ok := jsonNumber(raw, numRe)
+	return ok && v == math.Trunc(v)
+}
+
+// jsonStrictNumber is jsonNumber, but reports strings that are not just a
+// number (see strictNumRe) as not ok.
+func jsonStrictNumber(raw json.RawMessage) (float64, bool) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if !strictNumRe.MatchString(strings.TrimSpace(s)) {
+			return 0, false
+		}
+		v, err := parseNumber(s, numRe)
+		return v, err == nil
+	}
+	return jsonNumber(raw, numRe)
+}
+
+// jsonCoord reads a coordinate: a number or integer string, or else a
+// string naming a category.
+func jsonCoord(raw json.RawMessage) (int, string, bool) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return 0, "", false
+		}
+		if v, err := strconv.Atoi(s); err == nil {
+			return v, "", true
+		}
+		return 0, s, true
+	}
+	v, ok := jsonNumber(raw, numRe)
+	return int(v), "", ok
+}
+
+// jsonText renders an extra value: strings unquoted, everything else as JSON.
+func jsonText(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	if string(raw) == "null" {
+		return ""
+	}
+	return string(raw)
+}
@@ -0,0 +1,45 @@
+package grovegrid
+
+import (
+	"net/url"
+	"strings"
+)
+
+// DefaultURLColumn is the extra that links cells when Options.URLColumn is
+// empty.
+const DefaultURLColumn = "url"
+
+// addLinks sets "url" on the points whose extra column (matched like the
+// headers, see headerIndex) holds a link, for the page to open on a click.
+// Only http and https URLs and relative ones are kept, so the data cannot
+// run script in the page.
+func addLinks(out *Output, column string) {
+	want := normalizeHeader(column)
+	for _, md := range out.Datasets {
+		for _, p := range md.Points {
+			ex, _ := p["extras"].(map[string]string)
+			for k, v := range ex {
+				if normalizeHeader(k) == want {
+					if link, ok := linkURL(v); ok {
+						p["url"] = link
+					}
+					break
+				}
+			}
+		}
+	}
+}
+
+// linkURL returns s trimmed if it is an http, https or relative URL.
+func linkURL(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	u, err := url.Parse(s)
+	if s == "" || err != nil {
+		return "", false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https":
+		return s, true
+	}
+	return "", false
+}
@@ -0,0 +1,56 @@
+package grovegrid
+
+// The marginal aggregates the page plots along the axes, see
+// Options.Marginals; both are computed either way.
+const (
+	MarginalSum  = "sum"
+	MarginalMean = "mean"
+)
+
+// MarginalKinds lists the supported marginal aggregates.
+var MarginalKinds = []string{MarginalSum, MarginalMean}
+
+// Marginals holds the aggregates of the rows and columns of a slice: X has
+// one per column from Meta.XMin to Meta.XMax, Y one per row from Meta.YMin
+// to Meta.YMax.
+type Marginals struct {
+	X []Marginal `json:"x"`
+	Y []Marginal `json:"y"`
+}
+
+// Marginal aggregates the cells with data of one row or column. Mean is 0
+// if Count is.
+type Marginal struct {
+	Sum   float64 `json:"sum"`
+	Mean  float64 `json:"mean"`
+	Count int     `json:"count"`
+}
+
+// addMarginals sets the Marginals of every dataset and Meta.Marginals to
+// kind. Cells without data are left out, except in signed datasets, whose
+// cells all have a value.
+func addMarginals(out *Output, kind string) {
+	m := &out.Meta
+	m.Marginals = kind
+	for name, md := range out.Datasets {
+		mg := &Marginals{X: make([]Marginal, m.columns()), Y: make([]Marginal, m.rows())}
+		signed := m.signed(name)
+		for _, h := range md.Heat {
+			if h[2] < 0 && !signed {
+				continue
+			}
+			for _, a := range []*Marginal{&mg.X[int(h[0])-m.XMin], &mg.Y[int(h[1])-m.YMin]} {
+				a.Sum += h[2]
+				a.Count++
+			}
+		}
+		for _, s := range [][]Marginal{mg.X, mg.Y} {
+			for i := range s {
+				if s[i].Count > 0 {
+					s[i].Mean = s[i].Sum / float64(s[i].Count)
+				}
+			}
+		}
+		md.Marginals = mg
+	}
+}
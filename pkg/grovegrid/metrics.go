@@ -0,0 +1,93 @@
+package grovegrid
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// buildMetrics builds one payload per column of opts.ValueColumns and
+// returns the first with the others in Output.Metrics. The inputs are
+// loaded once with the first column as Value; the other value columns are
+// extras of those records, and each metric's records are derived from
+// them with its column as Value and the first column as an extra, so each
+// cell carries all metrics. opts.ValueLabel names the first only.
+func buildMetrics(opts Options) (*Output, error) {
+	o := opts
+	o.ValueColumns = nil
+	o.Columns.Value = opts.ValueColumns[0]
+	o.Columns.metrics = opts.ValueColumns[1:]
+	slices, err := loadSlices(o)
+	if err != nil {
+		return nil, err
+	}
+	// derived before assembling, which merges and normalizes in place
+	derived := make([][]Slice, len(opts.ValueColumns))
+	for i, col := range opts.ValueColumns[1:] {
+		if derived[i+1], err = metricSlices(slices, col, opts.Strict, opts.Signed); err != nil {
+			return nil, err
+		}
+	}
+	first, err := assembleSlices(o, slices)
+	if err != nil {
+		return nil, err
+	}
+	first.Meta.Metrics = opts.ValueColumns
+	first.Metrics = make(map[string]*Output, len(opts.ValueColumns)-1)
+	o.ValueLabel = ""
+	for i, col := range opts.ValueColumns[1:] {
+		if first.Metrics[col], err = assembleSlices(o, derived[i+1]); err != nil {
+			return nil, err
+		}
+	}
+	return first, nil
+}
+
+// metricSlices copies slices with the extras column col as Value. The
+// Value it replaces becomes an extra under the slice's value label. The
+// cells of col are read like value cells: empty is no data, or drops the
+// record if signed, and strict rejects those that are not numbers.
+func metricSlices(slices []Slice, col string, strict, signed bool) ([]Slice, error) {
+	num := valueRe(signed)
+	out := make([]Slice, len(slices))
+	for i, sl := range slices {
+		k := headerIndex(sl.Labels.Extras, col)
+		if k < 0 {
+			return nil, &ParseError{Path: orDefault(sl.Origin, sl.Name), Err: fmt.Errorf("value column %q not found", col)}
+		}
+		key, prev := sl.Labels.Extras[k], sl.Labels.Value
+		labels := sl.Labels
+		labels.Value = key
+		labels.Extras = append([]string(nil), sl.Labels.Extras...)
+		labels.Extras[k] = prev
+		recs := make([]Record, 0, len(sl.Records))
+		for _, r := range sl.Records {
+			text := strings.TrimSpace(r.Extras[key])
+			if strict && text != "" && !strictNumRe.MatchString(text) {
+				return nil, &ParseError{Path: orDefault(sl.Origin, sl.Name), Err: fmt.Errorf("cell (%d, %d), column %s: %q is not a number", r.X, r.Y, key, text)}
+			}
+			if text == "" && signed {
+				continue
+			}
+			extras := make(map[string]string, len(r.Extras))
+			for name, v := range r.Extras {
+				if name != key {
+					extras[name] = v
+				}
+			}
+			extras[prev] = ""
+			if r.Value >= 0 || r.signed {
+				extras[prev] = strconv.FormatFloat(r.Value, 'f', -1, 64)
+			}
+			r.Extras = extras
+			r.Value = -1
+			if text != "" {
+				r.Value = atofSmart(text, num)
+			}
+			recs = append(recs, r)
+		}
+		sl.Records, sl.Labels = recs, labels
+		out[i] = sl
+	}
+	return out, nil
+}
@@ -0,0 +1,55 @@
+package grovegrid
+
+import (
+	"strings"
+	"testing"
+)
+
+// countingSource counts the Load calls of a ReaderSource.
+type countingSource struct {
+	data  string
+	loads int
+}
+
+func (s *countingSource) Load(opts Options) ([]Slice, error) {
+	s.loads++
+	return (&ReaderSource{R: strings.NewReader(s.data), Name: "2024-01"}).Load(opts)
+}
+
+func TestMetricsLoadOnce(t *testing.T) {
+	src := &countingSource{data: "row,position,errors,latency\n1,1,3,120\n1,2,5,\n"}
+	out, err := Build(Options{Source: src, ValueColumns: []string{"errors", "latency"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.loads != 1 {
+		t.Errorf("source loaded %d times, want 1", src.loads)
+	}
+	lat := out.Metrics["latency"]
+	if lat == nil {
+		t.Fatal("no latency payload")
+	}
+	if got := lat.Meta.Labels.Value; got != "latency" {
+		t.Errorf("latency value label %q", got)
+	}
+	heat := map[[2]float64]float64{}
+	for _, h := range lat.Datasets["2024-01"].Heat {
+		heat[[2]float64{h[0], h[1]}] = h[2]
+	}
+	if heat[[2]float64{1, 1}] != 120 || heat[[2]float64{1, 2}] != -1 {
+		t.Errorf("latency heat %v, want 120 at (1,1) and no data at (1,2)", heat)
+	}
+	for _, p := range lat.Datasets["2024-01"].Points {
+		if extras, _ := p["extras"].(map[string]string); p["x"] == 1 && p["y"] == 1 && extras["errors"] != "3" {
+			t.Errorf("latency point %v, want the errors extra 3", p)
+		}
+	}
+	if got := out.Datasets["2024-01"].ValueMax; got != 5 {
+		t.Errorf("errors value max %v, want 5", got)
+	}
+
+	src = &countingSource{data: "row,position,errors,latency\n1,1,3,slow\n"}
+	if _, err := Build(Options{Source: src, Strict: true, ValueColumns: []string{"errors", "latency"}}); err == nil || !strings.Contains(err.Error(), `"slow"`) {
+		t.Errorf("strict: got %v, want the latency cell rejected", err)
+	}
+}
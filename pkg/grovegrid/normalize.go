@@ -0,0 +1,87 @@
+package grovegrid
+
+import "sort"
+
+// The normalizations of Options.Normalize, each computed per slice over its
+// cells with data.
+const (
+	// NormalizeMinMax maps the values to 0..1, the lowest to 0.
+	NormalizeMinMax = "minmax"
+	// NormalizeZScore replaces the values by their standard scores,
+	// (value - mean) / standard deviation. They are signed, so every
+	// dataset is drawn like the Diff one.
+	NormalizeZScore = "zscore"
+	// NormalizeRank replaces the values by their percentile rank, the
+	// percentage of cells with a value up to theirs (0..100].
+	NormalizeRank = "rank"
+)
+
+// Normalizations lists the supported normalizations.
+var Normalizations = []string{NormalizeMinMax, NormalizeZScore, NormalizeRank}
+
+// normalize replaces the values of the records with data of each slice by
+// their normalization mode, keeping the raw values for the points.
+func normalize(slices []Slice, mode string) {
+	for _, sl := range slices {
+		var vs []float64
+		for _, r := range sl.Records {
+			if r.hasData() {
+				vs = append(vs, r.Value)
+			}
+		}
+		if len(vs) == 0 {
+			continue
+		}
+		f := normalizer(vs, mode)
+		for i := range sl.Records {
+			r := &sl.Records[i]
+			if r.hasData() {
+				raw := r.Value
+				r.raw, r.Value = &raw, f(raw)
+			}
+		}
+	}
+}
+
+// normalizer returns the normalization mode of a value among vs, which it
+// sorts.
+func normalizer(vs []float64, mode string) func(float64) float64 {
+	sort.Float64s(vs)
+	switch mode {
+	case NormalizeZScore:
+		mean, sd := spreadOf(vs, AnomalyStdDev)
+		return func(v float64) float64 {
+			if sd == 0 {
+				return 0
+			}
+			return (v - mean) / sd
+		}
+	case NormalizeRank:
+		return func(v float64) float64 {
+			n := sort.Search(len(vs), func(i int) bool { return vs[i] > v })
+			return 100 * float64(n) / float64(len(vs))
+		}
+	}
+	lo, hi := vs[0], vs[len(vs)-1]
+	return func(v float64) float64 {
+		if hi == lo {
+			return 1
+		}
+		return (v - lo) / (hi - lo)
+	}
+}
+
+// signedNormalized replaces the datasets of slices by signed ones of their
+// cells with data, see NormalizeZScore.
+func signedNormalized(out *Output, slices []Slice, bins int) {
+	for _, sl := range slices {
+		md := out.Datasets[sl.Name]
+		var recs []Record
+		for _, r := range sl.Records {
+			if r.raw != nil {
+				recs = append(recs, r)
+			}
+		}
+		out.Datasets[sl.Name] = signedMonth(recs, bins, md.Notes)
+	}
+}
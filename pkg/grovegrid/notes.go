@@ -0,0 +1,66 @@
+package grovegrid
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// notesSuffixes name the sidecar notes of a slice: 2025-03.notes.md holds
+// the note as text, 2025-03.notes.yaml (or .yml) under a "notes" key.
+var notesSuffixes = []string{".notes.md", ".notes.yaml", ".notes.yml"}
+
+// addSidecarNotes sets the Notes of the slices read from files (file i
+// holding parsed[i]) to the first sidecar notes found next to the file,
+// named after the slice or else after the file without extensions.
+func addSidecarNotes(files []string, parsed [][]Slice) error {
+	for i, f := range files {
+		dir := filepath.Dir(f)
+		base, _, _ := strings.Cut(filepath.Base(f), ".")
+		for j := range parsed[i] {
+			sl := &parsed[i][j]
+			for _, name := range []string{sl.Name, base} {
+				notes, err := sidecarNotes(filepath.Join(dir, name))
+				if err != nil {
+					return err
+				}
+				if notes != "" {
+					sl.Notes = notes
+					break
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// sidecarNotes returns the notes of the first sidecar of prefix, "" if it
+// has none.
+func sidecarNotes(prefix string) (string, error) {
+	for _, suffix := range notesSuffixes {
+		path := prefix + suffix
+		b, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if suffix == ".notes.md" {
+			return strings.TrimSpace(string(b)), nil
+		}
+		var doc struct {
+			Notes string `yaml:"notes"`
+		}
+		if err := yaml.Unmarshal(b, &doc); err != nil {
+			return "", fmt.Errorf("%s: %w", path, err)
+		}
+		return strings.TrimSpace(doc.Notes), nil
+	}
+	return "", nil
+}
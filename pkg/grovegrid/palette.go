@@ -0,0 +1,142 @@
+package grovegrid
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Palettes are the built-in gradients for values > 0, low to high. Names
+// are matched case-insensitively; a "_r" suffix reverses a palette.
+var Palettes = map[string][]string{
+	"rdylgn":  DefaultGradColors,
+	"viridis": {"#440154", "#3b528b", "#21918c", "#5ec962", "#fde725"},
+	"magma":   {"#000004", "#51127c", "#b73779", "#fc8961", "#fcfdbf"},
+	"blues":   {"#eff3ff", "#bdd7e7", "#6baed6", "#3182bd", "#08519c"},
+	"cividis": {"#00204d", "#414d6b", "#7c7b78", "#bcaf6f", "#ffea46"},
+	// Okabe-Ito vermillion → yellow → blue, a colorblind-safe stand-in for
+	// the red → green default.
+	"okabe-ito": {"#d55e00", "#e69f00", "#f0e442", "#56b4e9", "#0072b2"},
+}
+
+// ColorblindSafe lists the palettes that stay readable with the common
+// color vision deficiencies.
+var ColorblindSafe = []string{"cividis", "okabe-ito", "viridis"}
+
+// PaletteNames returns the names of the built-in palettes, sorted.
+func PaletteNames() []string {
+	names := make([]string, 0, len(Palettes))
+	for n := range Palettes {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParsePalette resolves s to gradient colors: the name of a built-in
+// palette (see Palettes) or a comma-separated list of #rgb/#rrggbb colors.
+func ParsePalette(s string) ([]string, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "#") {
+		name := strings.ToLower(s)
+		reverse := strings.HasSuffix(name, "_r")
+		name = strings.TrimSuffix(name, "_r")
+		p, ok := Palettes[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown palette %q, want a color list or one of %v", s, PaletteNames())
+		}
+		colors := append([]string(nil), p...)
+		if reverse {
+			for i, j := 0, len(colors)-1; i < j; i, j = i+1, j-1 {
+				colors[i], colors[j] = colors[j], colors[i]
+			}
+		}
+		return colors, nil
+	}
+	var colors []string
+	for _, c := range strings.Split(s, ",") {
+		c = strings.TrimSpace(c)
+		if _, err := parseHexColor(c); err != nil {
+			return nil, err
+		}
+		colors = append(colors, c)
+	}
+	return colors, nil
+}
+
+// RedGreen reports whether one end of colors is red and the other green,
+// the pair that red-green colorblind viewers cannot tell apart.
+func RedGreen(colors []string) bool {
+	if len(colors) < 2 {
+		return false
+	}
+	a, b := hueClass(colors[0]), hueClass(colors[len(colors)-1])
+	return a != b && a != "" && b != ""
+}
+
+// hueClass returns "red" or "green" for a saturated color of that hue.
+func hueClass(s string) string {
+	c, err := parseHexColor(s)
+	if err != nil {
+		return ""
+	}
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	hi, lo := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	if hi == 0 || (hi-lo)/hi < 0.3 {
+		return ""
+	}
+	var h float64
+	switch hi {
+	case r:
+		h = math.Mod((g-b)/(hi-lo), 6) * 60
+	case g:
+		h = ((b-r)/(hi-lo) + 2) * 60
+	default:
+		h = ((r-g)/(hi-lo) + 4) * 60
+	}
+	if h < 0 {
+		h += 360
+	}
+	switch {
+	case h < 25 || h > 335:
+		return "red"
+	case h > 75 && h < 165:
+		return "green"
+	}
+	return ""
+}
+
+// ResampleColors returns n colors spread evenly over the gradient colors,
+// interpolating in RGB between them. Invalid colors are returned unchanged.
+func ResampleColors(colors []string, n int) []string {
+	if n <= 0 || n == len(colors) || len(colors) == 0 {
+		return colors
+	}
+	stops := make([]color.RGBA, len(colors))
+	for i, c := range colors {
+		rgba, err := parseHexColor(c)
+		if err != nil {
+			return colors
+		}
+		stops[i] = rgba
+	}
+	out := make([]string, n)
+	for i := range out {
+		t := 0.0
+		if n > 1 {
+			t = float64(i) / float64(n-1) * float64(len(stops)-1)
+		}
+		j := min(int(t), len(stops)-2)
+		if j < 0 {
+			out[i] = colors[0]
+			continue
+		}
+		f := t - float64(j)
+		a, b := stops[j], stops[j+1]
+		mix := func(x, y uint8) uint8 { return uint8(math.Round(float64(x) + f*(float64(y)-float64(x)))) }
+		out[i] = fmt.Sprintf("#%02x%02x%02x", mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B))
+	}
+	return out
+}
@@ -0,0 +1,185 @@
+package grovegrid
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
+)
+
+// parquetBatch is the number of rows decoded per ReadRows call.
+const parquetBatch = 1024
+
+// ParseParquetFile reads one slice from a Parquet file. See ParseParquet.
+func ParseParquetFile(path string, cols Columns) ([]Record, Labels, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, Labels{}, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, Labels{}, err
+	}
+	return ParseParquet(f, st.Size(), cols)
+}
+
+// ParseParquet reads one slice from Parquet data with a flat schema.
+// Columns are selected like CSV headers; row groups are decoded in batches
+// so large extracts are never materialized as text. Values are read by
+// their logical type: decimals with their scale, dates and timestamps as
+// ISO 8601 text.
+func ParseParquet(r io.ReaderAt, size int64, cols Columns) ([]Record, Labels, error) {
+	pf, err := parquet.OpenFile(r, size)
+	if err != nil {
+		return nil, Labels{}, err
+	}
+
+	schema := pf.Schema()
+	leaves := schema.Columns()
+	header := make([]string, len(leaves))
+	types := make([]*format.LogicalType, len(leaves))
+	for i, path := range leaves {
+		header[i] = strings.Join(path, ".")
+		if leaf, ok := schema.Lookup(path...); ok {
+			types[i] = leaf.Node.Type().LogicalType()
+		}
+	}
+	conv, err := newRowConverter(header, cols)
+	if err != nil {
+		return nil, Labels{}, err
+	}
+
+	out := make([]Record, 0, pf.NumRows())
+	buf := make([]parquet.Row, parquetBatch)
+	cells := make([]string, len(header))
+	rowNum := 0
+	for _, rg := range pf.RowGroups() {
+		rows := rg.Rows()
+		for {
+			n, err := rows.ReadRows(buf)
+			for _, row := range buf[:n] {
+				for i := range cells {
+					cells[i] = ""
+				}
+				for _, v := range row {
+					if c := v.Column(); c >= 0 && c < len(cells) {
+						cells[c] = parquetText(v, types[c])
+					}
+				}
+				rowNum++
+				rec, ok, err := conv.record(cells)
+				if err != nil {
+					if err := conv.reject(fmt.Errorf("row %d, %w", rowNum, err)); err != nil {
+						rows.Close()
+						return nil, Labels{}, err
+					}
+				}
+				if ok {
+					out = append(out, rec)
+				}
+			}
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				rows.Close()
+				return nil, Labels{}, err
+			}
+		}
+		rows.Close()
+	}
+	return out, conv.labels(), nil
+}
+
+// parquetText renders v, of a column with the logical type lt (nil for
+// plain values), as cell text.
+func parquetText(v parquet.Value, lt *format.LogicalType) string {
+	if v.IsNull() {
+		return ""
+	}
+	switch {
+	case lt == nil:
+	case lt.Decimal != nil:
+		return decimalText(parquetUnscaled(v), int(lt.Decimal.Scale))
+	case lt.Date != nil:
+		return time.Unix(int64(v.Int32())*86400, 0).UTC().Format("2006-01-02")
+	case lt.Timestamp != nil:
+		var t time.Time
+		switch u := lt.Timestamp.Unit; {
+		case u.Millis != nil:
+			t = time.UnixMilli(v.Int64())
+		case u.Micros != nil:
+			t = time.UnixMicro(v.Int64())
+		default:
+			t = time.Unix(0, v.Int64())
+		}
+		if !lt.Timestamp.IsAdjustedToUTC {
+			// local time: no zone, so Options.Location applies
+			return t.UTC().Format("2006-01-02T15:04:05.999999999")
+		}
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	switch v.Kind() {
+	case parquet.Boolean:
+		return strconv.FormatBool(v.Boolean())
+	case parquet.Int32:
+		return strconv.FormatInt(int64(v.Int32()), 10)
+	case parquet.Int64:
+		return strconv.FormatInt(v.Int64(), 10)
+	case parquet.Float:
+		return strconv.FormatFloat(float64(v.Float()), 'f', -1, 32)
+	case parquet.Double:
+		return strconv.FormatFloat(v.Double(), 'f', -1, 64)
+	case parquet.Int96:
+		// the legacy timestamp of Impala and Spark: nanoseconds of the day
+		// and the Julian day number
+		i := v.Int96()
+		nanos := int64(uint64(i[1])<<32 | uint64(i[0]))
+		days := int64(i[2]) - 2440588 // Julian day of 1970-01-01
+		return time.Unix(days*86400, nanos).UTC().Format(time.RFC3339Nano)
+	default:
+		return string(v.ByteArray())
+	}
+}
+
+// parquetUnscaled returns the unscaled value of a decimal, stored as an
+// integer or as big-endian two's complement bytes.
+func parquetUnscaled(v parquet.Value) *big.Int {
+	switch v.Kind() {
+	case parquet.Int32:
+		return big.NewInt(int64(v.Int32()))
+	case parquet.Int64:
+		return big.NewInt(v.Int64())
+	}
+	b := v.ByteArray()
+	n := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+	}
+	return n
+}
+
+// decimalText renders unscaled × 10^-scale as a decimal, e.g. 1250 with
+// scale 2 as "12.50".
+func decimalText(unscaled *big.Int, scale int) string {
+	digits := new(big.Int).Abs(unscaled).String()
+	sign := ""
+	if unscaled.Sign() < 0 {
+		sign = "-"
+	}
+	if scale <= 0 {
+		return sign + digits + strings.Repeat("0", -scale)
+	}
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
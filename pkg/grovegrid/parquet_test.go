@@ -0,0 +1,85 @@
+package grovegrid
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestParquetLogicalTypes(t *testing.T) {
+	type row struct {
+		Row      int64     `parquet:"row"`
+		Position int64     `parquet:"position"`
+		Value    int64     `parquet:"value,decimal(2:18)"`
+		Size     float64   `parquet:"size"`
+		Cost     [9]byte   `parquet:"cost,decimal(3:20)"`
+		Day      int32     `parquet:"day,date"`
+		Taken    time.Time `parquet:"taken,timestamp(millisecond)"`
+		Seen     int64     `parquet:"seen,timestamp(microsecond)"`
+	}
+	taken := time.Date(2024, 3, 5, 10, 30, 0, 250e6, time.UTC)
+	rows := []row{{
+		Row:      1,
+		Position: 2,
+		Value:    1250, // 12.50
+		Size:     1,
+		Cost:     [9]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfb, 0x2e}, // -1234, so -1.234
+		Day:      19787,                                                         // 2024-03-05
+		Taken:    taken,
+		Seen:     taken.UnixMicro() + 1,
+	}}
+	path := filepath.Join(t.TempDir(), "2024-03.parquet")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := parquet.Write(f, rows); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	recs, _, err := ParseParquetFile(path, Columns{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 1 {
+		t.Fatalf("got %d records, want 1", len(recs))
+	}
+	r := recs[0]
+	if r.X != 1 || r.Y != 2 || r.Value != 12.5 {
+		t.Errorf("got cell (%d, %d) = %v, want (1, 2) = 12.5", r.X, r.Y, r.Value)
+	}
+	want := map[string]string{
+		"cost":  "-1.234",
+		"day":   "2024-03-05",
+		"taken": "2024-03-05T10:30:00.25Z",
+		"seen":  "2024-03-05T10:30:00.250001Z",
+	}
+	for k, v := range want {
+		if r.Extras[k] != v {
+			t.Errorf("%s: got %q, want %q", k, r.Extras[k], v)
+		}
+	}
+}
+
+func TestDecimalText(t *testing.T) {
+	for _, tt := range []struct {
+		unscaled int64
+		scale    int
+		want     string
+	}{
+		{1250, 2, "12.50"},
+		{5, 3, "0.005"},
+		{-5, 3, "-0.005"},
+		{42, 0, "42"},
+		{42, -2, "4200"},
+	} {
+		if got := decimalText(big.NewInt(tt.unscaled), tt.scale); got != tt.want {
+			t.Errorf("decimalText(%d, %d) = %q, want %q", tt.unscaled, tt.scale, got, tt.want)
+		}
+	}
+}
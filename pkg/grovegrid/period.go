@@ -0,0 +1,274 @@
+package grovegrid
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// monthKey names the calendar month of t, e.g. "2025-03".
+func monthKey(t time.Time) string {
+	return t.Format("2006-01")
+}
+
+// inZone returns t in loc, or in UTC if loc is nil.
+func inZone(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		return t.UTC()
+	}
+	return t.In(loc)
+}
+
+// The periods long-format input can be bucketed into, see Options.Period.
+const (
+	PeriodDay     = "day"
+	PeriodWeek    = "week"
+	PeriodMonth   = "month"
+	PeriodQuarter = "quarter"
+	PeriodYear    = "year"
+)
+
+// Periods lists the supported periods.
+var Periods = []string{PeriodDay, PeriodWeek, PeriodMonth, PeriodQuarter, PeriodYear}
+
+// periodKey names the period of t, e.g. "2025-03-14", "2025-W11" (ISO
+// week), "2025-03", "2025-Q1" or "2025"; monthKey if period is empty.
+func periodKey(t time.Time, period string) string {
+	switch period {
+	case PeriodDay:
+		return t.Format("2006-01-02")
+	case PeriodWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%04d-W%02d", year, week)
+	case PeriodQuarter:
+		return fmt.Sprintf("%04d-Q%d", t.Year(), (int(t.Month())+2)/3)
+	case PeriodYear:
+		return t.Format("2006")
+	}
+	return monthKey(t)
+}
+
+// bucketByDate regroups the records of slices, read from long-format
+// input, into one slice per period of their date column col in loc, in
+// chronological order; the column is dropped from the extras.
+func bucketByDate(slices []Slice, col, period string, loc *time.Location) ([]Slice, error) {
+	byPeriod := map[string]*Slice{}
+	var names []string
+	for _, sl := range slices {
+		key := ""
+		extras := make([]string, 0, len(sl.Labels.Extras))
+		for _, e := range sl.Labels.Extras {
+			if key == "" && normalizeHeader(e) == normalizeHeader(col) {
+				key = e
+			} else {
+				extras = append(extras, e)
+			}
+		}
+		if key == "" {
+			return nil, fmt.Errorf("slice %s: no date column %q", sl.Name, col)
+		}
+		labels := sl.Labels
+		labels.Extras = extras
+		for i, r := range sl.Records {
+			t, err := parseDate(r.Extras[key], loc)
+			if err != nil {
+				return nil, fmt.Errorf("slice %s, record %d: column %s: %w", sl.Name, i+1, key, err)
+			}
+			name := periodKey(inZone(t, loc), period)
+			out := byPeriod[name]
+			if out == nil {
+				out = &Slice{Name: name, Labels: labels}
+				byPeriod[name] = out
+				names = append(names, name)
+			}
+			delete(r.Extras, key)
+			out.Records = append(out.Records, r)
+		}
+	}
+	sortPeriods(names)
+	out := make([]Slice, len(names))
+	for i, n := range names {
+		out[i] = *byPeriod[n]
+	}
+	return out, nil
+}
+
+// dateLayouts are tried in order when a date arrives as text.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"2006-01",
+}
+
+// ParseDate parses a date or timestamp such as "2025-03-14",
+// "2025-03-14 10:00:00", RFC 3339 or just "2025-03"; one without a zone is
+// in UTC.
+func ParseDate(s string) (time.Time, error) {
+	return parseDate(s, nil)
+}
+
+// ParseDateIn is ParseDate, but a date or timestamp without a zone is in
+// loc (UTC if nil).
+func ParseDateIn(s string, loc *time.Location) (time.Time, error) {
+	return parseDate(s, loc)
+}
+
+func parseDate(v interface{}, loc *time.Location) (time.Time, error) {
+	if t, ok := v.(time.Time); ok {
+		return t, nil
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	s := sqlText(v)
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q as a date", s)
+}
+
+// periodName derives the slice name of the input file base (without
+// extension) from pattern, a regular expression; see Options.PeriodPattern.
+// Without a pattern the name is base itself.
+func periodName(pattern, base string) (string, error) {
+	if pattern == "" {
+		return base, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("period pattern: %w", err)
+	}
+	m := re.FindStringSubmatch(base)
+	if m == nil {
+		return "", fmt.Errorf("name %q does not match the period pattern %q", base, pattern)
+	}
+	group := map[string]string{}
+	for i, n := range re.SubexpNames() {
+		if n != "" && m[i] != "" {
+			group[n] = m[i]
+		}
+	}
+	if p, ok := group["period"]; ok {
+		return p, nil
+	}
+	if y, ok := group["year"]; ok {
+		year, err := strconv.Atoi(y)
+		if err != nil {
+			return "", fmt.Errorf("name %q: year %q is not a number", base, y)
+		}
+		num := func(name string) (int, error) {
+			s := group[name]
+			if n, err := strconv.Atoi(s); err == nil {
+				return n, nil
+			}
+			if name == "month" && s != "" {
+				for _, layout := range []string{"Jan", "January"} {
+					if t, err := time.Parse(layout, strings.ToUpper(s[:1])+strings.ToLower(s[1:])); err == nil {
+						return int(t.Month()), nil
+					}
+				}
+			}
+			return 0, fmt.Errorf("name %q: %s %q is not a number", base, name, s)
+		}
+		switch {
+		case group["quarter"] != "":
+			q, err := num("quarter")
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%04d-Q%d", year, q), nil
+		case group["week"] != "":
+			w, err := num("week")
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%04d-W%02d", year, w), nil
+		case group["month"] != "":
+			mo, err := num("month")
+			if err != nil {
+				return "", err
+			}
+			if group["day"] == "" {
+				return fmt.Sprintf("%04d-%02d", year, mo), nil
+			}
+			d, err := num("day")
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%04d-%02d-%02d", year, mo, d), nil
+		}
+		return fmt.Sprintf("%04d", year), nil
+	}
+	if len(m) > 1 {
+		return m[1], nil
+	}
+	return m[0], nil
+}
+
+// periodStart returns the start of the period named key: a day
+// (2025-03-14), an ISO week (2025-W11), a month (2025-03), a quarter
+// (2025-Q1) or a year (2025).
+func periodStart(key string) (time.Time, bool) {
+	for _, layout := range []string{"2006-01-02", "2006-01", "2006"} {
+		if t, err := time.Parse(layout, key); err == nil {
+			return t, true
+		}
+	}
+	var year, n int
+	if _, err := fmt.Sscanf(key, "%4d-Q%d", &year, &n); err == nil && n >= 1 && n <= 4 && len(key) == 7 {
+		return time.Date(year, time.Month(3*n-2), 1, 0, 0, 0, 0, time.UTC), true
+	}
+	if _, err := fmt.Sscanf(key, "%4d-W%2d", &year, &n); err == nil && n >= 1 && n <= 53 && len(key) == 8 {
+		// ISO week 1 holds January 4th
+		jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+		monday := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+		return monday.AddDate(0, 0, 7*(n-1)), true
+	}
+	return time.Time{}, false
+}
+
+// periodEnd returns the end of the period named key, which starts at
+// start: a year, quarter, week or month later, or else a day.
+func periodEnd(key string, start time.Time) time.Time {
+	switch {
+	case len(key) == 4:
+		return start.AddDate(1, 0, 0)
+	case strings.Contains(key, "-Q"):
+		return start.AddDate(0, 3, 0)
+	case strings.Contains(key, "-W"):
+		return start.AddDate(0, 0, 7)
+	case len(key) == 7:
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// sortPeriods sorts slice names chronologically by periodStart, a longer
+// period (by periodEnd) before the shorter ones it starts with, such as
+// 2025, 2025-Q1, 2025-01, 2025-W01; names that are not periods follow in
+// lexical order.
+func sortPeriods(names []string) {
+	sort.SliceStable(names, func(i, j int) bool {
+		ti, oki := periodStart(names[i])
+		tj, okj := periodStart(names[j])
+		switch {
+		case oki && okj && !ti.Equal(tj):
+			return ti.Before(tj)
+		case oki && okj:
+			if ei, ej := periodEnd(names[i], ti), periodEnd(names[j], tj); !ei.Equal(ej) {
+				return ei.After(ej)
+			}
+		case oki != okj:
+			return oki
+		}
+		return names[i] < names[j]
+	})
+}
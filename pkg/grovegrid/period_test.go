@@ -0,0 +1,23 @@
+package grovegrid
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortPeriods(t *testing.T) {
+	// 2025-W01 starts on 2024-12-30
+	names := []string{"notes", "2025-01", "2025-02-01", "2025-Q1", "2025-W01", "2025", "2025-01-01"}
+	sortPeriods(names)
+	want := []string{"2025-W01", "2025", "2025-Q1", "2025-01", "2025-01-01", "2025-02-01", "notes"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+
+	// a week starting on the first of a month follows the month
+	names = []string{"2024-W27", "2024-07"}
+	sortPeriods(names)
+	if want := []string{"2024-07", "2024-W27"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+}
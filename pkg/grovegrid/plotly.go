@@ -0,0 +1,149 @@
+package grovegrid
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PlotlyFigure returns a Plotly figure of one slice: a heatmap trace and a
+// scatter trace sized by Size. The heatmap plots color indexes on a stepped
+// colorscale so the colors match the page exactly; the real values are in
+// customdata and shown on hover.
+func PlotlyFigure(out *Output, month string) (map[string]interface{}, error) {
+	md, ok := out.Datasets[month]
+	if !ok {
+		return nil, fmt.Errorf("no slice %q", month)
+	}
+	m := out.metaFor(month)
+	lb := m.Labels
+	legend := m.legend()
+	n := len(legend)
+
+	xs := make([]int, m.columns())
+	for i := range xs {
+		xs[i] = m.XMin + i
+	}
+	ys := make([]int, m.rows())
+	for i := range ys {
+		ys[i] = m.YMin + i
+	}
+	// z and customdata are indexed [y][x] from YMin and XMin; cells stay
+	// nil without a value
+	z := make([][]interface{}, m.rows())
+	custom := make([][]interface{}, m.rows())
+	for i := range z {
+		z[i] = make([]interface{}, m.columns())
+		custom[i] = make([]interface{}, m.columns())
+	}
+	for _, h := range m.heat(md) {
+		x, y := int(h[0])-m.XMin, int(h[1])-m.YMin
+		z[y][x] = m.colorIndex(h[2])
+		if h[2] < 0 {
+			custom[y][x] = "no data"
+		} else {
+			custom[y][x] = h[2]
+		}
+	}
+	colorscale := make([][2]interface{}, 0, 2*n)
+	tickvals := make([]float64, 0, n)
+	ticktext := make([]string, 0, n)
+	for i, e := range legend {
+		colorscale = append(colorscale, [2]interface{}{float64(i) / float64(n), e.color}, [2]interface{}{float64(i+1) / float64(n), e.color})
+		tickvals = append(tickvals, float64(i)+0.5)
+		ticktext = append(ticktext, e.label)
+	}
+
+	var px, py []int
+	var sizes []float64
+	var text []string
+	for _, p := range md.Points {
+		x, _ := p["x"].(int)
+		y, _ := p["y"].(int)
+		size, _ := p["size"].(float64)
+		extras, _ := p["extras"].(map[string]string)
+		lines := []string{fmt.Sprintf("%s %s, %s %s", lb.X, m.AxisNames.Name("x", x), lb.Y, m.AxisNames.Name("y", y))}
+		if v, _ := p["value"].(float64); v < 0 {
+			lines = append(lines, "no data")
+		} else {
+			lines = append(lines, lb.Value+": "+formatRaw(lb.ValueFormat, v))
+		}
+		lines = append(lines, lb.Size+": "+formatRaw(lb.SizeFormat, size))
+		for _, e := range lb.Extras {
+			if v := extras[e]; v != "" {
+				lines = append(lines, e+": "+v)
+			}
+		}
+		px = append(px, x)
+		py = append(py, y)
+		sizes = append(sizes, m.pointDiameter(size, 24))
+		text = append(text, strings.Join(lines, "<br>"))
+	}
+
+	title := month
+	if m.Title != "" {
+		title = m.Title + " – " + month
+	}
+	axis := func(name, key string, lo, hi int) map[string]interface{} {
+		a := map[string]interface{}{
+			"title":     map[string]interface{}{"text": name, "font": map[string]interface{}{"color": chartAxisName}},
+			"dtick":     1,
+			"showgrid":  false,
+			"zeroline":  false,
+			"linecolor": chartAxisLine,
+		}
+		if m.AxisNames != nil {
+			vals := make([]int, 0, max(0, hi-lo+1))
+			text := make([]string, 0, cap(vals))
+			for i := lo; i <= hi; i++ {
+				vals, text = append(vals, i), append(text, m.AxisNames.Name(key, i))
+			}
+			a["tickmode"], a["tickvals"], a["ticktext"] = "array", vals, text
+		}
+		return a
+	}
+	return map[string]interface{}{
+		"data": []interface{}{
+			map[string]interface{}{
+				"type":          "heatmap",
+				"name":          lb.Value,
+				"x":             xs,
+				"y":             ys,
+				"z":             z,
+				"customdata":    custom,
+				"zmin":          0,
+				"zmax":          n,
+				"colorscale":    colorscale,
+				"xgap":          1,
+				"ygap":          1,
+				"hovertemplate": lb.X + " %{x}, " + lb.Y + " %{y}<br>" + lb.Value + ": %{customdata}<extra></extra>",
+				"colorbar":      map[string]interface{}{"tickvals": tickvals, "ticktext": ticktext, "title": map[string]interface{}{"text": orDefault(m.Legend.Title, lb.Value)}},
+			},
+			map[string]interface{}{
+				"type":      "scatter",
+				"mode":      "markers",
+				"name":      lb.Size,
+				"x":         px,
+				"y":         py,
+				"text":      text,
+				"hoverinfo": "text",
+				"marker": map[string]interface{}{
+					"size":     sizes,
+					"sizemode": "diameter",
+					"color":    chartPoint,
+					"line":     map[string]interface{}{"color": "#000", "width": 0.8},
+				},
+			},
+		},
+		"layout": map[string]interface{}{
+			"title":         map[string]interface{}{"text": title},
+			"paper_bgcolor": chartBackground,
+			"plot_bgcolor":  chartBackground,
+			"font":          map[string]interface{}{"color": chartText},
+			"showlegend":    false,
+			"width":         m.columns()*24 + 160,
+			"height":        m.rows()*24 + 140,
+			"xaxis":         axis(lb.X, "x", m.XMin, m.XMax),
+			"yaxis":         axis(lb.Y, "y", m.YMin, m.YMax),
+		},
+	}, nil
+}
@@ -0,0 +1,252 @@
+package grovegrid
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// PNGOptions configures WritePNG.
+type PNGOptions struct {
+	// Cell is the edge of one grid cell in CSS pixels (default 24).
+	Cell float64
+	// DPI sets the resolution; 96 (the default) is one pixel per CSS pixel,
+	// 192 doubles every dimension. It is also recorded in the file.
+	DPI float64
+}
+
+func (o PNGOptions) withDefaults() PNGOptions {
+	if o.Cell <= 0 {
+		o.Cell = 24
+	}
+	if o.DPI <= 0 {
+		o.DPI = 96
+	}
+	return o
+}
+
+// WritePNGFile writes the heatmap of one slice to path. See WritePNG.
+func WritePNGFile(path string, out *Output, month string, opts PNGOptions) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := WritePNG(f, out, month, opts); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// WritePNG rasterizes the heatmap of one slice with the layout of WriteSVG:
+// title, legend, colored cells, circles sized by Size and axes. Colors must
+// be #rgb or #rrggbb.
+func WritePNG(w io.Writer, out *Output, month string, opts PNGOptions) error {
+	md, ok := out.Datasets[month]
+	if !ok {
+		return fmt.Errorf("no slice %q", month)
+	}
+	opts = opts.withDefaults()
+	m := out.metaFor(month)
+	l := newChartLayout(m, opts.Cell)
+	r := &pngRenderer{scale: opts.DPI / 96, dpi: opts.DPI}
+	r.img = image.NewRGBA(image.Rect(0, 0, r.px(l.width), r.px(l.height)))
+
+	colors := map[string]color.RGBA{}
+	parse := func(s string) (color.RGBA, error) {
+		if c, ok := colors[s]; ok {
+			return c, nil
+		}
+		c, err := parseHexColor(s)
+		if err != nil {
+			return c, err
+		}
+		colors[s] = c
+		return c, nil
+	}
+	for _, s := range append([]string{chartBackground, chartText, chartAxisName, chartAxisLine, chartPoint, m.ZeroColor, m.NoDataColor}, m.GradColors...) {
+		if _, err := parse(s); err != nil {
+			return err
+		}
+	}
+	hex := func(s string) color.RGBA { return colors[s] }
+
+	r.fill(0, 0, l.width, l.height, hex(chartBackground))
+	title := month
+	if m.Title != "" {
+		title = m.Title + " – " + month
+	}
+	if err := r.text(16, 30, 16, title, hex(chartText), 0); err != nil {
+		return err
+	}
+
+	lx := 16.0
+	for _, e := range m.titledLegend() {
+		tx := lx
+		if e.color != "" {
+			r.fill(lx, l.legendTop, 14, 14, hex(e.color))
+			tx += 18
+		}
+		r.text(tx, l.legendTop+11, 11, e.label, hex(chartText), 0)
+		lx += e.width()
+	}
+
+	for _, h := range m.heat(md) {
+		x, y := l.cellOrigin(int(h[0]), int(h[1]))
+		r.fill(x, y, l.cell, l.cell, hex(chartBackground))
+		r.fill(x+0.5, y+0.5, l.cell-1, l.cell-1, hex(m.ColorFor(h[2])))
+	}
+	for _, pt := range md.Points {
+		px, _ := pt["x"].(int)
+		py, _ := pt["y"].(int)
+		size, _ := pt["size"].(float64)
+		x, y := l.cellOrigin(px, py)
+		r.circle(x+l.cell/2, y+l.cell/2, m.pointDiameter(size, l.cell)/2, 0.8, hex(chartPoint))
+	}
+
+	bottom := l.plotTop + l.plotH
+	r.fill(l.plotLeft, bottom, l.plotW, 1, hex(chartAxisLine))
+	r.fill(l.plotLeft, l.plotTop, 1, l.plotH, hex(chartAxisLine))
+	for x := m.XMin; x <= m.XMax; x += l.xTickStep {
+		cx, _ := l.cellOrigin(x, m.YMin)
+		r.text(cx+l.cell/2, bottom+14, 11, m.AxisNames.Name("x", x), hex(chartText), 0.5)
+	}
+	for y := m.YMin; y <= m.YMax; y += l.yTickStep {
+		_, cy := l.cellOrigin(m.XMin, y)
+		r.text(l.plotLeft-6, cy+l.cell/2+4, 11, m.AxisNames.Name("y", y), hex(chartText), 1)
+	}
+	r.text(l.plotLeft+l.plotW/2, bottom+34, 12, m.Labels.X, hex(chartAxisName), 0.5)
+	r.text(16, l.plotTop-8, 12, m.Labels.Y, hex(chartAxisName), 0)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, r.img); err != nil {
+		return err
+	}
+	_, err := w.Write(withPHYs(buf.Bytes(), opts.DPI))
+	return err
+}
+
+// pngRenderer draws in CSS pixels onto an image scaled by scale.
+type pngRenderer struct {
+	img   *image.RGBA
+	scale float64
+	dpi   float64
+	faces map[float64]font.Face
+}
+
+func (r *pngRenderer) px(v float64) int { return int(math.Round(v * r.scale)) }
+
+func (r *pngRenderer) fill(x, y, w, h float64, c color.RGBA) {
+	rect := image.Rect(r.px(x), r.px(y), r.px(x+w), r.px(y+h))
+	draw.Draw(r.img, rect, &image.Uniform{c}, image.Point{}, draw.Src)
+}
+
+// circle draws an anti-aliased disc with a black border of width stroke.
+func (r *pngRenderer) circle(cx, cy, radius, stroke float64, c color.RGBA) {
+	cx, cy, radius, stroke = cx*r.scale, cy*r.scale, radius*r.scale, stroke*r.scale
+	b := image.Rect(int(cx-radius-1), int(cy-radius-1), int(cx+radius+2), int(cy+radius+2)).Intersect(r.img.Bounds())
+	black := color.RGBA{A: 255}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			d := math.Hypot(float64(x)+0.5-cx, float64(y)+0.5-cy)
+			cover := math.Max(0, math.Min(1, radius-d+0.5))
+			if cover == 0 {
+				continue
+			}
+			col := c
+			if t := math.Max(0, math.Min(1, d-(radius-stroke)+0.5)); t > 0 {
+				col = mix(c, black, t)
+			}
+			r.img.SetRGBA(x, y, mix(r.img.RGBAAt(x, y), col, cover))
+		}
+	}
+}
+
+// text draws s with its baseline at y; anchor 0 aligns left, 0.5 centers
+// and 1 aligns right on x.
+func (r *pngRenderer) text(x, y, size float64, s string, c color.RGBA, anchor float64) error {
+	face, err := r.face(size)
+	if err != nil {
+		return err
+	}
+	d := &font.Drawer{Dst: r.img, Src: &image.Uniform{c}, Face: face}
+	width := float64(d.MeasureString(s)) / 64
+	d.Dot = fixed.Point26_6{
+		X: fixed.Int26_6((x*r.scale - width*anchor) * 64),
+		Y: fixed.Int26_6(y * r.scale * 64),
+	}
+	d.DrawString(s)
+	return nil
+}
+
+func (r *pngRenderer) face(size float64) (font.Face, error) {
+	if f, ok := r.faces[size]; ok {
+		return f, nil
+	}
+	ft, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		return nil, err
+	}
+	// size is in CSS pixels: 72pt at 96 DPI is 96px
+	f, err := opentype.NewFace(ft, &opentype.FaceOptions{Size: size * 72 / 96, DPI: r.dpi, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, err
+	}
+	if r.faces == nil {
+		r.faces = map[float64]font.Face{}
+	}
+	r.faces[size] = f
+	return f, nil
+}
+
+func mix(a, b color.RGBA, t float64) color.RGBA {
+	ch := func(x, y uint8) uint8 { return uint8(math.Round(float64(x)*(1-t) + float64(y)*t)) }
+	return color.RGBA{ch(a.R, b.R), ch(a.G, b.G), ch(a.B, b.B), 255}
+}
+
+func parseHexColor(s string) (color.RGBA, error) {
+	h := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(h) == 3 {
+		h = string([]byte{h[0], h[0], h[1], h[1], h[2], h[2]})
+	}
+	v, err := strconv.ParseUint(h, 16, 32)
+	if err != nil || len(h) != 6 || !strings.HasPrefix(strings.TrimSpace(s), "#") {
+		return color.RGBA{}, fmt.Errorf("color %q: want #rgb or #rrggbb", s)
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}, nil
+}
+
+// withPHYs inserts a pHYs chunk recording dpi right after IHDR.
+func withPHYs(b []byte, dpi float64) []byte {
+	const ihdrEnd = 8 + 4 + 4 + 13 + 4 // signature, length, type, data, CRC
+	if len(b) < ihdrEnd {
+		return b
+	}
+	ppm := uint32(math.Round(dpi / 0.0254))
+	chunk := make([]byte, 0, 21)
+	chunk = binary.BigEndian.AppendUint32(chunk, 9)
+	chunk = append(chunk, "pHYs"...)
+	chunk = binary.BigEndian.AppendUint32(chunk, ppm)
+	chunk = binary.BigEndian.AppendUint32(chunk, ppm)
+	chunk = append(chunk, 1) // unit: metre
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+	out := make([]byte, 0, len(b)+len(chunk))
+	out = append(out, b[:ihdrEnd]...)
+	out = append(out, chunk...)
+	return append(out, b[ihdrEnd:]...)
+}
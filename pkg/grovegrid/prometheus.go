@@ -0,0 +1,178 @@
+package grovegrid
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PrometheusSource runs a PromQL range query and maps every series onto a
+// grid cell through two labels holding integer coordinates. Samples are
+// averaged per series and calendar month; each month becomes a slice.
+// Series that map onto the same cell are merged by Options.Duplicates like
+// the rows of a file, in the order Prometheus returns them. The remaining
+// series labels are carried as extras; Columns.Filter applies to the
+// averaged records.
+type PrometheusSource struct {
+	// URL is the Prometheus base URL, e.g. http://prometheus:9090.
+	URL    string
+	Query  string
+	Start  time.Time
+	End    time.Time
+	Step   time.Duration
+	XLabel string
+	YLabel string
+	// Client defaults to an http.Client with a 30s timeout.
+	Client *http.Client
+}
+
+type promResponse struct {
+	Status    string `json:"status"`
+	Error     string `json:"error"`
+	ErrorType string `json:"errorType"`
+	Data      struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string    `json:"metric"`
+			Values [][2]json.RawMessage `json:"values"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// Load implements Source.
+func (s *PrometheusSource) Load(opts Options) ([]Slice, error) {
+	if s.XLabel == "" || s.YLabel == "" {
+		return nil, fmt.Errorf("prometheus: X and Y labels are required")
+	}
+	keep, err := compileFilter(opts.Columns.Filter)
+	if err != nil {
+		return nil, err
+	}
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	step := s.Step
+	if step <= 0 {
+		step = time.Hour
+	}
+	q := url.Values{
+		"query": {s.Query},
+		"start": {strconv.FormatInt(s.Start.Unix(), 10)},
+		"end":   {strconv.FormatInt(s.End.Unix(), 10)},
+		"step":  {strconv.FormatFloat(step.Seconds(), 'f', -1, 64)},
+	}
+	u := strings.TrimSuffix(s.URL, "/") + "/api/v1/query_range?" + q.Encode()
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInputDir, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var pr promResponse
+	if err := json.Unmarshal(body, &pr); err != nil {
+		return nil, fmt.Errorf("prometheus: %s: %w", resp.Status, err)
+	}
+	if pr.Status != "success" {
+		return nil, fmt.Errorf("prometheus: %s: %s", pr.ErrorType, pr.Error)
+	}
+	if pr.Data.ResultType != "matrix" {
+		return nil, fmt.Errorf("prometheus: unexpected result type %q", pr.Data.ResultType)
+	}
+
+	type key struct {
+		month  string
+		series int
+	}
+	type acc struct {
+		x, y   int
+		sum    float64
+		n      int
+		extras map[string]string
+	}
+	accs := map[key]*acc{}
+	var order []key
+	extraKeys := map[string]bool{}
+	for i, series := range pr.Data.Result {
+		x, err := strconv.Atoi(series.Metric[s.XLabel])
+		if err != nil {
+			return nil, fmt.Errorf("prometheus: series %v: label %s is not an integer", series.Metric, s.XLabel)
+		}
+		y, err := strconv.Atoi(series.Metric[s.YLabel])
+		if err != nil {
+			return nil, fmt.Errorf("prometheus: series %v: label %s is not an integer", series.Metric, s.YLabel)
+		}
+		extras := map[string]string{}
+		for k, v := range series.Metric {
+			if k != s.XLabel && k != s.YLabel {
+				extras[k] = v
+				extraKeys[k] = true
+			}
+		}
+		for _, sample := range series.Values {
+			var ts float64
+			var raw string
+			if json.Unmarshal(sample[0], &ts) != nil || json.Unmarshal(sample[1], &raw) != nil {
+				continue
+			}
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			sec, frac := math.Modf(ts)
+			k := key{month: monthKey(inZone(time.Unix(int64(sec), int64(frac*1e9)), opts.Location)), series: i}
+			a := accs[k]
+			if a == nil {
+				a = &acc{x: x, y: y, extras: extras}
+				accs[k] = a
+				order = append(order, k)
+			}
+			a.sum += v
+			a.n++
+		}
+	}
+
+	labels := Labels{X: s.XLabel, Y: s.YLabel, Value: "value", Size: "Size", Extras: []string{}}
+	for k := range extraKeys {
+		labels.Extras = append(labels.Extras, k)
+	}
+	sort.Strings(labels.Extras)
+
+	byMonth := map[string][]Record{}
+	for _, k := range order {
+		a := accs[k]
+		rec := Record{X: a.x, Y: a.y, Value: a.sum / float64(a.n), Extras: a.extras, signed: opts.Signed}
+		if keep != nil && !keep(rec) {
+			continue
+		}
+		byMonth[k.month] = append(byMonth[k.month], rec)
+	}
+	months := make([]string, 0, len(byMonth))
+	for m := range byMonth {
+		months = append(months, m)
+	}
+	sort.Strings(months)
+	out := make([]Slice, 0, len(months))
+	for _, m := range months {
+		recs := byMonth[m]
+		// stable, so colliding series stay in order for Options.Duplicates
+		sort.SliceStable(recs, func(i, j int) bool {
+			if recs[i].X != recs[j].X {
+				return recs[i].X < recs[j].X
+			}
+			return recs[i].Y < recs[j].Y
+		})
+		out = append(out, Slice{Name: m, Records: recs, Labels: labels})
+	}
+	return out, nil
+}
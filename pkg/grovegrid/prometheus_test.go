@@ -0,0 +1,61 @@
+package grovegrid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPrometheusCollidingSeries(t *testing.T) {
+	// two series on cell (1, 2), from different hosts, and one on (2, 1)
+	const body = `{"status":"success","data":{"resultType":"matrix","result":[
+		{"metric":{"rack":"1","slot":"2","host":"a"},"values":[[1709251200,"2"],[1709254800,"4"]]},
+		{"metric":{"rack":"1","slot":"2","host":"b"},"values":[[1709251200,"9"]]},
+		{"metric":{"rack":"2","slot":"1","host":"c"},"values":[[1709251200,"5"]]}]}}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+	src := &PrometheusSource{
+		URL:    srv.URL,
+		Query:  "temp",
+		Start:  time.Unix(1709251200, 0),
+		End:    time.Unix(1709254800, 0),
+		XLabel: "rack",
+		YLabel: "slot",
+	}
+
+	if _, err := Build(Options{Source: src, Duplicates: DupError}); err == nil {
+		t.Error("-dup error: got no error for colliding series")
+	}
+	tests := []struct {
+		policy string
+		value  float64
+		host   string
+	}{
+		{DupFirst, 3, "a"},
+		{DupLast, 9, "b"},
+		{DupSum, 12, "a"},
+		{DupMean, 6, "a"},
+	}
+	for _, tt := range tests {
+		out, err := Build(Options{Source: src, Duplicates: tt.policy})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.policy, err)
+		}
+		var found bool
+		for _, p := range out.Datasets["2024-03"].Points {
+			if p["x"] == 1 && p["y"] == 2 {
+				found = true
+				host := p["extras"].(map[string]string)["host"]
+				if p["value"] != tt.value || host != tt.host {
+					t.Errorf("%s: cell (1, 2) is %v from host %s, want %v from host %s", tt.policy, p["value"], host, tt.value, tt.host)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("%s: no cell (1, 2) in %v", tt.policy, out.Datasets["2024-03"].Points)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package grovegrid
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// URLSource loads slices over HTTP(S). A URL ending in a supported file
+// extension is fetched as a single input; any other URL is treated as a
+// directory listing whose links to supported files are fetched in turn.
+type URLSource struct {
+	URL string
+	// Client defaults to an http.Client with a 30s timeout.
+	Client *http.Client
+	// Retries is the number of extra attempts after a failed request.
+	Retries int
+}
+
+// Load implements Source.
+func (s *URLSource) Load(opts Options) ([]Slice, error) {
+	base, err := url.Parse(s.URL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInputDir, err)
+	}
+	var files []*url.URL
+	if supportedInput(base.Path) {
+		files = []*url.URL{base}
+	} else {
+		listing, err := s.fetch(base.String())
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInputDir, err)
+		}
+		files = linkedInputs(base, listing)
+		if len(files) == 0 {
+			return nil, fmt.Errorf("%w at %s", ErrNoInput, s.URL)
+		}
+	}
+
+	var out []Slice
+	for _, u := range files {
+		b, err := s.fetch(u.String())
+		if err != nil {
+			return nil, &ParseError{Path: u.String(), Err: err}
+		}
+		name, _ := url.PathUnescape(path.Base(u.Path))
+		ss, err := opts.loadInput(name, bytes.NewReader(b), int64(len(b)))
+		if err != nil {
+			return nil, &ParseError{Path: u.String(), Err: err}
+		}
+		out = append(out, ss...)
+	}
+	return out, nil
+}
+
+var hrefRe = regexp.MustCompile(`(?i)href\s*=\s*["']([^"'#?]+)`)
+
+// linkedInputs returns the supported files linked from an HTML listing,
+// resolved against base and sorted.
+func linkedInputs(base *url.URL, listing []byte) []*url.URL {
+	if base.Path != "" && !bytes.HasSuffix([]byte(base.Path), []byte("/")) {
+		b := *base
+		b.Path += "/"
+		base = &b
+	}
+	seen := map[string]bool{}
+	var out []*url.URL
+	for _, m := range hrefRe.FindAllSubmatch(listing, -1) {
+		ref, err := url.Parse(string(m[1]))
+		if err != nil || !supportedInput(ref.Path) {
+			continue
+		}
+		u := base.ResolveReference(ref)
+		if !seen[u.String()] {
+			seen[u.String()] = true
+			out = append(out, u)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].String() < out[j].String() })
+	return out
+}
+
+// fetch GETs u, retrying network errors, 429 and 5xx responses with
+// exponential backoff.
+func (s *URLSource) fetch(u string) ([]byte, error) {
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	var lastErr error
+	for attempt := 0; attempt <= s.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(1<<(attempt-1)) * 500 * time.Millisecond)
+		}
+		resp, err := client.Get(u)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		b, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		switch {
+		case err != nil:
+			lastErr = err
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			lastErr = fmt.Errorf("GET %s: %s", u, resp.Status)
+		case resp.StatusCode != http.StatusOK:
+			return nil, fmt.Errorf("GET %s: %s", u, resp.Status)
+		default:
+			return b, nil
+		}
+	}
+	return nil, lastErr
+}
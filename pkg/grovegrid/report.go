@@ -0,0 +1,156 @@
+package grovegrid
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// monthStats summarizes the cells with data of one slice.
+type monthStats struct {
+	cells          int
+	min, max, mean float64
+	values         map[[2]int]float64
+}
+
+func statsOf(md *MonthData, signed bool) monthStats {
+	s := monthStats{values: map[[2]int]float64{}, min: math.Inf(1), max: math.Inf(-1)}
+	sum := 0.0
+	for _, p := range md.Points {
+		v, _ := p["value"].(float64)
+		if v < 0 && !signed {
+			continue
+		}
+		x, _ := p["x"].(int)
+		y, _ := p["y"].(int)
+		s.values[[2]int{x, y}] = v
+		s.cells++
+		sum += v
+		s.min = math.Min(s.min, v)
+		s.max = math.Max(s.max, v)
+	}
+	if s.cells > 0 {
+		s.mean = sum / float64(s.cells)
+	}
+	return s
+}
+
+// WriteReportFile writes the Markdown report to path. See WriteReport.
+func WriteReportFile(path string, out *Output, top int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := WriteReport(f, out, top); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// WriteReport writes a Markdown summary: one overview table (coverage,
+// min/max/mean and the change of the mean per month) and, per month, the
+// top cells by value with their change against the previous month.
+func WriteReport(w io.Writer, out *Output, top int) error {
+	m := &out.Meta
+	lb := m.Labels
+	bw := bufio.NewWriter(w)
+	p := func(format string, args ...interface{}) { fmt.Fprintf(bw, format, args...) }
+
+	title := m.Title
+	if title == "" {
+		title = "GroveGrid"
+	}
+	p("# %s\n\n", mdCell(title))
+	p("Grid: %d × %d (%s × %s)\n\n", m.columns(), m.rows(), mdCell(lb.X), mdCell(lb.Y))
+	p("| Month | Cells with data | Coverage | Min %[1]s | Max %[1]s | Mean %[1]s | Δ mean |\n", mdCell(lb.Value))
+	p("|---|---:|---:|---:|---:|---:|---:|\n")
+
+	total := m.columns() * m.rows()
+	stats := make([]monthStats, len(m.Months))
+	for i, month := range m.Months {
+		s := statsOf(out.Datasets[month], m.signed(month))
+		stats[i] = s
+		coverage := 0.0
+		if total > 0 {
+			coverage = float64(s.cells) / float64(total) * 100
+		}
+		delta := "–"
+		if i > 0 && s.cells > 0 && stats[i-1].cells > 0 {
+			delta = signedNum(s.mean - stats[i-1].mean)
+		}
+		if s.cells == 0 {
+			p("| %s | 0 | 0%% | – | – | – | – |\n", mdCell(month))
+			continue
+		}
+		p("| %s | %d | %s%% | %s | %s | %s | %s |\n", mdCell(month), s.cells, reportNum(coverage), reportNum(s.min), reportNum(s.max), reportNum(s.mean), delta)
+	}
+
+	for i, month := range m.Months {
+		s := stats[i]
+		p("\n## %s\n\n", mdCell(month))
+		if notes := out.Datasets[month].Notes; notes != "" {
+			p("%s\n\n", mdText.Replace(notes))
+		}
+		if s.cells == 0 {
+			p("No data.\n")
+			continue
+		}
+		cells := make([][2]int, 0, len(s.values))
+		for c := range s.values {
+			cells = append(cells, c)
+		}
+		sort.Slice(cells, func(a, b int) bool {
+			va, vb := s.values[cells[a]], s.values[cells[b]]
+			if va != vb {
+				return va > vb
+			}
+			if cells[a][0] != cells[b][0] {
+				return cells[a][0] < cells[b][0]
+			}
+			return cells[a][1] < cells[b][1]
+		})
+		if top > 0 && len(cells) > top {
+			cells = cells[:top]
+		}
+		p("| %s | %s | %s | Δ vs previous |\n|---:|---:|---:|---:|\n", mdCell(lb.X), mdCell(lb.Y), mdCell(lb.Value))
+		for _, c := range cells {
+			delta := "–"
+			if i > 0 {
+				if prev, ok := stats[i-1].values[c]; ok {
+					delta = signedNum(s.values[c] - prev)
+				}
+			}
+			p("| %d | %d | %s | %s |\n", c[0], c[1], reportNum(s.values[c]), delta)
+		}
+	}
+	return bw.Flush()
+}
+
+func reportNum(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+func signedNum(v float64) string {
+	if v > 0 {
+		return "+" + reportNum(v)
+	}
+	return reportNum(v)
+}
+
+// mdText escapes the HTML Markdown passes through, so labels and names from
+// the input cannot add markup (or script) to the rendered report.
+var mdText = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// mdCell keeps text from breaking a Markdown table row, opening a code
+// span or adding HTML.
+func mdCell(s string) string {
+	return mdCellText.Replace(mdText.Replace(s))
+}
+
+var mdCellText = strings.NewReplacer("|", `\|`, "`", "\\`", "\n", " ")
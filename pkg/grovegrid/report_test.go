@@ -0,0 +1,80 @@
+package grovegrid
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// hostile is input text that would add markup or script to the outputs,
+// or break a Markdown table, if written as is.
+const hostile = "</script><img src=x onerror=alert(1)> a|b `c`"
+
+// hostileOutput builds a payload whose title, labels, slice notes and
+// extras hold hostile.
+func hostileOutput(t *testing.T) *Output {
+	t.Helper()
+	in := t.TempDir()
+	csv := "x,y," + strings.ReplaceAll(hostile, ",", "") + ",size,remark\n1,1,3,1,\"" + hostile + "\"\n1,2,5,2,ok\n"
+	if err := os.WriteFile(filepath.Join(in, "2025-01.csv"), []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := Build(Options{InDir: in, Title: hostile, XLabel: hostile, Months: map[string]MonthOptions{"2025-01": {Notes: hostile}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestWriteReportEscapes(t *testing.T) {
+	out := hostileOutput(t)
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, out, 5); err != nil {
+		t.Fatal(err)
+	}
+	report := buf.String()
+	for _, raw := range []string{"<img", "</script>"} {
+		if strings.Contains(report, raw) {
+			t.Errorf("report holds %q unescaped:\n%s", raw, report)
+		}
+	}
+	// the table rows keep their columns and open no code spans; the notes
+	// are Markdown
+	for _, line := range strings.Split(report, "\n") {
+		want := map[string]int{"| Month |": 8, "| &lt;/script&gt;": 5}
+		for prefix, n := range want {
+			if !strings.HasPrefix(line, prefix) {
+				continue
+			}
+			cells := strings.ReplaceAll(strings.ReplaceAll(line, `\|`, ""), "\\`", "")
+			if got := strings.Count(cells, "|"); got != n || strings.Contains(cells, "`") {
+				t.Errorf("header row has %d separators, want %d, and no code span: %s", got, n, line)
+			}
+		}
+	}
+	for _, want := range []string{"# &lt;/script&gt;&lt;img src=x onerror=alert(1)&gt; a\\|b \\`c\\`", "\n&lt;/script&gt;&lt;img src=x onerror=alert(1)&gt; a|b `c`\n"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report lacks %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestWriteSVGEscapes(t *testing.T) {
+	out := hostileOutput(t)
+	var buf bytes.Buffer
+	if err := WriteSVG(&buf, out, "2025-01"); err != nil {
+		t.Fatal(err)
+	}
+	svg := buf.String()
+	if strings.Contains(svg, "<img") || strings.Contains(svg, "</script>") {
+		t.Errorf("SVG holds markup from the input:\n%s", svg)
+	}
+	esc := "&lt;/script&gt;&lt;img src=x onerror=alert(1)&gt; a|b `c`"
+	for _, want := range []string{"<title>" + esc + " – 2025-01</title>", "<desc>" + esc + "</desc>"} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("SVG lacks %q", want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package grovegrid
+
+import (
+	"math"
+	"strconv"
+)
+
+// Color scales for values > 0, see Options.Scale.
+const (
+	// ScaleLinear splits ValueMinPos..ValueMax into equal-width bins.
+	ScaleLinear = "linear"
+	// ScaleLog splits it into bins of equal width on a log scale, so every
+	// order of magnitude gets its share of the colors.
+	ScaleLog = "log"
+	// ScaleQuantile puts the same number of values > 0 into every bin.
+	ScaleQuantile = "quantile"
+	// ScaleThreshold uses fixed classes from Options.Thresholds. Unlike
+	// the other scales, Breaks holds the lower bound of each class.
+	ScaleThreshold = "threshold"
+	// ScaleDiverging centers the gradient on Options.Center: the colors
+	// below the middle of GradColors cover the values below it, the others
+	// the values above.
+	ScaleDiverging = "diverging"
+)
+
+// Scales lists the supported color scales.
+var Scales = []string{ScaleLinear, ScaleLog, ScaleQuantile, ScaleThreshold, ScaleDiverging}
+
+// Value ranges, see Options.Range.
+const (
+	RangeGlobal = "global"
+	RangeMonth  = "month"
+)
+
+// metaFor returns the Meta to color month with: out.Meta, or with
+// RangeMonth a copy carrying the month's own ranges and breaks.
+func (out *Output) metaFor(month string) *Meta {
+	md, ok := out.Datasets[month]
+	if out.Meta.Range != RangeMonth || !ok {
+		return &out.Meta
+	}
+	m := out.Meta
+	m.ValueMinPos, m.ValueMax = md.ValueMinPos, md.ValueMax
+	m.SizeMin, m.SizeMax = md.SizeMin, md.SizeMax
+	m.Breaks = md.Breaks
+	return &m
+}
+
+// minPositive stands in for ValueMinPos when that is 0.
+const minPositive = 0.00001
+
+// scaleBreaks returns the upper edges of bins gradient bins over lo..hi.
+// The last edge is hi.
+func scaleBreaks(scale string, lo, hi float64, bins int) []float64 {
+	if hi <= 0 || bins == 0 {
+		return nil
+	}
+	if lo <= 0 {
+		lo = minPositive
+	}
+	breaks := make([]float64, bins)
+	if scale == ScaleLog && hi > lo {
+		step := (math.Log(hi) - math.Log(lo)) / float64(bins)
+		for i := range breaks {
+			breaks[i] = math.Exp(math.Log(lo) + step*float64(i+1))
+		}
+	} else {
+		step := (hi - lo) / float64(bins)
+		for i := range breaks {
+			breaks[i] = lo + step*float64(i+1)
+		}
+	}
+	breaks[bins-1] = hi
+	return breaks
+}
+
+// divergingBreaks returns the upper edges of bins bins, the lower half
+// spread over lo..center and the upper half over center..hi. With an odd
+// bin count the middle bin straddles center, else center is an edge.
+func divergingBreaks(center, lo, hi float64, bins int) []float64 {
+	if bins == 0 {
+		return nil
+	}
+	lo, hi = math.Min(lo, center), math.Max(hi, center)
+	breaks := make([]float64, bins)
+	for i := range breaks {
+		t := float64(i+1) / float64(bins)
+		if t <= 0.5 {
+			breaks[i] = lo + t*2*(center-lo)
+		} else {
+			breaks[i] = center + (t-0.5)*2*(hi-center)
+		}
+	}
+	breaks[bins-1] = hi
+	return breaks
+}
+
+// quantileBreaks returns the upper edges of bins bins holding about the
+// same number of values each. values must be sorted and > 0; equal values
+// stay in one bin, so skewed data may leave bins empty.
+func quantileBreaks(values []float64, bins int) []float64 {
+	if len(values) == 0 || bins == 0 {
+		return nil
+	}
+	breaks := make([]float64, bins)
+	for i := range breaks {
+		rank := int(math.Ceil(float64(len(values))*float64(i+1)/float64(bins))) - 1
+		breaks[i] = values[max(rank, 0)]
+	}
+	return breaks
+}
+
+// thresholdClasses returns the legend labels of the classes starting at
+// the ascending thresholds: "0–50", "50–80", …, "≥ 95", formatted with f
+// but without its unit, which the legends add.
+func thresholdClasses(thresholds []float64, f *Format) []string {
+	num := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	if f != nil {
+		unitless := *f
+		unitless.Unit = ""
+		num = unitless.Apply
+	}
+	classes := make([]string, len(thresholds))
+	for i, t := range thresholds {
+		if i == len(thresholds)-1 {
+			classes[i] = "≥ " + num(t)
+		} else {
+			classes[i] = num(t) + "–" + num(thresholds[i+1])
+		}
+	}
+	return classes
+}
+
+// breaks returns Meta.Breaks, or linear breaks for a Meta without them.
+func (m *Meta) breaks() []float64 {
+	if len(m.Breaks) == len(m.GradColors) {
+		return m.Breaks
+	}
+	return scaleBreaks(ScaleLinear, m.ValueMinPos, m.ValueMax, len(m.GradColors))
+}
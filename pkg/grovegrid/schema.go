@@ -0,0 +1,423 @@
+package grovegrid
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SchemaURI is the JSON Schema dialect of OutputSchema.
+const SchemaURI = "https://json-schema.org/draft/2020-12/schema"
+
+// schemaEnums lists the values of the string fields of the payload that
+// have a fixed set, by type and JSON name.
+var schemaEnums = map[string][]string{
+	"Meta.layout": {LayoutRows, LayoutFlat},
+	"Meta.range":  {RangeGlobal, RangeMonth},
+	"Meta.scale":  Scales,
+}
+
+// OutputSchema returns the JSON Schema of the JSON payload, an Output in
+// either layout, derived from the Go types and their JSON names: fields
+// without omitempty are required, and slices, maps and pointers without
+// it may be null. Unknown properties are allowed, so that consumers keep
+// working as fields are added.
+func OutputSchema() map[string]interface{} {
+	g := schemaGen{defs: map[string]interface{}{}}
+	g.defs["Output"] = map[string]interface{}{
+		"type":     "object",
+		"required": []string{"meta", "datasets"},
+		"properties": map[string]interface{}{
+			"meta": g.schema(reflect.TypeOf(Meta{})),
+			"datasets": map[string]interface{}{
+				"type": "object",
+				"additionalProperties": map[string]interface{}{"anyOf": []interface{}{
+					g.schema(reflect.TypeOf(MonthData{})),
+					g.schema(reflect.TypeOf(flatMonth{})),
+				}},
+			},
+			"metrics": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": schemaRef("Output"),
+			},
+		},
+	}
+	// points are maps, see point
+	points := map[string]interface{}{"type": []string{"array", "null"}, "items": schemaRef("Point")}
+	for _, name := range []string{"MonthData", "FlatMonthData"} {
+		g.defs[name].(map[string]interface{})["properties"].(map[string]interface{})["points"] = points
+	}
+	number := map[string]interface{}{"type": "number"}
+	g.defs["Point"] = map[string]interface{}{
+		"type":     "object",
+		"required": []string{"x", "y", "value", "size"},
+		"properties": map[string]interface{}{
+			"x":     map[string]interface{}{"type": "integer"},
+			"y":     map[string]interface{}{"type": "integer"},
+			"value": number,
+			"size":  number,
+			"extras": map[string]interface{}{
+				"type":                 []string{"object", "null"},
+				"additionalProperties": map[string]interface{}{"type": []string{"string", "number", "boolean", "null"}},
+			},
+			"raw":           number,
+			"highlight":     map[string]interface{}{"enum": []string{HighlightTop, HighlightBottom}},
+			"anomaly":       map[string]interface{}{"type": "boolean"},
+			"url":           map[string]interface{}{"type": "string"},
+			"size_metrics":  map[string]interface{}{"type": "object", "additionalProperties": number},
+			"color_metrics": map[string]interface{}{"type": "object", "additionalProperties": number},
+		},
+	}
+	return map[string]interface{}{
+		"$schema":     SchemaURI,
+		"title":       "grovegrid payload",
+		"description": "The JSON payload of a grovegrid page (-json-out, data.json of -external-data): meta describes the grid and datasets holds each slice.",
+		"$ref":        "#/$defs/Output",
+		"$defs":       g.defs,
+	}
+}
+
+func schemaRef(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/$defs/" + name}
+}
+
+// schemaGen collects the definitions of the struct types of the payload.
+type schemaGen struct {
+	defs map[string]interface{}
+}
+
+// schema returns the schema of t, a reference for struct types.
+func (g *schemaGen) schema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Interface:
+		return map[string]interface{}{}
+	case reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.field(t.Elem()), "minItems": t.Len(), "maxItems": t.Len()}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": g.field(t.Elem())}
+	case reflect.Map:
+		s := map[string]interface{}{"type": "object", "additionalProperties": g.field(t.Elem())}
+		if t.Key().Kind() == reflect.Int {
+			s["propertyNames"] = map[string]interface{}{"pattern": "^-?[0-9]+$"}
+		}
+		return s
+	case reflect.Struct:
+		name := t.Name()
+		if t == reflect.TypeOf(flatMonth{}) {
+			name = "FlatMonthData"
+		}
+		if _, ok := g.defs[name]; !ok {
+			g.defs[name] = nil // recursion
+			props := map[string]interface{}{}
+			required := []string{}
+			g.fields(t, name, props, &required)
+			sort.Strings(required)
+			g.defs[name] = map[string]interface{}{"type": "object", "required": required, "properties": props}
+		}
+		return schemaRef(name)
+	}
+	panic("grovegrid: no schema for " + t.String())
+}
+
+// field returns the schema of a value of type t that encodes as null if it
+// is a nil slice, map or pointer.
+func (g *schemaGen) field(t reflect.Type) map[string]interface{} {
+	s := g.schema(t)
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map:
+	default:
+		return s
+	}
+	if typ, ok := s["type"].(string); ok {
+		s["type"] = []string{typ, "null"}
+		return s
+	}
+	return map[string]interface{}{"anyOf": []interface{}{s, map[string]interface{}{"type": "null"}}}
+}
+
+// fields adds the JSON fields of struct t, named def, to props, inlining
+// embedded structs whose fields are not shadowed like encoding/json.
+func (g *schemaGen) fields(t reflect.Type, def string, props map[string]interface{}, required *[]string) {
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			embedded = append(embedded, f.Type)
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		omitempty := strings.Contains(opts, "omitempty")
+		var s map[string]interface{}
+		if omitempty {
+			s = g.schema(f.Type)
+		} else {
+			s = g.field(f.Type)
+			*required = append(*required, name)
+		}
+		if enum, ok := schemaEnums[def+"."+name]; ok {
+			s = map[string]interface{}{"enum": enum}
+		}
+		props[name] = s
+	}
+	for _, e := range embedded {
+		if e.Kind() == reflect.Pointer {
+			e = e.Elem()
+		}
+		inner := map[string]interface{}{}
+		var innerRequired []string
+		g.fields(e, def, inner, &innerRequired)
+		for name, s := range inner {
+			if _, ok := props[name]; !ok {
+				props[name] = s
+				if slices.Contains(innerRequired, name) {
+					*required = append(*required, name)
+				}
+			}
+		}
+	}
+}
+
+// SchemaIssue is a place where a payload does not match OutputSchema.
+type SchemaIssue struct {
+	// Path locates the value, such as datasets.2025-01.points[3].x.
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidateOutput checks the JSON payload data against OutputSchema and
+// returns the issues found; the error is set if data is not JSON.
+func ValidateOutput(data []byte) ([]SchemaIssue, error) {
+	var schema interface{}
+	b, err := json.Marshal(OutputSchema())
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &schema); err != nil {
+		return nil, err
+	}
+	return validateSchema(schema, data)
+}
+
+// validateSchema checks the JSON document data against schema, a decoded
+// JSON Schema using the keywords of OutputSchema: $ref to $defs, type,
+// enum, properties, required, additionalProperties, propertyNames with
+// pattern, items, minItems, maxItems and anyOf.
+func validateSchema(schema interface{}, data []byte) ([]SchemaIssue, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	root, _ := schema.(map[string]interface{})
+	defs, _ := root["$defs"].(map[string]interface{})
+	v := schemaValidator{defs: defs}
+	v.check(root, doc, "")
+	return v.issues, nil
+}
+
+type schemaValidator struct {
+	defs   map[string]interface{}
+	issues []SchemaIssue
+}
+
+func (v *schemaValidator) fail(path, format string, args ...interface{}) {
+	if path == "" {
+		path = "(root)"
+	}
+	v.issues = append(v.issues, SchemaIssue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *schemaValidator) check(s map[string]interface{}, doc interface{}, path string) {
+	if ref, ok := s["$ref"].(string); ok {
+		def, _ := v.defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]interface{})
+		if def == nil {
+			v.fail(path, "unknown schema reference %s", ref)
+			return
+		}
+		v.check(def, doc, path)
+	}
+	if anyOf, ok := s["anyOf"].([]interface{}); ok {
+		// report the branch closest to matching
+		var best []SchemaIssue
+		for i, alt := range anyOf {
+			sub := schemaValidator{defs: v.defs}
+			a, _ := alt.(map[string]interface{})
+			sub.check(a, doc, path)
+			if len(sub.issues) == 0 {
+				best = nil
+				break
+			}
+			if i == 0 || len(sub.issues) < len(best) {
+				best = sub.issues
+			}
+		}
+		v.issues = append(v.issues, best...)
+	}
+	if t, ok := s["type"]; ok && !schemaTypeMatches(t, doc) {
+		v.fail(path, "want %s, got %s", schemaTypeNames(t), jsonTypeName(doc))
+		return
+	}
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if e == doc {
+				found = true
+			}
+		}
+		if !found {
+			v.fail(path, "%v is not one of %v", doc, enum)
+		}
+	}
+	switch d := doc.(type) {
+	case map[string]interface{}:
+		v.checkObject(s, d, path)
+	case []interface{}:
+		if n, ok := s["minItems"].(float64); ok && float64(len(d)) < n {
+			v.fail(path, "want at least %v items, got %d", n, len(d))
+		}
+		if n, ok := s["maxItems"].(float64); ok && float64(len(d)) > n {
+			v.fail(path, "want at most %v items, got %d", n, len(d))
+		}
+		if items, ok := s["items"].(map[string]interface{}); ok {
+			for i, e := range d {
+				v.check(items, e, path+"["+strconv.Itoa(i)+"]")
+			}
+		}
+	}
+}
+
+func (v *schemaValidator) checkObject(s map[string]interface{}, d map[string]interface{}, path string) {
+	join := func(k string) string {
+		if path == "" {
+			return k
+		}
+		return path + "." + k
+	}
+	required, _ := s["required"].([]interface{})
+	for _, r := range required {
+		if k, _ := r.(string); k != "" {
+			if _, ok := d[k]; !ok {
+				v.fail(path, "missing required property %q", k)
+			}
+		}
+	}
+	props, _ := s["properties"].(map[string]interface{})
+	var pattern *regexp.Regexp
+	if pn, ok := s["propertyNames"].(map[string]interface{}); ok {
+		if p, ok := pn["pattern"].(string); ok {
+			pattern, _ = regexp.Compile(p)
+		}
+	}
+	keys := make([]string, 0, len(d))
+	for k := range d {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if pattern != nil && !pattern.MatchString(k) {
+			v.fail(join(k), "property name does not match %s", pattern)
+		}
+		if p, ok := props[k].(map[string]interface{}); ok {
+			v.check(p, d[k], join(k))
+			continue
+		}
+		switch ap := s["additionalProperties"].(type) {
+		case bool:
+			if !ap {
+				v.fail(join(k), "unknown property")
+			}
+		case map[string]interface{}:
+			v.check(ap, d[k], join(k))
+		}
+	}
+}
+
+func schemaTypeMatches(t interface{}, doc interface{}) bool {
+	switch t := t.(type) {
+	case string:
+		return jsonTypeIs(t, doc)
+	case []interface{}:
+		for _, e := range t {
+			if name, _ := e.(string); jsonTypeIs(name, doc) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func jsonTypeIs(name string, doc interface{}) bool {
+	switch d := doc.(type) {
+	case nil:
+		return name == "null"
+	case bool:
+		return name == "boolean"
+	case string:
+		return name == "string"
+	case json.Number:
+		if name == "number" {
+			return true
+		}
+		f, err := d.Float64()
+		return name == "integer" && err == nil && f == math.Trunc(f)
+	case []interface{}:
+		return name == "array"
+	case map[string]interface{}:
+		return name == "object"
+	}
+	return false
+}
+
+func schemaTypeNames(t interface{}) string {
+	if list, ok := t.([]interface{}); ok {
+		names := make([]string, len(list))
+		for i, e := range list {
+			names[i] = fmt.Sprint(e)
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+func jsonTypeName(doc interface{}) string {
+	switch doc.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", doc)
+}
@@ -0,0 +1,191 @@
+package grovegrid
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	sheetsAPI   = "https://sheets.googleapis.com/v4/spreadsheets/"
+	sheetsScope = "https://www.googleapis.com/auth/spreadsheets.readonly"
+)
+
+// SheetsSource loads slices from a Google Sheets spreadsheet using a service
+// account. Every tab (or only those listed in Tabs) becomes a slice named
+// after the tab; the first row is the header and is mapped like a CSV header.
+// Share the spreadsheet with the service account's e-mail address.
+type SheetsSource struct {
+	SpreadsheetID string
+	Tabs          []string
+	// Credentials is the service-account key file (JSON) as downloaded
+	// from the Google Cloud console.
+	Credentials []byte
+	// Client defaults to an http.Client with a 30s timeout.
+	Client *http.Client
+}
+
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// Load implements Source.
+func (s *SheetsSource) Load(opts Options) ([]Slice, error) {
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	token, err := s.accessToken(client)
+	if err != nil {
+		return nil, fmt.Errorf("sheets auth: %w", err)
+	}
+
+	tabs := s.Tabs
+	if len(tabs) == 0 {
+		var meta struct {
+			Sheets []struct {
+				Properties struct {
+					Title string `json:"title"`
+				} `json:"properties"`
+			} `json:"sheets"`
+		}
+		u := sheetsAPI + url.PathEscape(s.SpreadsheetID) + "?fields=sheets.properties.title"
+		if err := sheetsGet(client, token, u, &meta); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInputDir, err)
+		}
+		for _, sh := range meta.Sheets {
+			tabs = append(tabs, sh.Properties.Title)
+		}
+	}
+
+	var out []Slice
+	for _, tab := range tabs {
+		// the titles are chosen by whoever edits the spreadsheet
+		if err := checkSliceName(tab); err != nil {
+			return nil, &ParseError{Path: "sheet " + tab, Err: err}
+		}
+		var vr struct {
+			Values [][]json.RawMessage `json:"values"`
+		}
+		u := sheetsAPI + url.PathEscape(s.SpreadsheetID) + "/values/" + url.PathEscape(sheetsRange(tab)) +
+			"?valueRenderOption=UNFORMATTED_VALUE&dateTimeRenderOption=FORMATTED_STRING"
+		if err := sheetsGet(client, token, u, &vr); err != nil {
+			return nil, &ParseError{Path: "sheet " + tab, Err: err}
+		}
+		if len(vr.Values) == 0 {
+			continue
+		}
+		rows := make([][]string, len(vr.Values))
+		for i, row := range vr.Values {
+			rows[i] = make([]string, len(row))
+			for j, cell := range row {
+				rows[i][j] = jsonText(cell)
+			}
+		}
+		recs, labels, skipped, err := recordsFromRows(rows, opts.columnsFor(tab))
+		if err != nil {
+			return nil, &ParseError{Path: "sheet " + tab, Err: err}
+		}
+		out = append(out, Slice{Name: tab, Records: recs, Labels: labels, Skipped: skipped, Origin: fmt.Sprintf("sheet %q", tab)})
+	}
+	return out, nil
+}
+
+// accessToken exchanges a signed JWT for an OAuth access token.
+func (s *SheetsSource) accessToken(client *http.Client) (string, error) {
+	var sa serviceAccount
+	if err := json.Unmarshal(s.Credentials, &sa); err != nil {
+		return "", fmt.Errorf("credentials: %w", err)
+	}
+	block, _ := pem.Decode([]byte(sa.PrivateKey))
+	if block == nil {
+		return "", errors.New("credentials: no PEM private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("credentials: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", errors.New("credentials: private key is not RSA")
+	}
+	tokenURI := orDefault(sa.TokenURI, "https://oauth2.googleapis.com/token")
+
+	now := time.Now()
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   sa.ClientEmail,
+		"scope": sheetsScope,
+		"aud":   tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	signingInput := header + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	assertion := signingInput + "." + enc.EncodeToString(sig)
+
+	resp, err := client.PostForm(tokenURI, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return "", err
+	}
+	return tok.AccessToken, nil
+}
+
+// sheetsRange quotes a tab title as an A1 range covering the whole tab.
+func sheetsRange(tab string) string {
+	return "'" + strings.ReplaceAll(tab, "'", "''") + "'"
+}
+
+func sheetsGet(client *http.Client, token, u string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("sheets API: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	return dec.Decode(v)
+}
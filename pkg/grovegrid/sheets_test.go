@@ -0,0 +1,68 @@
+package grovegrid
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// handlerTransport answers every request with a handler instead of the
+// network.
+type handlerTransport struct{ h http.Handler }
+
+func (t handlerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	w := httptest.NewRecorder()
+	t.h.ServeHTTP(w, r)
+	return w.Result(), nil
+}
+
+func TestSheetsHostileTab(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds, _ := json.Marshal(serviceAccount{
+		ClientEmail: "grove@example.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI:    "https://oauth2.example/token",
+	})
+	var fetched []string
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Host == "oauth2.example":
+			w.Write([]byte(`{"access_token":"t"}`))
+		case strings.Contains(r.URL.Path, "/values/"):
+			fetched = append(fetched, r.URL.Path)
+			w.Write([]byte(`{"values":[["row","position","value"],[1,1,3]]}`))
+		default:
+			w.Write([]byte(`{"sheets":[{"properties":{"title":"2025-01"}},{"properties":{"title":"../../x"}}]}`))
+		}
+	})
+	src := &SheetsSource{SpreadsheetID: "id", Credentials: creds, Client: &http.Client{Transport: handlerTransport{h}}}
+	_, err = Build(Options{Source: src})
+	var pe *ParseError
+	if !errors.As(err, &pe) || !strings.Contains(err.Error(), "../../x") {
+		t.Fatalf("got %v, want a ParseError naming the tab", err)
+	}
+	for _, p := range fetched {
+		if strings.Contains(p, "../../x") {
+			t.Errorf("fetched the hostile tab: %s", p)
+		}
+	}
+
+	src.Tabs = []string{".."}
+	if _, err := Build(Options{Source: src}); !errors.As(err, &pe) {
+		t.Errorf("-sheets-tabs ..: got %v, want a ParseError", err)
+	}
+}
@@ -0,0 +1,41 @@
+package grovegrid
+
+import "math"
+
+// hasData reports whether r has a value: one >= 0, or any with
+// Options.Signed.
+func (r Record) hasData() bool {
+	return r.Value >= 0 || r.signed
+}
+
+// markSigned marks the values of recs as data, negative ones included, see
+// Options.Signed.
+func markSigned(recs []Record) {
+	for i := range recs {
+		recs[i].signed = true
+	}
+}
+
+// signedSlices replaces the datasets of slices by signed ones, see
+// Options.Signed; with shared they all span the largest absolute value of
+// any slice, as RangeGlobal does.
+func signedSlices(out *Output, slices []Slice, bins int, shared bool) {
+	var maxAbs float64
+	for _, sl := range slices {
+		md := signedMonth(sl.Records, bins, out.Datasets[sl.Name].Notes)
+		out.Datasets[sl.Name] = md
+		maxAbs = math.Max(maxAbs, md.ValueMax)
+	}
+	m := &out.Meta
+	center := 0.0
+	m.Scale, m.Center, m.Classes, m.Clamp = ScaleDiverging, &center, nil, nil
+	m.ValueMinPos, m.ValueMax = -maxAbs, maxAbs
+	m.Breaks = divergingBreaks(0, -maxAbs, maxAbs, bins)
+	if !shared {
+		return
+	}
+	for _, sl := range slices {
+		md := out.Datasets[sl.Name]
+		md.ValueMinPos, md.ValueMax, md.Breaks = m.ValueMinPos, m.ValueMax, m.Breaks
+	}
+}
@@ -0,0 +1,55 @@
+package grovegrid
+
+import "strings"
+
+// SizeMetric is one of the size columns of Options.SizeColumns, with its
+// range over all slices.
+type SizeMetric struct {
+	// Name is the column as given, the key of the point's size_metrics.
+	Name  string  `json:"name"`
+	Label string  `json:"label"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+}
+
+// addSizeMetrics lists cols, the Size column and then extras, as
+// Meta.SizeMetrics and adds the numbers of the extras to every point, as
+// "size_metrics" keyed by column; cells without a number leave them out.
+func addSizeMetrics(out *Output, cols []string) {
+	out.Meta.SizeMetrics = []SizeMetric{{Name: cols[0], Label: out.Meta.Labels.Size, Min: out.Meta.SizeMin, Max: out.Meta.SizeMax}}
+	for _, col := range cols[1:] {
+		m := SizeMetric{Name: col, Label: col}
+		key := ""
+		for _, e := range out.Meta.Labels.Extras {
+			if normalizeHeader(e) == normalizeHeader(col) {
+				key, m.Label = e, e
+				break
+			}
+		}
+		seen := false
+		for _, md := range out.Datasets {
+			for _, p := range md.Points {
+				ex, _ := p["extras"].(map[string]string)
+				s := strings.TrimSpace(ex[key])
+				if key == "" || s == "" {
+					continue
+				}
+				v := atofSmart(s, numRe)
+				sm, _ := p["size_metrics"].(map[string]float64)
+				if sm == nil {
+					sm = map[string]float64{}
+					p["size_metrics"] = sm
+				}
+				sm[col] = v
+				if !seen || v < m.Min {
+					m.Min = v
+				}
+				if !seen || v > m.Max {
+					m.Max = v
+				}
+				seen = true
+			}
+		}
+		out.Meta.SizeMetrics = append(out.Meta.SizeMetrics, m)
+	}
+}
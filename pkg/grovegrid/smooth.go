@@ -0,0 +1,59 @@
+package grovegrid
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SmoothName names the rolling mean slice of slice name over n months, or
+// n slices if per is "slice", e.g. "2025-03 (3-month mean)".
+func SmoothName(name string, n int, per string) string {
+	return name + " (" + strconv.Itoa(n) + "-" + per + " mean)"
+}
+
+// smoothed returns, for each of slices in chronological order (see
+// sortPeriods), a slice holding the mean of each cell over the n months
+// ending with it (n slices if the names are not months), ignoring no data
+// as DupMean does; windows at the start cover fewer slices. The notes map
+// each new slice to its note.
+func smoothed(slices []Slice, n int) ([]Slice, map[string]string) {
+	if n <= 0 || len(slices) == 0 {
+		return nil, nil
+	}
+	byName := make(map[string]Slice, len(slices))
+	names := make([]string, len(slices))
+	for i, sl := range slices {
+		byName[sl.Name] = sl
+		names[i] = sl.Name
+	}
+	sortPeriods(names)
+	sorted := make([]Slice, len(names))
+	for i, n := range names {
+		sorted[i] = byName[n]
+	}
+	per := "slice"
+	if allMonths(names) {
+		per = "month"
+	}
+	out := make([]Slice, 0, len(sorted))
+	notes := make(map[string]string, len(sorted))
+	for i, sl := range sorted {
+		var window []Record
+		from := i
+		for j := i; j >= 0; j-- {
+			if (per == "month" && monthsBetween(names[j], names[i]) >= n) || (per == "slice" && i-j >= n) {
+				break
+			}
+			from = j
+			window = append(window, sorted[j].Records...)
+		}
+		recs, _ := mergeDuplicates(window, DupMean)
+		// every slice needs records of its own, categorize updates them
+		recs = append([]Record(nil), recs...)
+		name := SmoothName(sl.Name, n, per)
+		out = append(out, Slice{Name: name, Records: recs, Labels: sl.Labels})
+		notes[name] = fmt.Sprintf("Rolling mean of each cell over the %d %ss ending %s, from the slices %s.", n, per, sl.Name, strings.Join(names[from:i+1], ", "))
+	}
+	return out, notes
+}
@@ -0,0 +1,140 @@
+package grovegrid
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// SQLSource loads slices from a database query. Each result row is one
+// record; the PeriodColumn value names the slice the row belongs to and all
+// other result columns are mapped like CSV headers.
+type SQLSource struct {
+	DB    *sql.DB
+	Query string
+	// Args are bound to the placeholders in Query.
+	Args []any
+	// PeriodColumn names the result column used for bucketing (default "month").
+	PeriodColumn string
+	// DateColumn, if set, replaces PeriodColumn: rows are bucketed into
+	// periods of Options.Period (calendar months such as "2006-01" by
+	// default) by the date or timestamp in this column.
+	DateColumn string
+}
+
+// Load implements Source.
+func (s *SQLSource) Load(opts Options) ([]Slice, error) {
+	periodCol := orDefault(s.PeriodColumn, "month")
+	if s.DateColumn != "" {
+		periodCol = s.DateColumn
+	}
+	rows, err := s.DB.Query(s.Query, s.Args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	periodIdx := headerIndex(names, periodCol)
+	if periodIdx < 0 {
+		return nil, fmt.Errorf("query result has no column %q", periodCol)
+	}
+	header := make([]string, 0, len(names)-1)
+	for i, n := range names {
+		if i != periodIdx {
+			header = append(header, n)
+		}
+	}
+	cols := opts.Columns
+	cols.Strict = opts.Strict
+	cols.Signed = opts.Signed
+	cols.Date = "" // see DateColumn
+	conv, err := newRowConverter(header, cols)
+	if err != nil {
+		return nil, err
+	}
+
+	vals := make([]interface{}, len(names))
+	ptrs := make([]interface{}, len(names))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	byPeriod := map[string][]Record{}
+	var order []string
+	cells := make([]string, len(header))
+	rowNum := 0
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		rowNum++
+		period := sqlText(vals[periodIdx])
+		if t, ok := vals[periodIdx].(time.Time); ok {
+			period = periodKey(inZone(t, opts.Location), opts.Period)
+		}
+		if s.DateColumn != "" {
+			t, err := parseDate(vals[periodIdx], opts.Location)
+			if err != nil {
+				return nil, &ParseError{Path: "query", Err: fmt.Errorf("row %d, column %s: %w", rowNum, periodCol, err)}
+			}
+			period = periodKey(inZone(t, opts.Location), opts.Period)
+		}
+		j := 0
+		for i, v := range vals {
+			if i != periodIdx {
+				cells[j] = sqlText(v)
+				j++
+			}
+		}
+		rec, ok, err := conv.record(cells)
+		if err != nil {
+			if err := conv.reject(fmt.Errorf("row %d, %w", rowNum, err)); err != nil {
+				return nil, &ParseError{Path: "query", Err: err}
+			}
+		}
+		if !ok {
+			continue
+		}
+		if _, seen := byPeriod[period]; !seen {
+			order = append(order, period)
+		}
+		byPeriod[period] = append(byPeriod[period], rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	labels := conv.labels()
+	out := make([]Slice, 0, len(order))
+	for _, p := range order {
+		out = append(out, Slice{Name: p, Records: byPeriod[p], Labels: labels})
+	}
+	return out, nil
+}
+
+// sqlText renders a scanned database value as cell text. Times, which
+// the period column turns into period names before, are RFC 3339.
+func sqlText(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(t)
+	case string:
+		return t
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	case time.Time:
+		return t.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(t)
+	}
+}
@@ -0,0 +1,95 @@
+package grovegrid
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+// openSQLite returns a database at a new path holding the statements run.
+func openSQLite(t *testing.T, stmts ...string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "grove.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestSQLSliceNames(t *testing.T) {
+	for _, month := range []string{"../../escaped", "a/b", `a\b`, "..", ".", ".hidden", "", "2025-01\n"} {
+		db := openSQLite(t,
+			"CREATE TABLE trees (month TEXT, row INTEGER, position INTEGER, value REAL)",
+			"INSERT INTO trees VALUES ('2025-01', 1, 1, 3)",
+		)
+		if _, err := db.Exec("INSERT INTO trees VALUES (?, 1, 2, 4)", month); err != nil {
+			t.Fatal(err)
+		}
+		src := &SQLSource{DB: db, Query: "SELECT month, row, position, value FROM trees"}
+		_, err := Build(Options{Source: src})
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Errorf("month %q: got %v, want a ParseError", month, err)
+		}
+	}
+}
+
+func TestCheckSliceName(t *testing.T) {
+	for name, ok := range map[string]bool{
+		"2025-01":             true,
+		"2025-Q1":             true,
+		"ALL (mean)":          true,
+		"Werk Straße 2":       true,
+		"":                    false,
+		".":                   false,
+		"..":                  false,
+		".git":                false,
+		"../x":                false,
+		"x/..":                false,
+		`..\x`:                false,
+		"tab\there":           false,
+		"2025-01\x00.html":    false,
+		"DIFF (a - b)\u0085x": false,
+	} {
+		if err := checkSliceName(name); (err == nil) != ok {
+			t.Errorf("checkSliceName(%q) = %v, want ok %v", name, err, ok)
+		}
+	}
+}
+
+func TestSQLStrictRows(t *testing.T) {
+	db := openSQLite(t,
+		"CREATE TABLE trees (month TEXT, row INTEGER, position INTEGER, value TEXT)",
+		"INSERT INTO trees VALUES ('2025-01', 1, 1, '3'), ('2025-01', 1, 2, '12abc'), ('2025-01', 2, 1, '4'), ('2025-01', 2, 2, 'approx 7')",
+	)
+	opts := Options{Source: &SQLSource{DB: db, Query: "SELECT month, row, position, value FROM trees"}, Strict: true}
+	_, err := Build(opts)
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Errorf("Build: got %v, want a ParseError", err)
+	}
+
+	v, err := Validate(opts, ValueRange{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var malformed []string
+	for _, is := range v.Issues {
+		if is.Kind == IssueMalformed {
+			malformed = append(malformed, is.Message)
+		}
+	}
+	if len(malformed) != 2 || !strings.HasPrefix(malformed[0], "row 2,") || !strings.HasPrefix(malformed[1], "row 4,") {
+		t.Errorf("malformed issues %q, want rows 2 and 4", malformed)
+	}
+}
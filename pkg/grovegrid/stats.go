@@ -0,0 +1,57 @@
+package grovegrid
+
+// Stats summarizes the values of the cells with data of one or more
+// slices; the other fields are 0 if Count is.
+type Stats struct {
+	Count int `json:"count"`
+	// Coverage is the percentage of the grid cells with data.
+	Coverage float64 `json:"coverage"`
+	Mean     float64 `json:"mean"`
+	Median   float64 `json:"median"`
+	// StdDev is the population standard deviation.
+	StdDev float64 `json:"stddev"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+}
+
+// addStats sets Meta.Stats for every dataset and Meta.Summary over the
+// input slices names. Cells without data are left out, except in signed
+// datasets, whose cells all have a value.
+func addStats(out *Output, names []string) {
+	m := &out.Meta
+	values := func(name string) []float64 {
+		var vs []float64
+		for _, h := range out.Datasets[name].Heat {
+			if h[2] >= 0 || m.signed(name) {
+				vs = append(vs, h[2])
+			}
+		}
+		return vs
+	}
+	grid := m.columns() * m.rows()
+	m.Stats = make(map[string]Stats, len(out.Datasets))
+	for name := range out.Datasets {
+		m.Stats[name] = statsOfValues(values(name), grid)
+	}
+	var all []float64
+	for _, name := range names {
+		all = append(all, values(name)...)
+	}
+	m.Summary = statsOfValues(all, grid*len(names))
+}
+
+// statsOfValues summarizes vs, the values of cells out of grid; it sorts
+// vs.
+func statsOfValues(vs []float64, grid int) Stats {
+	s := Stats{Count: len(vs)}
+	if len(vs) == 0 {
+		return s
+	}
+	if grid > 0 {
+		s.Coverage = 100 * float64(len(vs)) / float64(grid)
+	}
+	s.Median = median(vs)
+	s.Min, s.Max = vs[0], vs[len(vs)-1]
+	s.Mean, s.StdDev = spreadOf(vs, AnomalyStdDev)
+	return s
+}
@@ -0,0 +1,263 @@
+package grovegrid
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"os"
+	"strconv"
+)
+
+// Chart colors shared by the static renderers; they match the page.
+const (
+	chartBackground = "#0b0e11"
+	chartText       = "#cbd5dc"
+	chartAxisName   = "#9aa4ad"
+	chartAxisLine   = "#44515c"
+	chartPoint      = "#91cc75"
+)
+
+// ColorFor maps a cell value to its color the way the page's visual map
+// does: -1 is no data, 0 the zero color, and values above 0 fall into the
+// gradient bin whose upper edge (Meta.Breaks) they do not exceed, or for
+// ScaleThreshold into the last class whose lower bound they reach.
+func (m *Meta) ColorFor(v float64) string {
+	switch i := m.colorIndex(v); i {
+	case 0:
+		return m.NoDataColor
+	case 1:
+		return m.ZeroColor
+	default:
+		return m.GradColors[i-2]
+	}
+}
+
+// colorIndex numbers the colors of ColorFor: 0 is no data, 1 zero and
+// 2+i the gradient bin i.
+func (m *Meta) colorIndex(v float64) int {
+	switch {
+	case v < 0:
+		return 0
+	case v == 0 || len(m.GradColors) == 0:
+		return 1
+	}
+	breaks := m.breaks()
+	if m.Scale == ScaleThreshold {
+		i := 0
+		for i+1 < len(breaks) && v >= breaks[i+1] {
+			i++
+		}
+		return 2 + i
+	}
+	for i, b := range breaks {
+		if v <= b+1e-9 {
+			return 2 + i
+		}
+	}
+	return 2 + len(m.GradColors) - 1
+}
+
+// ColorThresholds describes ColorFor as a threshold scale: values below
+// domain[0] get colors[0], values in [domain[i-1], domain[i]) get colors[i]
+// and values from the last threshold up get the last color. Bin edges are
+// nudged up so that, like the page, a bin includes its upper edge.
+func (m *Meta) ColorThresholds() (domain []float64, colors []string) {
+	const eps = 1e-12
+	domain = []float64{0, eps}
+	colors = []string{m.NoDataColor, m.ZeroColor}
+	breaks := m.breaks()
+	if len(breaks) == 0 {
+		return domain, append(colors, m.ZeroColor)
+	}
+	if m.Scale == ScaleThreshold {
+		for _, b := range breaks[1:] {
+			domain = append(domain, b)
+		}
+		return domain, append(colors, m.GradColors...)
+	}
+	for _, b := range breaks[:len(breaks)-1] {
+		domain = append(domain, b+eps)
+	}
+	return domain, append(colors, m.GradColors...)
+}
+
+// legendEntry is one swatch of the static legend; a title has no color.
+type legendEntry struct {
+	color, label string
+}
+
+// legend returns one entry per color, in colorIndex order.
+func (m *Meta) legend() []legendEntry {
+	f := m.valueFormat()
+	out := []legendEntry{{m.NoDataColor, "no data"}, {m.ZeroColor, f.Apply(0)}}
+	if len(m.Classes) == len(m.GradColors) {
+		unit := ""
+		if f != nil {
+			unit = f.Unit
+		}
+		for i, c := range m.Classes {
+			out = append(out, legendEntry{m.GradColors[i], c + unit})
+		}
+		return out
+	}
+	for i, b := range m.breaks() {
+		out = append(out, legendEntry{m.GradColors[i], "≤ " + f.Apply(b)})
+	}
+	return out
+}
+
+// titledLegend is legend with the legend title, if any, in front.
+func (m *Meta) titledLegend() []legendEntry {
+	if m.Legend.Title == "" {
+		return m.legend()
+	}
+	return append([]legendEntry{{"", m.Legend.Title}}, m.legend()...)
+}
+
+// width is the space e takes in a static legend.
+func (e legendEntry) width() float64 {
+	w := float64(len([]rune(e.label)))*6.5 + 14
+	if e.color != "" {
+		w += 18
+	}
+	return w
+}
+
+// pointDiameter scales the page's 6–28px circle sizes to cell.
+func (m *Meta) pointDiameter(size float64, cell float64) float64 {
+	d := 6.0
+	if m.SizeMax > m.SizeMin {
+		t := (size - m.SizeMin) / (m.SizeMax - m.SizeMin)
+		d = math.Max(6, math.Min(28, 6+t*22))
+	}
+	return d * cell / 24
+}
+
+// chartLayout positions the parts of a static chart, in pixels.
+type chartLayout struct {
+	cell                 float64
+	width, height        float64
+	plotLeft, plotTop    float64
+	plotW, plotH         float64
+	legendTop            float64
+	xTickStep, yTickStep int
+	xMin, yMax           int
+}
+
+func newChartLayout(m *Meta, cell float64) chartLayout {
+	const pad, charW = 16.0, 7.0
+	yDigits := float64(m.tickWidth("y", m.YMin, m.YMax))
+	l := chartLayout{cell: cell, legendTop: pad + 28, xMin: m.XMin, yMax: m.YMax}
+	l.plotLeft = pad + yDigits*charW + 12
+	l.plotTop = l.legendTop + 40
+	l.plotW = float64(m.columns()) * cell
+	l.plotH = float64(m.rows()) * cell
+	legendW := pad
+	for _, e := range m.titledLegend() {
+		legendW += e.width()
+	}
+	l.width = math.Max(math.Max(l.plotLeft+l.plotW+pad, 480), legendW+pad)
+	l.height = l.plotTop + l.plotH + 44
+	l.xTickStep = int(math.Max(1, math.Ceil((float64(m.tickWidth("x", m.XMin, m.XMax))*charW+6)/cell)))
+	l.yTickStep = int(math.Max(1, math.Ceil(16/cell)))
+	return l
+}
+
+// tickWidth is the length in runes of the longest tick label of lo..hi.
+func (m *Meta) tickWidth(axis string, lo, hi int) int {
+	w := len(strconv.Itoa(hi))
+	for i := lo; i <= hi; i++ {
+		w = max(w, len([]rune(m.AxisNames.Name(axis, i))))
+	}
+	return w
+}
+
+// cellOrigin is the top-left corner of cell (x, y); y=YMin is the bottom
+// row.
+func (l chartLayout) cellOrigin(x, y int) (float64, float64) {
+	return l.plotLeft + float64(x-l.xMin)*l.cell, l.plotTop + float64(l.yMax-y)*l.cell
+}
+
+// WriteSVGFile writes the heatmap of one slice to path. See WriteSVG.
+func WriteSVGFile(path string, out *Output, month string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := WriteSVG(f, out, month); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// WriteSVG renders the heatmap of one slice as a standalone SVG: cells
+// colored like the page, circles sized by Size, axes, title and legend.
+func WriteSVG(w io.Writer, out *Output, month string) error {
+	md, ok := out.Datasets[month]
+	if !ok {
+		return fmt.Errorf("no slice %q", month)
+	}
+	m := out.metaFor(month)
+	l := newChartLayout(m, 24)
+	bw := bufio.NewWriter(w)
+	p := func(format string, args ...interface{}) { fmt.Fprintf(bw, format, args...) }
+	esc := html.EscapeString
+
+	title := month
+	if m.Title != "" {
+		title = m.Title + " – " + month
+	}
+	p(`<svg xmlns="http://www.w3.org/2000/svg" width="%g" height="%g" viewBox="0 0 %g %g" font-family="sans-serif">`+"\n", l.width, l.height, l.width, l.height)
+	// title and desc name the chart for screen readers and as tooltip
+	p("<title>%s</title>\n", esc(title))
+	if md.Notes != "" {
+		p("<desc>%s</desc>\n", esc(md.Notes))
+	}
+	p(`<rect width="100%%" height="100%%" fill="%s"/>`+"\n", chartBackground)
+	p(`<text x="16" y="30" font-size="16" fill="%s">%s</text>`+"\n", chartText, esc(title))
+
+	// legend
+	lx := 16.0
+	for _, e := range m.titledLegend() {
+		tx := lx
+		if e.color != "" {
+			p(`<rect x="%g" y="%g" width="14" height="14" fill="%s"/>`, lx, l.legendTop, e.color)
+			tx += 18
+		}
+		p(`<text x="%g" y="%g" font-size="11" fill="%s">%s</text>`+"\n", tx, l.legendTop+11, chartText, esc(e.label))
+		lx += e.width()
+	}
+
+	// cells
+	for _, h := range m.heat(md) {
+		x, y := l.cellOrigin(int(h[0]), int(h[1]))
+		p(`<rect x="%g" y="%g" width="%g" height="%g" fill="%s" stroke="%s"/>`+"\n", x, y, l.cell, l.cell, m.ColorFor(h[2]), chartBackground)
+	}
+	// points
+	for _, pt := range md.Points {
+		px, _ := pt["x"].(int)
+		py, _ := pt["y"].(int)
+		size, _ := pt["size"].(float64)
+		x, y := l.cellOrigin(px, py)
+		p(`<circle cx="%g" cy="%g" r="%g" fill="%s" stroke="#000" stroke-width="0.8"/>`+"\n", x+l.cell/2, y+l.cell/2, m.pointDiameter(size, l.cell)/2, chartPoint)
+	}
+
+	// axes
+	bottom := l.plotTop + l.plotH
+	p(`<path d="M%g %gH%g M%g %gV%g" stroke="%s" fill="none"/>`+"\n", l.plotLeft, bottom, l.plotLeft+l.plotW, l.plotLeft, l.plotTop, bottom, chartAxisLine)
+	for x := m.XMin; x <= m.XMax; x += l.xTickStep {
+		cx, _ := l.cellOrigin(x, m.YMin)
+		p(`<text x="%g" y="%g" font-size="11" fill="%s" text-anchor="middle">%s</text>`+"\n", cx+l.cell/2, bottom+14, chartText, esc(m.AxisNames.Name("x", x)))
+	}
+	for y := m.YMin; y <= m.YMax; y += l.yTickStep {
+		_, cy := l.cellOrigin(m.XMin, y)
+		p(`<text x="%g" y="%g" font-size="11" fill="%s" text-anchor="end">%s</text>`+"\n", l.plotLeft-6, cy+l.cell/2+4, chartText, esc(m.AxisNames.Name("y", y)))
+	}
+	p(`<text x="%g" y="%g" font-size="12" fill="%s" text-anchor="middle">%s</text>`+"\n", l.plotLeft+l.plotW/2, bottom+34, chartAxisName, esc(m.Labels.X))
+	p(`<text x="%g" y="%g" font-size="12" fill="%s">%s</text>`+"\n", 16.0, l.plotTop-8, chartAxisName, esc(m.Labels.Y))
+	p("</svg>\n")
+	return bw.Flush()
+}
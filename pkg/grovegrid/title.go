@@ -0,0 +1,53 @@
+package grovegrid
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// titleField matches the placeholders of Options.Title.
+var titleField = regexp.MustCompile(`{([a-z_]+)}`)
+
+// TitleFields lists the placeholders Options.Title may hold.
+var TitleFields = []string{"period_range", "generated_date", "months_count"}
+
+// CheckTitle reports a placeholder of title, an Options.Title, that is not
+// one of TitleFields.
+func CheckTitle(title string) error {
+	for _, m := range titleField.FindAllStringSubmatch(title, -1) {
+		if !slices.Contains(TitleFields, m[1]) {
+			return fmt.Errorf("title %q: unknown placeholder %q, want one of %v", title, m[0], TitleFields)
+		}
+	}
+	return nil
+}
+
+// expandTitle fills the placeholders of title for the input slices names,
+// in period order, built on date: {period_range} is the first and last
+// slice ("2025-01 – 2025-06"), {generated_date} date (empty for a
+// reproducible build without a time) and {months_count} the number of
+// slices. Other text is kept as is.
+func expandTitle(title string, names []string, date string) string {
+	var period string
+	switch len(names) {
+	case 0:
+	case 1:
+		period = names[0]
+	default:
+		period = names[0] + " – " + names[len(names)-1]
+	}
+	fields := map[string]string{
+		"period_range":   period,
+		"generated_date": date,
+		"months_count":   strconv.Itoa(len(names)),
+	}
+	return titleField.ReplaceAllStringFunc(title, func(m string) string {
+		if v, ok := fields[strings.Trim(m, "{}")]; ok {
+			return v
+		}
+		return m
+	})
+}
@@ -0,0 +1,24 @@
+package grovegrid
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// tooltipField matches the placeholders of Options.Tooltip.
+var tooltipField = regexp.MustCompile(`{{(.*?)}}`)
+
+// tooltipFieldName matches the fields a placeholder may name.
+var tooltipFieldName = regexp.MustCompile(`^\s*\.(X|Y|Value|Size|Month|Extras\.[^\s{}]+)\s*$`)
+
+// CheckTooltip reports a placeholder of tpl, an Options.Tooltip, that does
+// not name a field.
+func CheckTooltip(tpl string) error {
+	for _, m := range tooltipField.FindAllStringSubmatch(tpl, -1) {
+		if !tooltipFieldName.MatchString(m[1]) {
+			return fmt.Errorf("tooltip %q: unknown field %q, want .X, .Y, .Value, .Size, .Month or .Extras.<column>", tpl, strings.TrimSpace(m[1]))
+		}
+	}
+	return nil
+}
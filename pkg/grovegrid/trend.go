@@ -0,0 +1,85 @@
+package grovegrid
+
+import (
+	"fmt"
+	"time"
+)
+
+// Trend describes the dataset of per-cell linear trends added by
+// Options.Trend. Its values are slopes, change per Per, so like Diff they
+// may be negative and are colored on a scale diverging at 0.
+type Trend struct {
+	// Name is the key of the dataset in Output.Datasets.
+	Name string `json:"name"`
+	// Per is "month" if the slices are months (e.g. 2025-03), so gaps
+	// between them count, else "slice".
+	Per string `json:"per"`
+}
+
+// addTrend adds the dataset of the least-squares slope of each cell's
+// values over the slices names, in order, to out; cells need data in at
+// least two slices. Size and extras are taken from a cell's last record.
+// records holds the records of each slice.
+func addTrend(out *Output, records map[string][]Record, names []string, bins int) {
+	per := "slice"
+	if allMonths(names) {
+		per = "month"
+	}
+	type acc struct {
+		last                     Record
+		n                        int
+		sumT, sumV, sumTT, sumTV float64
+	}
+	cells := map[[2]int]*acc{}
+	var order [][2]int
+	for i, name := range names {
+		t := float64(i)
+		if per == "month" {
+			t = float64(monthsBetween(names[0], name))
+		}
+		for _, r := range records[name] {
+			if !r.hasData() {
+				continue
+			}
+			k := [2]int{r.X, r.Y}
+			a := cells[k]
+			if a == nil {
+				a = &acc{}
+				cells[k] = a
+				order = append(order, k)
+			}
+			a.last = r
+			a.n++
+			a.sumT += t
+			a.sumV += r.Value
+			a.sumTT += t * t
+			a.sumTV += t * r.Value
+		}
+	}
+	var slopes []Record
+	for _, k := range order {
+		a := cells[k]
+		n := float64(a.n)
+		den := n*a.sumTT - a.sumT*a.sumT
+		if a.n < 2 || den == 0 {
+			continue
+		}
+		r := a.last
+		r.Value = (n*a.sumTV - a.sumT*a.sumV) / den
+		r.raw = nil
+		slopes = append(slopes, r)
+	}
+	name := "TREND (per " + per + ")"
+	out.Datasets[name] = signedMonth(slopes, bins, fmt.Sprintf("Linear trend of each cell per %s over %d slices, for cells with data in at least two.", per, len(names)))
+	out.Meta.Trend = &Trend{Name: name, Per: per}
+}
+
+// allMonths reports whether every name is a month such as 2025-03.
+func allMonths(names []string) bool {
+	for _, n := range names {
+		if _, err := time.Parse("2006-01", n); err != nil {
+			return false
+		}
+	}
+	return len(names) > 0
+}
@@ -0,0 +1,215 @@
+package grovegrid
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The kinds of Issue reported by Validate.
+const (
+	// IssueMalformed is an input file or row that cannot be read, including
+	// cells strict parsing rejects.
+	IssueMalformed = "malformed"
+	// IssueHeader is a slice whose columns differ from the first slice's.
+	IssueHeader = "header"
+	// IssueDuplicate is an (x, y) cell given more than once in a slice.
+	IssueDuplicate = "duplicate"
+	// IssueRange is a value outside ValueRange.
+	IssueRange = "range"
+)
+
+// Issue is one problem found by Validate. Path names the input file, Slice
+// the slice, if known.
+type Issue struct {
+	Kind    string `json:"kind"`
+	Path    string `json:"path,omitempty"`
+	Slice   string `json:"slice,omitempty"`
+	Message string `json:"message"`
+}
+
+// Validation is the result of Validate.
+type Validation struct {
+	Files   int     `json:"files"`
+	Slices  int     `json:"slices"`
+	Records int     `json:"records"`
+	Issues  []Issue `json:"issues"`
+}
+
+// ValueRange bounds the values Validate accepts; nil bounds are open.
+type ValueRange struct {
+	Min, Max *float64
+}
+
+// Validate reads the inputs like Build, but instead of assembling a payload
+// it reports every malformed file or row (parsing strictly, see
+// Columns.Strict), slices whose columns differ from the first slice's,
+// duplicate cells and values outside vr. Malformed rows are skipped and do
+// not count as records. An input file that does not parse is an issue; the
+// error is set if the inputs cannot be listed, as for Build, or if
+// opts.Source, which loads all slices at once, fails.
+func Validate(opts Options, vr ValueRange) (*Validation, error) {
+	opts.Strict = true
+	v := &Validation{Issues: []Issue{}}
+	type input struct {
+		path   string
+		slices []Slice
+	}
+	var inputs []input
+	if opts.Source != nil {
+		var rejected []error
+		so := opts
+		so.Columns.rejected = &rejected
+		ss, err := opts.Source.Load(so)
+		if err != nil {
+			return nil, err
+		}
+		for _, err := range rejected {
+			v.Issues = append(v.Issues, Issue{Kind: IssueMalformed, Path: "source", Message: err.Error()})
+		}
+		if len(ss) == 0 {
+			return nil, ErrNoInput
+		}
+		if opts.Columns.Date != "" {
+			if ss, err = bucketByDate(ss, opts.Columns.Date, opts.Period, opts.Location); err != nil {
+				return nil, err
+			}
+		}
+		inputs = append(inputs, input{slices: ss})
+	} else {
+		files, err := listInputs(opts.InDir)
+		if err != nil {
+			return nil, err
+		}
+		v.Files = len(files)
+		for _, f := range files {
+			var rejected []error
+			fo := opts
+			fo.Columns.rejected = &rejected
+			ss, err := fo.loadFile(f)
+			if err != nil {
+				v.Issues = append(v.Issues, Issue{Kind: IssueMalformed, Path: f, Message: err.Error()})
+				continue
+			}
+			for _, err := range rejected {
+				v.Issues = append(v.Issues, Issue{Kind: IssueMalformed, Path: f, Message: err.Error()})
+			}
+			if opts.Columns.Date != "" {
+				if ss, err = bucketByDate(ss, opts.Columns.Date, opts.Period, opts.Location); err != nil {
+					v.Issues = append(v.Issues, Issue{Kind: IssueMalformed, Path: f, Message: err.Error()})
+					continue
+				}
+			}
+			inputs = append(inputs, input{path: f, slices: ss})
+		}
+	}
+
+	var first *Slice
+	for _, in := range inputs {
+		for i := range in.slices {
+			sl := &in.slices[i]
+			v.Slices++
+			v.Records += len(sl.Records)
+			issue := func(kind, format string, args ...interface{}) {
+				v.Issues = append(v.Issues, Issue{Kind: kind, Path: in.path, Slice: sl.Name, Message: fmt.Sprintf(format, args...)})
+			}
+			if err := checkSliceName(sl.Name); err != nil {
+				issue(IssueMalformed, "%v", err)
+			}
+			if first == nil {
+				first = sl
+			} else if diff := labelDiff(first.Labels, sl.Labels); diff != "" {
+				issue(IssueHeader, "columns differ from slice %s: %s", first.Name, diff)
+			}
+			for _, d := range duplicates(sl.Records) {
+				issue(IssueDuplicate, "cell (%s, %s) appears %d times", d.x, d.y, d.n)
+			}
+			for _, r := range sl.Records {
+				if !r.hasData() {
+					continue // no data
+				}
+				if (vr.Min != nil && r.Value < *vr.Min) || (vr.Max != nil && r.Value > *vr.Max) {
+					issue(IssueRange, "cell (%s, %s): value %g is outside %s", coordText(r.X, r.XName), coordText(r.Y, r.YName), r.Value, vr)
+				}
+			}
+		}
+	}
+	return v, nil
+}
+
+// String renders vr as an interval such as [0, 100].
+func (vr ValueRange) String() string {
+	bound := func(b *float64, open string) string {
+		if b == nil {
+			return open
+		}
+		return strconv.FormatFloat(*b, 'g', -1, 64)
+	}
+	return "[" + bound(vr.Min, "-inf") + ", " + bound(vr.Max, "inf") + "]"
+}
+
+// labelDiff describes how the columns of b differ from those of a, or
+// returns "" if they match.
+func labelDiff(a, b Labels) string {
+	var diffs []string
+	for _, c := range []struct{ role, a, b string }{
+		{"X", a.X, b.X}, {"Y", a.Y, b.Y}, {"Value", a.Value, b.Value}, {"Size", a.Size, b.Size},
+	} {
+		if c.a != c.b {
+			diffs = append(diffs, fmt.Sprintf("%s is %q, not %q", c.role, c.b, c.a))
+		}
+	}
+	var missing, extra []string
+	for _, e := range a.Extras {
+		if !slices.Contains(b.Extras, e) {
+			missing = append(missing, e)
+		}
+	}
+	for _, e := range b.Extras {
+		if !slices.Contains(a.Extras, e) {
+			extra = append(extra, e)
+		}
+	}
+	if len(missing) > 0 {
+		diffs = append(diffs, "missing "+strings.Join(missing, ", "))
+	}
+	if len(extra) > 0 {
+		diffs = append(diffs, "extra "+strings.Join(extra, ", "))
+	}
+	return strings.Join(diffs, "; ")
+}
+
+type duplicate struct {
+	x, y string
+	n    int
+}
+
+// duplicates lists the cells given more than once in recs, in order of
+// first appearance.
+func duplicates(recs []Record) []duplicate {
+	count := map[[2]string]int{}
+	var order [][2]string
+	for _, r := range recs {
+		k := [2]string{coordText(r.X, r.XName), coordText(r.Y, r.YName)}
+		if count[k] == 0 {
+			order = append(order, k)
+		}
+		count[k]++
+	}
+	var out []duplicate
+	for _, k := range order {
+		if count[k] > 1 {
+			out = append(out, duplicate{x: k[0], y: k[1], n: count[k]})
+		}
+	}
+	return out
+}
+
+// coordText is the input text of a coordinate: its category name, if any.
+func coordText(n int, name string) string {
+	if name != "" {
+		return name
+	}
+	return strconv.Itoa(n)
+}
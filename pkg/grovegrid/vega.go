@@ -0,0 +1,81 @@
+package grovegrid
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VegaLiteSpec returns a Vega-Lite v5 specification of one slice: a rect
+// layer colored like the page and a circle layer sized by Size. The data is
+// read from dataURL, a JSON array of MonthData.Cells.
+func VegaLiteSpec(out *Output, month, dataURL string) (map[string]interface{}, error) {
+	if _, ok := out.Datasets[month]; !ok {
+		return nil, fmt.Errorf("no slice %q", month)
+	}
+	m := out.metaFor(month)
+	lb := m.Labels
+	domain, colors := m.ColorThresholds()
+	tooltip := []map[string]interface{}{
+		{"field": "x", "type": "ordinal", "title": lb.X},
+		{"field": "y", "type": "ordinal", "title": lb.Y},
+		{"field": "value", "type": "quantitative", "title": lb.Value},
+		{"field": "size", "type": "quantitative", "title": lb.Size},
+	}
+	for _, e := range lb.Extras {
+		tooltip = append(tooltip, map[string]interface{}{"field": "extras." + vegaField(e), "type": "nominal", "title": e})
+	}
+	sizeScale := map[string]interface{}{"range": []float64{36, 36}}
+	if m.SizeMax > m.SizeMin {
+		// page circles are 6–28px across; Vega-Lite sizes are areas
+		sizeScale = map[string]interface{}{"domain": []float64{m.SizeMin, m.SizeMax}, "range": []float64{36, 784}, "clamp": true}
+	}
+	title := month
+	if m.Title != "" {
+		title = m.Title + " – " + month
+	}
+	return map[string]interface{}{
+		"$schema":    "https://vega.github.io/schema/vega-lite/v5.json",
+		"title":      title,
+		"background": chartBackground,
+		"data":       map[string]interface{}{"url": dataURL},
+		"width":      m.columns() * 24,
+		"height":     m.rows() * 24,
+		"config": map[string]interface{}{
+			"view":   map[string]interface{}{"stroke": nil},
+			"title":  map[string]interface{}{"color": chartText},
+			"axis":   map[string]interface{}{"labelColor": chartText, "titleColor": chartAxisName, "domainColor": chartAxisLine, "tickColor": chartAxisLine},
+			"legend": map[string]interface{}{"labelColor": chartText, "titleColor": chartAxisName},
+		},
+		"encoding": map[string]interface{}{
+			"x": map[string]interface{}{"field": "x", "type": "ordinal", "title": lb.X, "axis": map[string]interface{}{"labelAngle": 0}},
+			"y": map[string]interface{}{"field": "y", "type": "ordinal", "title": lb.Y, "sort": "descending"},
+		},
+		"layer": []interface{}{
+			map[string]interface{}{
+				"mark": map[string]interface{}{"type": "rect", "stroke": chartBackground},
+				"encoding": map[string]interface{}{
+					"color": map[string]interface{}{
+						"field": "value", "type": "quantitative", "title": lb.Value,
+						"scale": map[string]interface{}{"type": "threshold", "domain": domain, "range": colors},
+					},
+					"tooltip": tooltip,
+				},
+			},
+			map[string]interface{}{
+				"transform": []interface{}{map[string]interface{}{"filter": "datum.value >= 0"}},
+				"mark":      map[string]interface{}{"type": "circle", "color": chartPoint, "stroke": "#000", "strokeWidth": 0.8, "opacity": 1},
+				"encoding": map[string]interface{}{
+					"size":    map[string]interface{}{"field": "size", "type": "quantitative", "title": lb.Size, "scale": sizeScale},
+					"tooltip": tooltip,
+				},
+			},
+		},
+	}, nil
+}
+
+var vegaFieldEscaper = strings.NewReplacer(".", `\.`, "[", `\[`, "]", `\]`)
+
+// vegaField escapes a key so Vega-Lite does not read it as a nested path.
+func vegaField(s string) string {
+	return vegaFieldEscaper.Replace(s)
+}
@@ -0,0 +1,179 @@
+package grovegrid
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// xlsxSheet is one worksheet of a workbook as rows of cell text.
+type xlsxSheet struct {
+	name string
+	rows [][]string
+}
+
+// readXLSX returns the non-empty worksheets of an Excel workbook in workbook
+// order. Numeric cells are stored in the shortest representation, such as
+// 1.5E-3; they are rewritten as plain decimals (0.0015), which the number
+// reading of the other inputs takes as they are.
+func readXLSX(r io.ReaderAt, size int64) ([]xlsxSheet, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var wb struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeZipXML(files, "xl/workbook.xml", &wb); err != nil {
+		return nil, err
+	}
+	var rels struct {
+		Rels []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeZipXML(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	targets := map[string]string{}
+	for _, r := range rels.Rels {
+		t := r.Target
+		if strings.HasPrefix(t, "/") {
+			t = strings.TrimPrefix(t, "/")
+		} else {
+			t = path.Join("xl", t)
+		}
+		targets[r.ID] = t
+	}
+
+	var shared []string
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		var sst struct {
+			Items []xlsxText `xml:"si"`
+		}
+		if err := decodeZipXML(files, "xl/sharedStrings.xml", &sst); err != nil {
+			return nil, err
+		}
+		for _, it := range sst.Items {
+			shared = append(shared, it.String())
+		}
+	}
+
+	var out []xlsxSheet
+	for _, sh := range wb.Sheets {
+		var ws struct {
+			Rows []struct {
+				R     int `xml:"r,attr"`
+				Cells []struct {
+					Ref    string   `xml:"r,attr"`
+					Type   string   `xml:"t,attr"`
+					Value  string   `xml:"v"`
+					Inline xlsxText `xml:"is"`
+				} `xml:"c"`
+			} `xml:"sheetData>row"`
+		}
+		if err := decodeZipXML(files, targets[sh.RID], &ws); err != nil {
+			return nil, fmt.Errorf("sheet %q: %w", sh.Name, err)
+		}
+		var rows [][]string
+		for ri, row := range ws.Rows {
+			rowIdx := row.R - 1
+			if row.R == 0 {
+				rowIdx = ri
+			}
+			for len(rows) <= rowIdx {
+				rows = append(rows, nil)
+			}
+			var cells []string
+			for ci, c := range row.Cells {
+				col := ci
+				if c.Ref != "" {
+					col = xlsxColumn(c.Ref)
+				}
+				for len(cells) <= col {
+					cells = append(cells, "")
+				}
+				switch c.Type {
+				case "s":
+					i, err := strconv.Atoi(c.Value)
+					if err == nil && i >= 0 && i < len(shared) {
+						cells[col] = shared[i]
+					}
+				case "inlineStr":
+					cells[col] = c.Inline.String()
+				case "", "n":
+					cells[col] = c.Value
+					if v, err := strconv.ParseFloat(c.Value, 64); err == nil {
+						cells[col] = strconv.FormatFloat(v, 'f', -1, 64)
+					}
+				default:
+					cells[col] = c.Value
+				}
+			}
+			rows[rowIdx] = cells
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		out = append(out, xlsxSheet{name: sh.Name, rows: rows})
+	}
+	return out, nil
+}
+
+// xlsxText is a shared or inline string, either plain or rich text runs.
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+// xlsxColumn returns the zero-based column of a cell reference like "AB12".
+func xlsxColumn(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+	}
+	return col - 1
+}
+
+func decodeZipXML(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("%s missing from workbook", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(io.LimitReader(rc, 1<<30)).Decode(v)
+}
@@ -0,0 +1,56 @@
+package grovegrid
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeXLSX writes a workbook with one sheet whose XML sheetData is rows.
+func writeXLSX(t *testing.T, path, rows string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, data := range map[string]string{
+		"xl/workbook.xml": `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Sheet1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/worksheets/sheet1.xml":   `<worksheet><sheetData>` + rows + `</sheetData></worksheet>`,
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(data))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestXLSXExponent(t *testing.T) {
+	in := t.TempDir()
+	writeXLSX(t, filepath.Join(in, "2025-01.xlsx"),
+		`<row r="1"><c r="A1" t="inlineStr"><is><t>x</t></is></c><c r="B1" t="inlineStr"><is><t>y</t></is></c><c r="C1" t="inlineStr"><is><t>value</t></is></c></row>`+
+			`<row r="2"><c r="A2"><v>1</v></c><c r="B2"><v>1</v></c><c r="C2"><v>1.5E-3</v></c></row>`+
+			`<row r="3"><c r="A3"><v>1</v></c><c r="B3"><v>2</v></c><c r="C3" t="n"><v>2.5E+4</v></c></row>`)
+	out, err := Build(Options{InDir: in})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int]float64{1: 0.0015, 2: 25000}
+	for _, p := range out.Datasets["2025-01"].Points {
+		y, _ := p["y"].(int)
+		if v, _ := p["value"].(float64); v != want[y] {
+			t.Errorf("y %d: value %v, want %v", y, v, want[y])
+		}
+	}
+	if n := len(out.Datasets["2025-01"].Points); n != 2 {
+		t.Errorf("%d points, want 2", n)
+	}
+}
@@ -0,0 +1,35 @@
+package grovegrid
+
+import (
+	"fmt"
+	"time"
+)
+
+// YoYName names the year-over-year delta dataset of month, e.g.
+// "YOY (2025-03 vs 2024-03)".
+func YoYName(month, prior string) string {
+	return "YOY (" + month + " vs " + prior + ")"
+}
+
+// addYoY adds, for each month in names whose month a year earlier is in
+// names too, the dataset of the deltas from that month, in order of
+// names. They are described like Diff by Meta.YoY.
+func addYoY(out *Output, records map[string][]Record, names []string, bins int) {
+	have := make(map[string]bool, len(names))
+	for _, n := range names {
+		have[n] = true
+	}
+	for _, n := range names {
+		t, err := time.Parse("2006-01", n)
+		if err != nil {
+			continue
+		}
+		prior := monthKey(t.AddDate(-1, 0, 0))
+		if !have[prior] {
+			continue
+		}
+		name := YoYName(n, prior)
+		out.Datasets[name] = deltaMonth(records[prior], records[n], bins, fmt.Sprintf("Change from %s to %s, a year later, per cell with data in both.", prior, n))
+		out.Meta.YoY = append(out.Meta.YoY, Diff{Name: name, From: prior, To: n})
+	}
+}